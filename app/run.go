@@ -8,18 +8,18 @@ import (
 
 	"github.com/docker/docker/api/types"
 	dcontainer "github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/client"
 	"github.com/docker/go-connections/nat"
 	"github.com/pkg/errors"
 
 	"github.com/buildpack/pack/container"
+	"github.com/buildpack/pack/internal/resource"
 	"github.com/buildpack/pack/logging"
 )
 
-func (i *Image) Run(ctx context.Context, docker *client.Client, ports []string) error {
+func (i *Image) Run(ctx context.Context, runtime container.Runtime, ports []string) error {
 	if ports == nil {
 		var err error
-		ports, err = exposedPorts(ctx, docker, i.RepoName)
+		ports, err = exposedPorts(ctx, runtime, i.RepoName)
 		if err != nil {
 			return err
 		}
@@ -30,12 +30,12 @@ func (i *Image) Run(ctx context.Context, docker *client.Client, ports []string)
 		return err
 	}
 
-	ctr, err := docker.ContainerCreate(ctx, &dcontainer.Config{
+	ctr, err := runtime.ContainerCreate(ctx, &dcontainer.Config{
 		Image:        i.RepoName,
 		AttachStdout: true,
 		AttachStderr: true,
 		ExposedPorts: parsedPorts,
-		Labels:       map[string]string{"author": "pack"},
+		Labels:       resource.Labels(resource.PurposeAppContainer),
 	}, &dcontainer.HostConfig{
 		AutoRemove:   true,
 		PortBindings: portBindings,
@@ -43,12 +43,12 @@ func (i *Image) Run(ctx context.Context, docker *client.Client, ports []string)
 	if err != nil {
 		return err
 	}
-	defer docker.ContainerRemove(context.Background(), ctr.ID, types.ContainerRemoveOptions{Force: true})
+	defer runtime.ContainerRemove(context.Background(), ctr.ID, types.ContainerRemoveOptions{Force: true})
 
 	logContainerListening(i.Logger, portBindings)
 	if err = container.Run(
 		ctx,
-		docker,
+		runtime,
 		ctr.ID,
 		logging.GetDebugWriter(i.Logger),
 		logging.GetDebugErrorWriter(i.Logger),
@@ -59,8 +59,8 @@ func (i *Image) Run(ctx context.Context, docker *client.Client, ports []string)
 	return nil
 }
 
-func exposedPorts(ctx context.Context, docker *client.Client, imageID string) ([]string, error) {
-	i, _, err := docker.ImageInspectWithRaw(ctx, imageID)
+func exposedPorts(ctx context.Context, runtime container.Runtime, imageID string) ([]string, error) {
+	i, _, err := runtime.ImageInspectWithRaw(ctx, imageID)
 	if err != nil {
 		return nil, err
 	}
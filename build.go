@@ -2,41 +2,65 @@ package pack
 
 import (
 	"context"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
-	"math/rand"
+	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/buildpack/imgutil"
-	"github.com/docker/docker/api/types"
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/pkg/errors"
 
 	"github.com/buildpack/pack/build"
 	"github.com/buildpack/pack/builder"
 	"github.com/buildpack/pack/buildpack"
+	"github.com/buildpack/pack/cache"
 	"github.com/buildpack/pack/internal/archive"
+	"github.com/buildpack/pack/internal/git"
+	"github.com/buildpack/pack/project"
 	"github.com/buildpack/pack/style"
 )
 
+// ignoreFile lists gitignore-style patterns for files in the app dir that are not used in the build
+const ignoreFile = ".packignore"
+
 type Lifecycle interface {
 	Execute(ctx context.Context, opts build.LifecycleOptions) error
+	ExecuteDetect(ctx context.Context, opts build.LifecycleOptions) (*build.DetectResult, error)
+	ExecuteShell(ctx context.Context, opts build.LifecycleOptions, detect bool, in io.Reader, out io.Writer) error
 }
 
 type BuildOptions struct {
 	Image             string              // required
-	Builder           string              // required
-	AppPath           string              // defaults to current working directory
+	AdditionalTags    []string            // extra tags the image is exported with, on the same registry as Image when publishing
+	Builder           string              // required unless set in the project descriptor
+	AppPath           string              // defaults to current working directory, may contain a project descriptor
+	AppReader         io.Reader           // tar stream of the app, used instead of AppPath when provided
 	RunImage          string              // defaults to the best mirror from the builder metadata or AdditionalMirrors
 	AdditionalMirrors map[string][]string // only considered if RunImage is not provided
-	Env               map[string]string
+	Env               map[string]string   // build-time env, keyed on platform env file names as returned by builder.ParseEnvAssignment
+	Secrets           map[string]string   // files, by ID, mounted read-only at /platform/bindings/<ID>/<file name> for detect and build only
+	Bindings          map[string]string   // dirs, by name, mounted read-only at /platform/bindings/<name> for detect and build only
+	CACerts           []string            // PEM files of CA certs trusted by every phase, in addition to those of the builder
 	Publish           bool
 	NoPull            bool
 	ClearCache        bool
 	Buildpacks        []string
-	ProxyConfig       *ProxyConfig // defaults to  environment proxy vars
+	Exclude           []string           // gitignore-style patterns for app files to leave out, added to those in .packignore
+	Include           []string           // when set, only app files matching these patterns are used
+	ProxyConfig       *ProxyConfig       // defaults to  environment proxy vars
+	EventHandler      build.EventHandler // receives structured build events, may be nil
+	Report            *BuildReport       // populated during the build when provided
+	CacheName         string             // name of the build cache to use instead of one keyed on Image, to share it between images
+	CacheFallbacks    []string           // names of build caches to restore from, in order, when the build cache is empty
+	KeepOnFailure     bool               // keep the volumes and failed container of a failed build for inspection
+	ResumeFrom        string             // phase to resume a build kept with KeepOnFailure from, one of detect, restore, analyze, build, export or cache
 }
 
 type ProxyConfig struct {
@@ -51,9 +75,38 @@ func (c *Client) Build(ctx context.Context, opts BuildOptions) error {
 		return errors.Wrapf(err, "invalid image name '%s'", opts.Image)
 	}
 
-	appPath, err := c.processAppPath(opts.AppPath)
+	additionalTags, err := c.processAdditionalTags(imageRef, opts.AdditionalTags, opts.Publish)
+	if err != nil {
+		return err
+	}
+
+	if err := builder.ValidateEnvFiles(opts.Env); err != nil {
+		return err
+	}
+
+	bindings, err := processBindings(opts.Secrets, opts.Bindings)
+	if err != nil {
+		return err
+	}
+
+	caCerts, err := processCACerts(opts.CACerts)
 	if err != nil {
-		return errors.Wrapf(err, "invalid app path '%s'", opts.AppPath)
+		return err
+	}
+
+	appPath, source, cleanup, err := c.resolveAppSource(ctx, opts.AppPath, opts.AppReader)
+	defer cleanup()
+	if err != nil {
+		return err
+	}
+
+	if opts, err = c.applyProjectDescriptor(appPath, opts); err != nil {
+		return err
+	}
+
+	appExclude, err := c.processAppExclude(appPath, opts.Exclude)
+	if err != nil {
+		return err
 	}
 
 	proxyConfig := c.processProxyConfig(opts.ProxyConfig)
@@ -63,7 +116,15 @@ func (c *Client) Build(ctx context.Context, opts BuildOptions) error {
 		return errors.Wrapf(err, "invalid builder '%s'", opts.Builder)
 	}
 
+	events := opts.EventHandler
+	if opts.Report != nil {
+		events = opts.Report.recorder(events)
+	}
+
+	fetchStart := time.Now()
+	events.Emit(build.Event{Type: build.EventBuilderFetchStarted, Image: builderRef.Name()})
 	rawBuilderImage, err := c.imageFetcher.Fetch(ctx, builderRef.Name(), true, !opts.NoPull)
+	events.Emit(build.Event{Type: build.EventBuilderFetchFinished, Image: builderRef.Name(), Duration: time.Since(fetchStart), Digest: imageDigest(rawBuilderImage), Err: err})
 	if err != nil {
 		return errors.Wrapf(err, "failed to fetch builder image '%s'", builderRef.Name())
 	}
@@ -75,7 +136,18 @@ func (c *Client) Build(ctx context.Context, opts BuildOptions) error {
 
 	runImage := c.resolveRunImage(opts.RunImage, imageRef.Context().RegistryStr(), builderImage.GetStackInfo(), opts.AdditionalMirrors)
 
-	if _, err := c.validateRunImage(ctx, runImage, opts.NoPull, opts.Publish, builderImage.StackID); err != nil {
+	if opts.Report != nil {
+		opts.Report.RunImage.Image = builderImage.GetStackInfo().RunImage.Image
+		if opts.RunImage != "" {
+			opts.Report.RunImage.Image = opts.RunImage
+		}
+	}
+
+	fetchStart = time.Now()
+	events.Emit(build.Event{Type: build.EventRunImageFetchStarted, Image: runImage})
+	runImg, err := c.validateRunImage(ctx, runImage, opts.NoPull, opts.Publish, builderImage.StackID)
+	events.Emit(build.Event{Type: build.EventRunImageFetchFinished, Image: runImage, Duration: time.Since(fetchStart), Digest: imageDigest(runImg), Err: err})
+	if err != nil {
 		return errors.Wrapf(err, "invalid run-image '%s'", runImage)
 	}
 
@@ -84,25 +156,251 @@ func (c *Client) Build(ctx context.Context, opts BuildOptions) error {
 		return errors.Wrap(err, "invalid buildpack")
 	}
 
-	ephemeralBuilder, err := c.createEphemeralBuilder(rawBuilderImage, opts.Env, group, extraBuildpacks)
+	ephemeralBuilder, err := c.createEphemeralBuilder(ctx, rawBuilderImage, group, extraBuildpacks)
+	if err != nil {
+		return err
+	}
+	if ephemeralBuilder.Name() != builderRef.Name() {
+		events.Emit(build.Event{Type: build.EventEphemeralBuilderCreated, Image: ephemeralBuilder.Name()})
+	}
+
+	if err := c.cacheUsage.touch(imageRef.Name()); err != nil {
+		c.logger.Debugf("Unable to record use of caches for %s: %s", style.Symbol(imageRef.Name()), err)
+	}
+	if opts.CacheName != "" {
+		if err := c.cacheUsage.touch(cache.NamedCacheKey(opts.CacheName)); err != nil {
+			c.logger.Debugf("Unable to record use of cache %s: %s", style.Symbol(opts.CacheName), err)
+		}
+	}
+
+	labels, err := projectMetadataLabels(source)
 	if err != nil {
 		return err
 	}
-	defer c.docker.ImageRemove(context.Background(), ephemeralBuilder.Name(), types.ImageRemoveOptions{Force: true})
 
 	return c.lifecycle.Execute(ctx, build.LifecycleOptions{
-		AppPath:    appPath,
-		Image:      imageRef,
-		Builder:    ephemeralBuilder,
-		RunImage:   runImage,
-		ClearCache: opts.ClearCache,
-		Publish:    opts.Publish,
-		HTTPProxy:  proxyConfig.HTTPProxy,
-		HTTPSProxy: proxyConfig.HTTPSProxy,
-		NoProxy:    proxyConfig.NoProxy,
+		AppPath:        appPath,
+		AppExclude:     appExclude,
+		AppInclude:     opts.Include,
+		Image:          imageRef,
+		AdditionalTags: additionalTags,
+		Builder:        ephemeralBuilder,
+		RunImage:       runImage,
+		Env:            opts.Env,
+		Bindings:       bindings,
+		CACerts:        caCerts,
+		ClearCache:     opts.ClearCache,
+		Publish:        opts.Publish,
+		HTTPProxy:      proxyConfig.HTTPProxy,
+		HTTPSProxy:     proxyConfig.HTTPSProxy,
+		NoProxy:        proxyConfig.NoProxy,
+		EventHandler:   events,
+		Labels:         labels,
+		CacheName:      opts.CacheName,
+		CacheFallbacks: opts.CacheFallbacks,
+		KeepOnFailure:  opts.KeepOnFailure,
+		ResumeFrom:     opts.ResumeFrom,
 	})
 }
 
+func imageDigest(img imgutil.Image) string {
+	if img == nil {
+		return ""
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		return ""
+	}
+	return digest
+}
+
+// resolveAppSource returns the local path of the app, spooling appReader or checking out a git source as needed.
+// The returned cleanup func removes anything created along the way and must be called even on error.
+func (c *Client) resolveAppSource(ctx context.Context, appPath string, appReader io.Reader) (string, *projectSource, func(), error) {
+	var cleanups []func()
+	cleanup := func() {
+		for _, f := range cleanups {
+			f()
+		}
+	}
+
+	resolvedAppPath := appPath
+	if appReader != nil {
+		appFile, err := spoolAppReader(appReader)
+		if err != nil {
+			return "", nil, cleanup, errors.Wrap(err, "reading app")
+		}
+		cleanups = append(cleanups, func() { os.Remove(appFile) })
+		resolvedAppPath = appFile
+	}
+
+	var source *projectSource
+	if gitSource, ok := git.ParseSource(appPath); ok && appReader == nil {
+		checkoutDir, err := ioutil.TempDir("", "pack.git.")
+		if err != nil {
+			return "", nil, cleanup, errors.Wrap(err, "creating checkout dir")
+		}
+		cleanups = append(cleanups, func() { os.RemoveAll(checkoutDir) })
+
+		if resolvedAppPath, source, err = c.checkoutGitSource(ctx, gitSource, checkoutDir); err != nil {
+			return "", nil, cleanup, errors.Wrapf(err, "invalid app path '%s'", appPath)
+		}
+	}
+
+	resolvedAppPath, err := c.processAppPath(resolvedAppPath)
+	if err != nil {
+		return "", nil, cleanup, errors.Wrapf(err, "invalid app path '%s'", appPath)
+	}
+	return resolvedAppPath, source, cleanup, nil
+}
+
+// applyProjectDescriptor fills in any options not provided by the caller from the project descriptor in
+// appPath, if there is one
+func (c *Client) applyProjectDescriptor(appPath string, opts BuildOptions) (BuildOptions, error) {
+	if fi, err := os.Stat(appPath); err != nil || !fi.IsDir() {
+		return opts, nil
+	}
+
+	descriptor, err := project.ReadDescriptor(appPath)
+	if err != nil {
+		return opts, err
+	}
+
+	if opts.Builder == "" {
+		opts.Builder = descriptor.Build.Builder
+	}
+
+	if opts.RunImage == "" {
+		opts.RunImage = descriptor.Build.RunImage
+	}
+
+	if len(opts.Buildpacks) == 0 {
+		opts.Buildpacks = descriptor.Build.BuildpackRefs()
+	}
+
+	if len(opts.Exclude) == 0 {
+		opts.Exclude = descriptor.Build.Exclude
+	}
+
+	if len(opts.Include) == 0 {
+		opts.Include = descriptor.Build.Include
+	}
+
+	if len(descriptor.Build.Env) > 0 {
+		env := descriptor.Build.EnvMap()
+		for k, v := range opts.Env {
+			env[k] = v
+		}
+		opts.Env = env
+	}
+
+	return opts, nil
+}
+
+// processAppExclude prepends the patterns in the app's ignore file, if there is one, to exclude
+func (c *Client) processAppExclude(appPath string, exclude []string) ([]string, error) {
+	if fi, err := os.Stat(appPath); err != nil || !fi.IsDir() {
+		return exclude, nil
+	}
+
+	contents, err := ioutil.ReadFile(filepath.Join(appPath, ignoreFile))
+	if os.IsNotExist(err) {
+		return exclude, nil
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "read %s", style.Symbol(ignoreFile))
+	}
+
+	c.logger.Debugf("Using %s from app dir", style.Symbol(ignoreFile))
+	return append(strings.Split(string(contents), "\n"), exclude...), nil
+}
+
+func (c *Client) processAdditionalTags(imageRef name.Reference, tags []string, publish bool) ([]string, error) {
+	var names []string
+	for _, tag := range tags {
+		ref, err := c.parseTagReference(tag)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid additional tag '%s'", tag)
+		}
+
+		if publish && ref.Context().RegistryStr() != imageRef.Context().RegistryStr() {
+			return nil, fmt.Errorf("additional tag %s must be on registry %s when publishing", style.Symbol(tag), style.Symbol(imageRef.Context().RegistryStr()))
+		}
+		names = append(names, ref.Name())
+	}
+	return names, nil
+}
+
+// processBindings checks that each secret is a file and each binding a dir, and returns their absolute paths keyed on
+// where they are mounted below /platform/bindings. Only the paths are handled, so their contents are never logged.
+func processBindings(secrets, bindings map[string]string) (map[string]string, error) {
+	result, used := map[string]string{}, map[string]bool{}
+	for _, kind := range []struct {
+		name  string
+		items map[string]string
+		dir   bool
+	}{
+		{"secret", secrets, false},
+		{"binding", bindings, true},
+	} {
+		for name, src := range kind.items {
+			if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+				return nil, fmt.Errorf("invalid %s name %s", kind.name, style.Symbol(name))
+			}
+			if used[name] {
+				return nil, fmt.Errorf("%s name %s is already used", kind.name, style.Symbol(name))
+			}
+			used[name] = true
+			absSrc, err := filepath.Abs(src)
+			if err != nil {
+				return nil, errors.Wrapf(err, "resolve %s %s", kind.name, style.Symbol(name))
+			}
+			fi, err := os.Stat(absSrc)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid %s %s", kind.name, style.Symbol(name))
+			}
+			switch {
+			case kind.dir && !fi.IsDir():
+				return nil, fmt.Errorf("%s %s must be a directory, %s is not", kind.name, style.Symbol(name), style.Symbol(src))
+			case !kind.dir && fi.IsDir():
+				return nil, fmt.Errorf("%s %s must be a file, %s is a directory", kind.name, style.Symbol(name), style.Symbol(src))
+			}
+			if kind.dir {
+				result[name] = absSrc
+			} else {
+				result[name+"/"+filepath.Base(absSrc)] = absSrc
+			}
+		}
+	}
+	return result, nil
+}
+
+// processCACerts reads the CA certs in files into a single PEM bundle, checking that each file holds at least one
+func processCACerts(files []string) ([]byte, error) {
+	var bundle []byte
+	for _, file := range files {
+		contents, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, errors.Wrapf(err, "read CA cert file %s", style.Symbol(file))
+		}
+
+		found := false
+		for block, rest := pem.Decode(contents); block != nil; block, rest = pem.Decode(rest) {
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+			if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+				return nil, errors.Wrapf(err, "invalid CA cert in %s", style.Symbol(file))
+			}
+			bundle = append(bundle, pem.EncodeToMemory(block)...)
+			found = true
+		}
+		if !found {
+			return nil, fmt.Errorf("no PEM encoded CA certs found in %s", style.Symbol(file))
+		}
+	}
+	return bundle, nil
+}
+
 func (c *Client) processBuilderName(builderName string) (name.Reference, error) {
 	if builderName == "" {
 		return nil, errors.New("builder is a required parameter if the client has no default builder")
@@ -177,13 +475,39 @@ func (c *Client) processAppPath(appPath string) (string, error) {
 		}
 
 		if !isZip {
-			return "", errors.New("app path must be a directory or zip")
+			if _, err := fh.Seek(0, io.SeekStart); err != nil {
+				return "", errors.Wrap(err, "read file")
+			}
+
+			isTar, err := archive.IsTar(fh)
+			if err != nil {
+				return "", errors.Wrap(err, "check tar")
+			}
+
+			if !isTar {
+				return "", errors.New("app path must be a directory, zip or tar")
+			}
 		}
 	}
 
 	return resolvedAppPath, nil
 }
 
+// spoolAppReader copies r to a temporary file so it can be validated before it is streamed to the build
+func spoolAppReader(r io.Reader) (string, error) {
+	fh, err := ioutil.TempFile("", "pack.app.")
+	if err != nil {
+		return "", err
+	}
+	defer fh.Close()
+
+	if _, err := io.Copy(fh, r); err != nil {
+		os.Remove(fh.Name())
+		return "", err
+	}
+	return fh.Name(), nil
+}
+
 func (c *Client) processProxyConfig(config *ProxyConfig) ProxyConfig {
 	var (
 		httpProxy, httpsProxy, noProxy string
@@ -265,32 +589,3 @@ func (c *Client) parseBuildpack(bp string) (string, string) {
 
 	return parts[0], ""
 }
-
-func (c *Client) createEphemeralBuilder(rawBuilderImage imgutil.Image, env map[string]string, group builder.OrderEntry, buildpacks []buildpack.Buildpack) (*builder.Builder, error) {
-	origBuilderName := rawBuilderImage.Name()
-	bldr, err := builder.New(rawBuilderImage, fmt.Sprintf("pack.local/builder/%x:latest", randString(10)))
-	if err != nil {
-		return nil, errors.Wrapf(err, "invalid builder %s", style.Symbol(origBuilderName))
-	}
-	bldr.SetEnv(env)
-	for _, bp := range buildpacks {
-		c.logger.Debugf("adding buildpack %s version %s to builder", style.Symbol(bp.ID), style.Symbol(bp.Version))
-		bldr.AddBuildpack(bp)
-	}
-	if len(group.Group) > 0 {
-		c.logger.Debug("setting custom order")
-		bldr.SetOrder([]builder.OrderEntry{group})
-	}
-	if err := bldr.Save(); err != nil {
-		return nil, err
-	}
-	return bldr, nil
-}
-
-func randString(n int) string {
-	b := make([]byte, n)
-	for i := range b {
-		b[i] = 'a' + byte(rand.Intn(26))
-	}
-	return string(b)
-}
@@ -0,0 +1,158 @@
+package build
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	dcontainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/pkg/errors"
+
+	"github.com/buildpack/pack/container"
+	"github.com/buildpack/pack/internal/archive"
+	"github.com/buildpack/pack/internal/resource"
+	"github.com/buildpack/pack/style"
+)
+
+// daemonProbeTimeout is how long the daemon probe may take before the daemon is taken to be unreachable
+const daemonProbeTimeout = 30 * time.Second
+
+// daemonProbeScript exits non-zero when the daemon cannot be reached from the container. A TCP daemon is connected to
+// with bash, as builder images are not expected to have a Docker client, and the script exits 127 when there is no
+// bash. A unix socket only has to be mounted, as a container can always connect to one.
+const daemonProbeScript = `if [ -n "$DOCKER_HOST" ]; then
+  addr=${DOCKER_HOST#tcp://}
+  exec bash -c "exec 3<>/dev/tcp/${addr%:*}/${addr##*:}"
+fi
+test -S ` + ctrDockerSocket
+
+const (
+	// ctrDockerSocket is where the daemon socket is mounted in lifecycle containers, the path they look in by default
+	ctrDockerSocket = "/var/run/docker.sock"
+	// ctrDockerCertPath is where TLS certs for a TCP daemon are copied in lifecycle containers
+	ctrDockerCertPath = "/docker-certs"
+)
+
+// daemonAccess describes how a lifecycle container reaches the Docker daemon pack is using
+type daemonAccess struct {
+	binds       []string
+	env         []string
+	networkMode string
+	certPath    string // local dir of TLS certs to copy into the container
+}
+
+// newDaemonAccess works out how lifecycle containers can reach the daemon at host, the address of the client pack is
+// using. certPath and tlsVerify are the DOCKER_CERT_PATH and DOCKER_TLS_VERIFY settings that client was created with.
+func newDaemonAccess(host, certPath string, tlsVerify bool) (*daemonAccess, error) {
+	hostURL, err := client.ParseHostURL(host)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid docker host %s", style.Symbol(host))
+	}
+
+	switch hostURL.Scheme {
+	case "unix":
+		if runtime.GOOS != "linux" {
+			// Docker Desktop runs the daemon in a VM, which exposes it to containers at the default socket path
+			return &daemonAccess{binds: []string{fmt.Sprintf("%s:%s", ctrDockerSocket, ctrDockerSocket)}}, nil
+		}
+		socket := hostURL.Host
+		if _, err := os.Stat(socket); err != nil {
+			return nil, errors.Wrapf(err, "docker socket %s cannot be mounted into lifecycle containers", style.Symbol(socket))
+		}
+		return &daemonAccess{binds: []string{fmt.Sprintf("%s:%s", socket, ctrDockerSocket)}}, nil
+	case "npipe":
+		// Docker for Windows exposes the daemon to Linux containers at the default socket path
+		return &daemonAccess{binds: []string{fmt.Sprintf("%s:%s", ctrDockerSocket, ctrDockerSocket)}}, nil
+	case "tcp", "http", "https":
+		access := &daemonAccess{env: []string{"DOCKER_HOST=tcp://" + hostURL.Host}}
+		if isLoopback(hostURL.Hostname()) {
+			// the daemon only listens on its own host, so the container has to share the host network
+			access.networkMode = "host"
+		}
+		if certPath != "" {
+			access.certPath = certPath
+			access.env = append(access.env, "DOCKER_CERT_PATH="+ctrDockerCertPath)
+			if tlsVerify {
+				access.env = append(access.env, "DOCKER_TLS_VERIFY=1")
+			}
+		}
+		return access, nil
+	default:
+		return nil, fmt.Errorf("docker daemon at %s cannot be reached from lifecycle containers, set %s to a unix socket or tcp address", style.Symbol(host), style.Symbol("DOCKER_HOST"))
+	}
+}
+
+// isRemoteDaemon reports whether the daemon at host may be on another machine, so that host paths cannot be bind
+// mounted into its containers
+func isRemoteDaemon(host string) bool {
+	hostURL, err := client.ParseHostURL(host)
+	if err != nil {
+		return false
+	}
+	switch hostURL.Scheme {
+	case "tcp", "http", "https":
+		return !isLoopback(hostURL.Hostname())
+	default:
+		return false
+	}
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// copyDockerCerts copies the TLS certs for a TCP daemon into the phase container. They are copied rather than bind
+// mounted because the daemon may be on another host.
+func (p *Phase) copyDockerCerts(ctx context.Context) error {
+	certReader := archive.ReadDirAsTar(p.dockerCertPath, ctrDockerCertPath, 0, 0, -1, nil)
+	defer certReader.Close()
+
+	if err := p.runtime.CopyToContainer(ctx, p.ctr.ID, "/", certReader, types.CopyToContainerOptions{}); err != nil {
+		return errors.Wrapf(err, "copy docker certs to '%s' container", p.name)
+	}
+	return nil
+}
+
+// probeDaemon runs a short-lived container of the builder that reaches the daemon the way the phase does, and fails
+// with a clear error when it cannot
+func (p *Phase) probeDaemon(ctx context.Context) error {
+	host := p.runtime.DaemonHost()
+	ctx, cancel := context.WithTimeout(ctx, daemonProbeTimeout)
+	defer cancel()
+
+	ctr, err := p.runtime.ContainerCreate(ctx, &dcontainer.Config{
+		Image:  p.ctrConf.Image,
+		Cmd:    []string{"/bin/sh", "-c", daemonProbeScript},
+		User:   "root",
+		Env:    p.daemonAccess.env,
+		Labels: resource.Labels(resource.PurposePhase),
+	}, &dcontainer.HostConfig{
+		Binds:       p.daemonAccess.binds,
+		NetworkMode: dcontainer.NetworkMode(p.daemonAccess.networkMode),
+	}, nil, "")
+	if err != nil {
+		return errors.Wrap(err, "failed to create docker daemon probe container")
+	}
+	defer p.runtime.ContainerRemove(context.Background(), ctr.ID, types.ContainerRemoveOptions{Force: true})
+
+	var out bytes.Buffer
+	err = container.Run(ctx, p.runtime, ctr.ID, &out, &out)
+	if exitErr, ok := errors.Cause(err).(*container.ExitError); ok && exitErr.StatusCode == 127 {
+		p.logger.Debugf("Unable to check the docker daemon can be reached from lifecycle containers, the builder has no bash")
+		return nil
+	}
+	if err != nil || ctx.Err() != nil {
+		return fmt.Errorf("docker daemon at %s cannot be reached from lifecycle containers, set %s to an address containers can reach", style.Symbol(host), style.Symbol("DOCKER_HOST"))
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	h "github.com/buildpack/pack/testhelpers"
+)
+
+func TestDaemonAccess(t *testing.T) {
+	spec.Run(t, "daemonAccess", testDaemonAccess, spec.Report(report.Terminal{}))
+}
+
+func testDaemonAccess(t *testing.T, when spec.G, it spec.S) {
+	when("#newDaemonAccess", func() {
+		when("the daemon is on a unix socket", func() {
+			var tmpDir string
+
+			it.Before(func() {
+				if runtime.GOOS != "linux" {
+					t.Skip("unix sockets are only mounted as is on linux")
+				}
+
+				var err error
+				tmpDir, err = ioutil.TempDir("", "daemon-access-test")
+				h.AssertNil(t, err)
+			})
+
+			it.After(func() {
+				os.RemoveAll(tmpDir)
+			})
+
+			it("mounts the socket at the default path", func() {
+				socket := filepath.Join(tmpDir, "docker.sock")
+				h.AssertNil(t, ioutil.WriteFile(socket, nil, 0600))
+
+				access, err := newDaemonAccess("unix://"+socket, "", false)
+				h.AssertNil(t, err)
+				h.AssertEq(t, access.binds, []string{socket + ":/var/run/docker.sock"})
+				h.AssertEq(t, access.networkMode, "")
+			})
+
+			it("fails when the socket does not exist", func() {
+				_, err := newDaemonAccess("unix://"+filepath.Join(tmpDir, "missing.sock"), "", false)
+				h.AssertError(t, err, "cannot be mounted into lifecycle containers")
+			})
+		})
+
+		when("the daemon is on tcp", func() {
+			it("passes the host to the container", func() {
+				access, err := newDaemonAccess("tcp://docker.example.com:2375", "", false)
+				h.AssertNil(t, err)
+				h.AssertEq(t, access.env, []string{"DOCKER_HOST=tcp://docker.example.com:2375"})
+				h.AssertEq(t, len(access.binds), 0)
+				h.AssertEq(t, access.networkMode, "")
+			})
+
+			it("uses the host network for a loopback address", func() {
+				access, err := newDaemonAccess("tcp://127.0.0.1:2375", "", false)
+				h.AssertNil(t, err)
+				h.AssertEq(t, access.networkMode, "host")
+			})
+
+			it("copies TLS certs into the container", func() {
+				access, err := newDaemonAccess("tcp://docker.example.com:2376", "/some/certs", true)
+				h.AssertNil(t, err)
+				h.AssertEq(t, access.certPath, "/some/certs")
+				h.AssertEq(t, access.env, []string{
+					"DOCKER_HOST=tcp://docker.example.com:2376",
+					"DOCKER_CERT_PATH=/docker-certs",
+					"DOCKER_TLS_VERIFY=1",
+				})
+			})
+		})
+
+		it("fails for a daemon the container cannot reach", func() {
+			_, err := newDaemonAccess("ssh://user@docker.example.com", "", false)
+			h.AssertError(t, err, "cannot be reached from lifecycle containers")
+		})
+	})
+
+	when("#isRemoteDaemon", func() {
+		it("is only true for a tcp daemon on another host", func() {
+			for host, remote := range map[string]bool{
+				"unix:///var/run/docker.sock":    false,
+				"npipe:////./pipe/docker_engine": false,
+				"tcp://127.0.0.1:2375":           false,
+				"tcp://localhost:2375":           false,
+				"tcp://docker.example.com:2376":  true,
+			} {
+				if isRemoteDaemon(host) != remote {
+					t.Errorf("%s: expected remote to be %t", host, remote)
+				}
+			}
+		})
+	})
+}
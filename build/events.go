@@ -0,0 +1,66 @@
+package build
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/buildpack/pack/buildpack"
+	"github.com/buildpack/pack/container"
+)
+
+type EventType string
+
+const (
+	EventBuilderFetchStarted     EventType = "builder-fetch-started"
+	EventBuilderFetchFinished    EventType = "builder-fetch-finished"
+	EventRunImageFetchStarted    EventType = "run-image-fetch-started"
+	EventRunImageFetchFinished   EventType = "run-image-fetch-finished"
+	EventEphemeralBuilderCreated EventType = "ephemeral-builder-created"
+	EventCacheSelected           EventType = "cache-selected"
+	EventCacheCleared            EventType = "cache-cleared"
+	EventGroupDetected           EventType = "group-detected"
+	EventPhaseStarted            EventType = "phase-started"
+	EventPhaseFinished           EventType = "phase-finished"
+	EventImageExported           EventType = "image-exported"
+)
+
+// Event describes a single step of a build. Only the fields relevant to the event Type are set.
+type Event struct {
+	Type     EventType
+	Time     time.Time
+	Image    string        // fetched image, ephemeral builder or exported image
+	Phase    string        // lifecycle phase, e.g. 'detector'
+	Duration time.Duration // time spent in a fetch or phase, set on finished events
+	ExitCode int           // exit code of the phase container, set on phase finished events
+	Cache    string        // name of the selected or cleared cache
+	Digest   string        // digest of the fetched or exported image, or image ID when exported to the daemon
+
+	Buildpacks []buildpack.BuildpackInfo // buildpack group chosen by the detector
+
+	Err error // error that ended the fetch or phase, if any
+}
+
+// EventHandler receives build events as they happen. It is called synchronously and should not block.
+type EventHandler func(Event)
+
+// Emit sends e to the handler, stamping the event time if it is not set. It is safe to call on a nil handler.
+func (h EventHandler) Emit(e Event) {
+	if h == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	h(e)
+}
+
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if exitErr, ok := errors.Cause(err).(*container.ExitError); ok {
+		return int(exitErr.StatusCode)
+	}
+	return -1
+}
@@ -2,72 +2,171 @@ package build
 
 import (
 	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
 	"math/rand"
+	"path"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/Masterminds/semver"
+	"github.com/buildpack/imgutil"
+	"github.com/docker/docker/api/types"
+	dcontainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/volume"
 	"github.com/docker/docker/client"
+	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/pkg/errors"
 
 	"github.com/buildpack/pack/builder"
 	"github.com/buildpack/pack/cache"
+	"github.com/buildpack/pack/container"
+	"github.com/buildpack/pack/internal/resource"
 	"github.com/buildpack/pack/lifecycle"
 	"github.com/buildpack/pack/logging"
 	"github.com/buildpack/pack/style"
 )
 
 type Lifecycle struct {
-	builder      *builder.Builder
-	logger       logging.Logger
-	docker       *client.Client
-	appPath      string
-	appOnce      *sync.Once
-	httpProxy    string
-	httpsProxy   string
-	noProxy      string
-	LayersVolume string
-	AppVolume    string
+	builder       *builder.Builder
+	platform      lifecycle.Platform
+	logger        logging.Logger
+	runtime       container.Runtime
+	docker        *client.Client // nil unless runtime is the Docker client, needed for caches kept in images
+	appPath       string
+	appExclude    []string
+	appInclude    []string
+	appOnce       *sync.Once
+	env           map[string]string
+	envOnce       *sync.Once
+	bindings      map[string]string
+	caCerts       []byte
+	caCertsOnce   *sync.Once
+	daemonOnce    *sync.Once
+	httpProxy     string
+	httpsProxy    string
+	noProxy       string
+	events        EventHandler
+	keepOnFailure bool
+	keptID        string // names the kept resources of a build, derived from its image, empty unless keeping
+	resumeFrom    string
+	LayersVolume  string
+	AppVolume     string
+	EnvVolume     string // empty when there is no build-time env
+	CACertsVolume string // empty when there are no CA certs
 }
 
 type Cache interface {
 	Name() string
+	Type() cache.Type
 	Clear(context.Context) error
+	Empty(context.Context) (bool, error)
 }
 
 func init() {
 	rand.Seed(time.Now().UTC().UnixNano())
 }
 
-func NewLifecycle(docker *client.Client, logger logging.Logger) *Lifecycle {
-	return &Lifecycle{logger: logger, docker: docker}
+// NewLifecycle returns a Lifecycle that runs phases with runtime, usually the Docker client
+func NewLifecycle(runtime container.Runtime, logger logging.Logger) *Lifecycle {
+	docker, _ := runtime.(*client.Client)
+	return &Lifecycle{logger: logger, runtime: runtime, docker: docker}
 }
 
 type LifecycleOptions struct {
-	AppPath    string
-	Image      name.Reference
-	Builder    *builder.Builder
-	RunImage   string
-	ClearCache bool
-	Publish    bool
-	HTTPProxy  string
-	HTTPSProxy string
-	NoProxy    string
-}
-
-func (l *Lifecycle) Execute(ctx context.Context, opts LifecycleOptions) error {
+	AppPath        string
+	AppExclude     []string // gitignore-style patterns for app files to leave out
+	AppInclude     []string // when set, only app files matching these patterns are used
+	Image          name.Reference
+	AdditionalTags []string          // extra tags the image is exported with
+	Labels         map[string]string // labels added to the exported image before it is tagged and reported
+	Builder        *builder.Builder
+	RunImage       string
+	Env            map[string]string // build-time env, as platform env files, seen by the detector and builder only
+	Bindings       map[string]string // host files and dirs, by path below /platform/bindings, mounted read-only for the detector and builder only
+	CACerts        []byte            // PEM encoded CA certs trusted by every phase, in addition to those of the builder
+	ClearCache     bool
+	Publish        bool
+	HTTPProxy      string
+	HTTPSProxy     string
+	NoProxy        string
+	EventHandler   EventHandler
+	CacheName      string   // name of the daemon build cache to use instead of one keyed on Image, to share it
+	CacheFallbacks []string // names of daemon build caches to restore from, in order, when the build cache is empty
+	KeepOnFailure  bool     // keep the volumes and failed container of a failed build for inspection, unless it mounts the env
+	ResumeFrom     string   // phase to resume a kept build from, earlier phases are skipped
+}
+
+// resumablePhases are the phases a kept build can be resumed from, in the order they run
+var resumablePhases = []string{"detect", "restore", "analyze", "build", "export", "cache"}
+
+// phaseNames maps the lifecycle binaries run by Execute to the phase names users resume from
+var phaseNames = map[string]string{
+	"detector": "detect",
+	"restorer": "restore",
+	"analyzer": "analyze",
+	"builder":  "build",
+	"exporter": "export",
+	"cacher":   "cache",
+}
+
+func (l *Lifecycle) Execute(ctx context.Context, opts LifecycleOptions) (err error) {
+	if opts.ResumeFrom != "" && phaseIndex(opts.ResumeFrom) < 0 {
+		return fmt.Errorf("cannot resume from unknown phase %s, must be one of %s", style.Symbol(opts.ResumeFrom), strings.Join(resumablePhases, ", "))
+	}
+
 	l.Setup(opts)
-	defer l.Cleanup()
+	if err := l.checkBindings(); err != nil {
+		return err
+	}
+	if l.keepOnFailure {
+		// the failed container of an earlier kept build of the image mounts its volumes, so it goes first
+		if err := l.removeKeptContainers(ctx); err != nil {
+			return err
+		}
+	}
+	if l.resumeFrom != "" {
+		if err := l.checkKeptVolumes(ctx); err != nil {
+			return err
+		}
+	} else {
+		if l.keepOnFailure {
+			// volumes are created if missing, so those of an earlier kept build would be reused otherwise
+			if err := l.Cleanup(); err != nil {
+				return errors.Wrap(err, "removing earlier kept build")
+			}
+		}
+		if err := l.createVolumes(ctx); err != nil {
+			return err
+		}
+	}
+	defer func() {
+		if err != nil && l.keepOnFailure {
+			l.logger.Infof("Keeping layers volume %s and app volume %s for inspection", style.Symbol(l.LayersVolume), style.Symbol(l.AppVolume))
+			l.logger.Infof("Rerun with %s to reuse them", style.Symbol("--resume-from <phase>"))
+			return
+		}
+		l.Cleanup()
+	}()
+	if err := l.createTransientVolumes(ctx); err != nil {
+		return err
+	}
+	defer func() { l.removeTransientVolumes(err != nil && l.keepOnFailure) }()
 
 	var buildCache, launchCache Cache
-	if l.supportsVolumeCache() {
-		buildCache = cache.NewVolumeCache(opts.Image, "build", l.docker)
-		launchCache = cache.NewVolumeCache(opts.Image, "launch", l.docker)
-		l.logger.Debugf("Using build cache volume %s", style.Symbol(buildCache.Name()))
-	} else {
-		buildCache = cache.NewImageCache(opts.Image, l.docker)
-		l.logger.Debugf("Using build cache image %s", style.Symbol(buildCache.Name()))
+	if buildCache, err = l.newDaemonBuildCache(opts.Image, opts.CacheName); err != nil {
+		return err
+	}
+	l.logger.Debugf("Using build cache %s %s", buildCache.Type(), style.Symbol(buildCache.Name()))
+	l.events.Emit(Event{Type: EventCacheSelected, Cache: buildCache.Name()})
+	if l.platform.LaunchCache() {
+		launchCache = cache.NewVolumeCache(opts.Image, "launch", l.runtime)
+		l.events.Emit(Event{Type: EventCacheSelected, Cache: launchCache.Name()})
 	}
 
 	if opts.ClearCache {
@@ -75,18 +174,28 @@ func (l *Lifecycle) Execute(ctx context.Context, opts LifecycleOptions) error {
 			return errors.Wrap(err, "clearing build cache")
 		}
 		l.logger.Debugf("Build cache %s cleared", style.Symbol(buildCache.Name()))
+		l.events.Emit(Event{Type: EventCacheCleared, Cache: buildCache.Name()})
+	}
+
+	restoreCache := buildCache
+	if !opts.ClearCache && len(opts.CacheFallbacks) > 0 {
+		if restoreCache, err = l.resolveRestoreCache(ctx, buildCache, opts.CacheFallbacks); err != nil {
+			return err
+		}
 	}
 
-	lifecycleVersion := l.builder.GetLifecycleVersion()
-	if lifecycleVersion == nil {
-		l.logger.Warn("lifecycle version unknown")
-		lifecycleVersion = semver.MustParse(lifecycle.DefaultLifecycleVersion)
+	if err := createCacheVolumes(ctx, buildCache, launchCache); err != nil {
+		return err
+	}
+
+	if lifecycleVersion := l.builder.GetLifecycleVersion(); lifecycleVersion == nil {
+		l.logger.Warn("lifecycle version unknown, keeping the build cache in an image")
 	} else {
 		l.logger.Debugf("Executing lifecycle version %s", style.Symbol(lifecycleVersion.String()))
 	}
 
 	l.logger.Debug(style.Step("DETECTING"))
-	if err := l.Detect(ctx); err != nil {
+	if err := l.runPhase("detector", func() error { return l.Detect(ctx) }); err != nil {
 		return err
 	}
 
@@ -94,63 +203,486 @@ func (l *Lifecycle) Execute(ctx context.Context, opts LifecycleOptions) error {
 	if opts.ClearCache {
 		l.logger.Debug("Skipping 'restore' due to clearing cache")
 	} else {
-		if err := l.Restore(ctx, l.supportsVolumeCache(), buildCache.Name()); err != nil {
+		if err := l.runPhase("restorer", func() error { return l.Restore(ctx, restoreCache) }); err != nil {
 			return err
 		}
 	}
 
 	l.logger.Debug(style.Step("ANALYZING"))
-	if opts.ClearCache && lifecycleVersion.LessThan(semver.MustParse("0.3.0")) {
+	if _, canSkipLayers := l.platform.SkipLayersArgs(); opts.ClearCache && !canSkipLayers {
 		l.logger.Debug("Skipping 'analyze' due to clearing cache")
 	} else {
-		if err := l.Analyze(ctx, opts.Image.Name(), opts.Publish, opts.ClearCache); err != nil {
+		if err := l.runPhase("analyzer", func() error { return l.Analyze(ctx, opts.Image.Name(), opts.Publish, opts.ClearCache) }); err != nil {
 			return err
 		}
 	}
 
 	l.logger.Debug(style.Step("BUILDING"))
-	if err := l.Build(ctx); err != nil {
+	if err := l.runPhase("builder", func() error { return l.Build(ctx) }); err != nil {
 		return err
 	}
 
 	l.logger.Debug(style.Step("EXPORTING"))
 	launchCacheName := ""
-	if l.supportsVolumeCache() {
+	if launchCache != nil {
 		launchCacheName = launchCache.Name()
 	}
-	if err := l.Export(ctx, opts.Image.Name(), opts.RunImage, opts.Publish, launchCacheName); err != nil {
+	if err := l.runPhase("exporter", func() error {
+		if err := l.Export(ctx, opts.Image.Name(), opts.RunImage, opts.Publish, launchCacheName); err != nil {
+			return err
+		}
+		if len(opts.Labels) > 0 {
+			if err := l.labelImage(ctx, opts.Image.Name(), opts.Labels, opts.Publish); err != nil {
+				return errors.Wrapf(err, "labelling image %s", style.Symbol(opts.Image.Name()))
+			}
+		}
+		for _, tag := range opts.AdditionalTags {
+			if err := l.tagImage(ctx, opts.Image.Name(), tag, opts.Publish); err != nil {
+				return errors.Wrapf(err, "tagging image %s as %s", style.Symbol(opts.Image.Name()), style.Symbol(tag))
+			}
+		}
+		return nil
+	}); err != nil {
 		return err
 	}
+	l.reportExportedImages(ctx, append([]string{opts.Image.Name()}, opts.AdditionalTags...), opts.Publish)
 
 	l.logger.Debug(style.Step("CACHING"))
-	if err := l.Cache(ctx, l.supportsVolumeCache(), buildCache.Name()); err != nil {
+	if err := l.runPhase("cacher", func() error { return l.Cache(ctx, buildCache) }); err != nil {
 		return err
 	}
+	if imageCache, ok := buildCache.(*cache.ImageCache); ok {
+		if err := imageCache.Label(ctx); err != nil {
+			l.logger.Warnf("Unable to label build cache image %s: %s", style.Symbol(imageCache.Name()), err)
+		}
+	}
 	return nil
 }
 
+// newDaemonBuildCache returns the build cache in the daemon called cacheName, or keyed on image when that is empty
+func (l *Lifecycle) newDaemonBuildCache(image name.Reference, cacheName string) (Cache, error) {
+	volumeCache := l.platform.BuildCache() == lifecycle.VolumeCacheMode
+	if !volumeCache && l.docker == nil {
+		return nil, fmt.Errorf("lifecycle version %s keeps the build cache in an image, which needs a Docker daemon", style.Symbol(l.lifecycleVersion()))
+	}
+	switch {
+	case volumeCache && cacheName != "":
+		return cache.NewNamedVolumeCache(cacheName, "build", l.runtime), nil
+	case volumeCache:
+		return cache.NewVolumeCache(image, "build", l.runtime), nil
+	case cacheName != "":
+		return cache.NewNamedImageCache(cacheName, l.docker), nil
+	default:
+		return cache.NewImageCache(image, l.docker), nil
+	}
+}
+
+// lifecycleVersion returns the version of the lifecycle of the builder for messages
+func (l *Lifecycle) lifecycleVersion() string {
+	if version := l.builder.GetLifecycleVersion(); version != nil {
+		return version.String()
+	}
+	return "unknown"
+}
+
+// resolveRestoreCache returns the cache to restore from, which is buildCache unless it is empty and one of the named
+// fallbacks is not
+func (l *Lifecycle) resolveRestoreCache(ctx context.Context, buildCache Cache, fallbacks []string) (Cache, error) {
+	empty, err := buildCache.Empty(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "checking build cache %s", style.Symbol(buildCache.Name()))
+	}
+	if !empty {
+		return buildCache, nil
+	}
+
+	for _, fallback := range fallbacks {
+		fallbackCache, err := l.newDaemonBuildCache(nil, fallback)
+		if err != nil {
+			return nil, err
+		}
+		empty, err := fallbackCache.Empty(ctx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "checking fallback cache %s", style.Symbol(fallback))
+		}
+		if !empty {
+			l.logger.Infof("Build cache is empty, restoring from cache %s", style.Symbol(fallback))
+			return fallbackCache, nil
+		}
+		l.logger.Debugf("Fallback cache %s is empty", style.Symbol(fallback))
+	}
+	return buildCache, nil
+}
+
+// createCacheVolumes creates any volume caches with labels recording their image, before a phase mounts them and
+// the daemon creates them without
+func createCacheVolumes(ctx context.Context, caches ...Cache) error {
+	for _, c := range caches {
+		if volumeCache, ok := c.(*cache.VolumeCache); ok {
+			if err := volumeCache.Create(ctx); err != nil {
+				return errors.Wrapf(err, "creating cache volume %s", style.Symbol(volumeCache.Name()))
+			}
+		}
+	}
+	return nil
+}
+
+// ExecuteDetect runs only the detect phase against the app and returns the selected group and build plan. Image,
+// RunImage and the cache options are ignored.
+func (l *Lifecycle) ExecuteDetect(ctx context.Context, opts LifecycleOptions) (*DetectResult, error) {
+	l.Setup(opts)
+	defer l.Cleanup()
+	if err := l.checkBindings(); err != nil {
+		return nil, err
+	}
+	if err := l.createVolumes(ctx); err != nil {
+		return nil, err
+	}
+	if err := l.createTransientVolumes(ctx); err != nil {
+		return nil, err
+	}
+	defer l.removeTransientVolumes(false)
+
+	l.logger.Debug(style.Step("DETECTING"))
+	var result *DetectResult
+	err := l.runPhase("detector", func() error {
+		var err error
+		result, err = l.DetectPlan(ctx)
+		return err
+	})
+	return result, err
+}
+
+// ExecuteShell opens an interactive shell as the CNB user in the environment the builder phase sees, with the app
+// and layers volumes mounted. When detect is set the detector is run first so the selected group is in place.
+func (l *Lifecycle) ExecuteShell(ctx context.Context, opts LifecycleOptions, detect bool, in io.Reader, out io.Writer) error {
+	l.Setup(opts)
+	defer l.Cleanup()
+	if err := l.checkBindings(); err != nil {
+		return err
+	}
+	if err := l.createVolumes(ctx); err != nil {
+		return err
+	}
+	if err := l.createTransientVolumes(ctx); err != nil {
+		return err
+	}
+	defer l.removeTransientVolumes(false)
+
+	if detect {
+		l.logger.Debug(style.Step("DETECTING"))
+		if err := l.runPhase("detector", func() error { return l.Detect(ctx) }); err != nil {
+			return err
+		}
+	}
+
+	l.logger.Debug(style.Step("SHELL"))
+	return l.Shell(ctx, in, out)
+}
+
 func (l *Lifecycle) Setup(opts LifecycleOptions) {
-	l.LayersVolume = "pack-layers-" + randString(10)
-	l.AppVolume = "pack-app-" + randString(10)
+	l.keepOnFailure = opts.KeepOnFailure || opts.ResumeFrom != ""
+	l.resumeFrom = opts.ResumeFrom
+	if l.keepOnFailure {
+		// kept volumes are named after the image so a later build of it can find them
+		sum := sha256.Sum256([]byte(opts.Image.Name()))
+		l.keptID = fmt.Sprintf("%x", sum[:6])
+		l.LayersVolume = "pack-layers-" + l.keptID
+		l.AppVolume = "pack-app-" + l.keptID
+	} else {
+		l.keptID = ""
+		l.LayersVolume = "pack-layers-" + randString(10)
+		l.AppVolume = "pack-app-" + randString(10)
+	}
 	l.appPath = opts.AppPath
+	l.appExclude = opts.AppExclude
+	l.appInclude = opts.AppInclude
 	l.appOnce = &sync.Once{}
+	if l.resumeFrom != "" {
+		// the app volume of the kept build is reused as is
+		l.appOnce.Do(func() {})
+	}
+	l.env = opts.Env
+	l.envOnce = &sync.Once{}
+	l.bindings = opts.Bindings
+	l.caCerts = opts.CACerts
+	l.caCertsOnce = &sync.Once{}
+	l.daemonOnce = &sync.Once{}
+	l.CACertsVolume = ""
+	if len(l.caCerts) > 0 {
+		l.CACertsVolume = "pack-ca-certs-" + randString(10)
+		if l.keepOnFailure {
+			// a kept failed container mounts the CA certs volume, so it is kept with the build
+			l.CACertsVolume = "pack-ca-certs-" + l.keptID
+		}
+	}
+	l.EnvVolume = ""
+	if len(l.env) > 0 {
+		// the env may hold secrets, so it gets a volume of its own that is never kept
+		l.EnvVolume = "pack-env-" + randString(10)
+	}
 	l.builder = opts.Builder
+	l.platform = opts.Builder.Platform()
 	l.httpProxy = opts.HTTPProxy
 	l.httpsProxy = opts.HTTPSProxy
 	l.noProxy = opts.NoProxy
+	l.events = opts.EventHandler
 }
 
 func (l *Lifecycle) Cleanup() error {
 	var reterr error
-	if err := l.docker.VolumeRemove(context.Background(), l.LayersVolume, true); err != nil {
+	if err := l.runtime.VolumeRemove(context.Background(), l.LayersVolume, true); err != nil {
 		reterr = errors.Wrapf(err, "failed to clean up layers volume %s", l.LayersVolume)
 	}
-	if err := l.docker.VolumeRemove(context.Background(), l.AppVolume, true); err != nil {
+	if err := l.runtime.VolumeRemove(context.Background(), l.AppVolume, true); err != nil {
 		reterr = errors.Wrapf(err, "failed to clean up app volume %s", l.AppVolume)
 	}
+	if l.keepOnFailure && l.CACertsVolume != "" {
+		if err := l.runtime.VolumeRemove(context.Background(), l.CACertsVolume, true); err != nil {
+			reterr = errors.Wrapf(err, "failed to clean up CA certs volume %s", l.CACertsVolume)
+		}
+	}
 	return reterr
 }
 
+// createVolumes creates the layers and app volumes labelled as pack resources, before a phase mounts them and the
+// daemon creates them without
+func (l *Lifecycle) createVolumes(ctx context.Context) error {
+	for volumeName, purpose := range map[string]string{l.LayersVolume: resource.PurposeLayers, l.AppVolume: resource.PurposeApp} {
+		labels := resource.Labels(purpose)
+		if l.keepOnFailure {
+			labels[resource.KeepLabel] = "true"
+		}
+		if _, err := l.runtime.VolumeCreate(ctx, volume.VolumeCreateBody{Name: volumeName, Labels: labels}); err != nil {
+			return errors.Wrapf(err, "creating volume %s", style.Symbol(volumeName))
+		}
+	}
+	return nil
+}
+
+// createTransientVolumes creates the volumes the build-time env and CA certs are kept in, if there are any. The env
+// volume is removed by removeTransientVolumes even when a failed build is kept, which is why a failed phase that
+// mounts it is not kept. The CA certs volume is kept along with a failed build, as any failed phase mounts it.
+func (l *Lifecycle) createTransientVolumes(ctx context.Context) error {
+	for volumeName, purpose := range map[string]string{l.EnvVolume: resource.PurposeEnv, l.CACertsVolume: resource.PurposeCACerts} {
+		if volumeName == "" {
+			continue
+		}
+		labels := resource.Labels(purpose)
+		if l.keepOnFailure && volumeName == l.CACertsVolume {
+			labels[resource.KeepLabel] = "true"
+		}
+		if _, err := l.runtime.VolumeCreate(ctx, volume.VolumeCreateBody{Name: volumeName, Labels: labels}); err != nil {
+			return errors.Wrapf(err, "creating volume %s", style.Symbol(volumeName))
+		}
+	}
+	return nil
+}
+
+func (l *Lifecycle) removeTransientVolumes(kept bool) {
+	for _, volumeName := range []string{l.EnvVolume, l.CACertsVolume} {
+		if volumeName == "" || (kept && volumeName == l.CACertsVolume) {
+			continue
+		}
+		if err := l.runtime.VolumeRemove(context.Background(), volumeName, true); err != nil {
+			l.logger.Warnf("Unable to remove volume %s: %s", style.Symbol(volumeName), err)
+		}
+	}
+}
+
+// checkBindings errors when the build has secrets or bindings and the daemon may be on another machine, where the
+// host paths they are bind mounted from do not exist
+func (l *Lifecycle) checkBindings() error {
+	if len(l.bindings) > 0 && isRemoteDaemon(l.runtime.DaemonHost()) {
+		return fmt.Errorf("secrets and bindings are mounted from the local filesystem, so they cannot be used with the remote docker daemon at %s", style.Symbol(l.runtime.DaemonHost()))
+	}
+	return nil
+}
+
+// bindingBinds returns the binds that mount the secrets and bindings of the build read-only below /platform/bindings
+func (l *Lifecycle) bindingBinds() []string {
+	var targets []string
+	for target := range l.bindings {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+
+	var binds []string
+	for _, target := range targets {
+		binds = append(binds, fmt.Sprintf("%s:%s:ro", l.bindings[target], path.Join(platformBindingsDir, target)))
+	}
+	return binds
+}
+
+// keptContainerName returns the name of the container of phase when keeping a failed build, which is the same for
+// every build of the image
+func (l *Lifecycle) keptContainerName(phase string) string {
+	return fmt.Sprintf("pack-kept-%s-%s", l.keptID, phase)
+}
+
+// removeKeptContainers removes the failed container kept by an earlier build of the image, if there is one
+func (l *Lifecycle) removeKeptContainers(ctx context.Context) error {
+	var phases []string
+	for phase := range phaseNames {
+		phases = append(phases, phase)
+	}
+	sort.Strings(phases)
+
+	for _, phase := range phases {
+		ctrName := l.keptContainerName(phase)
+		err := l.runtime.ContainerRemove(ctx, ctrName, types.ContainerRemoveOptions{Force: true})
+		if err != nil && !client.IsErrNotFound(err) {
+			return errors.Wrapf(err, "removing kept container %s", style.Symbol(ctrName))
+		}
+	}
+	return nil
+}
+
+// checkKeptVolumes ensures the volumes of a kept build exist before resuming it
+func (l *Lifecycle) checkKeptVolumes(ctx context.Context) error {
+	for _, volume := range []string{l.LayersVolume, l.AppVolume} {
+		if _, err := l.runtime.VolumeInspect(ctx, volume); err != nil {
+			if client.IsErrNotFound(err) {
+				return fmt.Errorf("no kept build to resume, volume %s does not exist, build with %s first", style.Symbol(volume), style.Symbol("--keep-on-failure"))
+			}
+			return errors.Wrapf(err, "inspecting volume %s", style.Symbol(volume))
+		}
+	}
+	l.logger.Debugf("Resuming from %s using layers volume %s and app volume %s", style.Symbol(l.resumeFrom), style.Symbol(l.LayersVolume), style.Symbol(l.AppVolume))
+	return nil
+}
+
+func phaseIndex(name string) int {
+	for i, phase := range resumablePhases {
+		if phase == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (l *Lifecycle) runPhase(name string, run func() error) error {
+	if l.resumeFrom != "" && phaseIndex(phaseNames[name]) < phaseIndex(l.resumeFrom) {
+		l.logger.Debugf("Skipping %s, resuming from %s", style.Symbol(name), style.Symbol(l.resumeFrom))
+		return nil
+	}
+
+	start := time.Now()
+	l.events.Emit(Event{Type: EventPhaseStarted, Phase: name})
+	err := run()
+	l.events.Emit(Event{
+		Type:     EventPhaseFinished,
+		Phase:    name,
+		Duration: time.Since(start),
+		ExitCode: exitCode(err),
+		Err:      err,
+	})
+	return err
+}
+
+// reportExportedImages logs the digest of each exported tag and emits an event for it
+func (l *Lifecycle) reportExportedImages(ctx context.Context, repoNames []string, publish bool) {
+	for _, repoName := range repoNames {
+		digest, err := l.imageDigest(ctx, repoName, publish)
+		if err != nil {
+			l.logger.Debugf("Unable to determine digest of image %s: %s", style.Symbol(repoName), err)
+		} else {
+			l.logger.Infof("Exported %s with digest %s", style.Symbol(repoName), style.Symbol(digest))
+		}
+		l.events.Emit(Event{Type: EventImageExported, Image: repoName, Digest: digest})
+	}
+}
+
+// labelImage adds labels to the exported image repoName. In the daemon they are added by committing a container of it,
+// which reuses its layers, rather than by saving it again. In a registry only its config and manifest are written.
+func (l *Lifecycle) labelImage(ctx context.Context, repoName string, labels map[string]string, publish bool) error {
+	if !publish {
+		// the container is never started, and is not labelled as the image committed from it would get its labels
+		ctr, err := l.runtime.ContainerCreate(ctx, &dcontainer.Config{Image: repoName}, nil, nil, "")
+		if err != nil {
+			return err
+		}
+		defer l.runtime.ContainerRemove(context.Background(), ctr.ID, types.ContainerRemoveOptions{Force: true})
+
+		_, err = l.runtime.ContainerCommit(ctx, ctr.ID, types.ContainerCommitOptions{
+			Reference: repoName,
+			Config:    &dcontainer.Config{Labels: labels},
+		})
+		return err
+	}
+
+	ref, err := name.ParseReference(repoName, name.WeakValidation)
+	if err != nil {
+		return err
+	}
+	auth, err := authn.DefaultKeychain.Resolve(ref.Context().Registry)
+	if err != nil {
+		return err
+	}
+	img, err := remote.Image(ref, remote.WithAuth(auth))
+	if err != nil {
+		return err
+	}
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return err
+	}
+	config := *cfg.Config.DeepCopy()
+	if config.Labels == nil {
+		config.Labels = map[string]string{}
+	}
+	for k, v := range labels {
+		config.Labels[k] = v
+	}
+	img, err = mutate.Config(img, config)
+	if err != nil {
+		return err
+	}
+	return remote.Write(ref, img, remote.WithAuth(auth))
+}
+
+// tagImage points tag at the exported image repoName, in the registry when publishing and in the daemon otherwise
+func (l *Lifecycle) tagImage(ctx context.Context, repoName, tag string, publish bool) error {
+	if !publish {
+		return l.runtime.ImageTag(ctx, repoName, tag)
+	}
+
+	ref, err := name.ParseReference(repoName, name.WeakValidation)
+	if err != nil {
+		return err
+	}
+	tagRef, err := name.ParseReference(tag, name.WeakValidation)
+	if err != nil {
+		return err
+	}
+	auth, err := authn.DefaultKeychain.Resolve(ref.Context().Registry)
+	if err != nil {
+		return err
+	}
+	img, err := remote.Image(ref, remote.WithAuth(auth))
+	if err != nil {
+		return err
+	}
+	// additional tags are on the same registry, so only the manifest is written
+	return remote.Write(tagRef, img, remote.WithAuth(auth))
+}
+
+// imageDigest returns the registry digest of a published image, or the image ID of an image in the daemon
+func (l *Lifecycle) imageDigest(ctx context.Context, repoName string, publish bool) (string, error) {
+	if publish {
+		img, err := imgutil.NewRemoteImage(repoName, authn.DefaultKeychain)
+		if err != nil {
+			return "", err
+		}
+		return img.Digest()
+	}
+	inspect, _, err := l.runtime.ImageInspectWithRaw(ctx, repoName)
+	if err != nil {
+		return "", err
+	}
+	return inspect.ID, nil
+}
+
 func randString(n int) string {
 	b := make([]byte, n)
 	for i := range b {
@@ -158,10 +690,3 @@ func randString(n int) string {
 	}
 	return string(b)
 }
-
-func (l *Lifecycle) supportsVolumeCache() bool {
-	if l.builder.GetLifecycleVersion() == nil {
-		return false
-	}
-	return l.builder.GetLifecycleVersion().Compare(semver.MustParse("0.2.0")) >= 0
-}
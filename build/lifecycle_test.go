@@ -0,0 +1,521 @@
+package build_test
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/fatih/color"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpack/pack/build"
+	"github.com/buildpack/pack/builder"
+	"github.com/buildpack/pack/cache"
+	"github.com/buildpack/pack/internal/mocks"
+	h "github.com/buildpack/pack/testhelpers"
+)
+
+func TestLifecycle(t *testing.T) {
+	color.NoColor = true
+	spec.Run(t, "Lifecycle", testLifecycle, spec.Parallel(), spec.Report(report.Terminal{}))
+}
+
+func testLifecycle(t *testing.T, when spec.G, it spec.S) {
+	var (
+		subject *build.Lifecycle
+		runtime *mocks.FakeRuntime
+		opts    build.LifecycleOptions
+		outBuf  bytes.Buffer
+	)
+
+	it.Before(func() {
+		runtime = mocks.NewFakeRuntime()
+		subject = build.NewLifecycle(runtime, mocks.NewMockLogger(&outBuf))
+
+		builderImage := mocks.NewFakeBuilderImage(t, "example.com/some/builder:tag", nil, builder.Config{
+			Stack: builder.StackConfig{ID: "some.stack.id"},
+		})
+		bldr, err := builder.GetBuilder(builderImage)
+		h.AssertNil(t, err)
+
+		imageRef, err := name.ParseReference("some/app", name.WeakValidation)
+		h.AssertNil(t, err)
+
+		opts = build.LifecycleOptions{
+			AppPath:  filepath.Join("testdata", "fake-app"),
+			Image:    imageRef,
+			Builder:  bldr,
+			RunImage: "some/run",
+		}
+	})
+
+	// phases returns the containers of lifecycle phases, leaving out the daemon probe
+	phases := func() []*mocks.FakeContainer {
+		var ctrs []*mocks.FakeContainer
+		for _, ctr := range runtime.Containers() {
+			if strings.HasPrefix(ctr.Config.Cmd[0], "/lifecycle/") {
+				ctrs = append(ctrs, ctr)
+			}
+		}
+		return ctrs
+	}
+
+	phaseNames := func() []string {
+		var names []string
+		for _, ctr := range phases() {
+			names = append(names, ctr.Config.Cmd[0])
+		}
+		return names
+	}
+
+	when("#Execute", func() {
+		it("runs each phase in a container of the builder", func() {
+			h.AssertNil(t, subject.Execute(context.Background(), opts))
+
+			h.AssertEq(t, phaseNames(), []string{
+				"/lifecycle/detector",
+				"/lifecycle/restorer",
+				"/lifecycle/analyzer",
+				"/lifecycle/builder",
+				"/lifecycle/exporter",
+				"/lifecycle/cacher",
+			})
+			for _, ctr := range runtime.Containers() {
+				h.AssertEq(t, ctr.Config.Image, "example.com/some/builder:tag")
+				h.AssertEq(t, ctr.Started, true)
+				h.AssertEq(t, ctr.Removed, true)
+			}
+		})
+
+		it("copies the app into the first phase", func() {
+			h.AssertNil(t, subject.Execute(context.Background(), opts))
+
+			detector := runtime.Containers()[0]
+			if _, ok := detector.Files["/workspace/fake-app-file"]; !ok {
+				t.Fatalf("expected app to be copied to the detector, got files %v", detector.Files)
+			}
+		})
+
+		it("removes the layers and app volumes but keeps the caches", func() {
+			h.AssertNil(t, subject.Execute(context.Background(), opts))
+
+			h.AssertEq(t, runtime.Volumes(), []string{
+				cache.NewVolumeCache(opts.Image, "build", runtime).Name(),
+				cache.NewVolumeCache(opts.Image, "launch", runtime).Name(),
+			})
+		})
+
+		it("keeps the build cache in an image when the lifecycle version is unknown, which needs a Docker daemon", func() {
+			builderImage := mocks.NewFakeBuilderImage(t, "example.com/some/builder:tag", nil, builder.Config{
+				Stack: builder.StackConfig{ID: "some.stack.id"},
+			})
+			h.AssertNil(t, builderImage.SetLabel("io.buildpacks.builder.metadata", `{"lifecycle": {}}`))
+			bldr, err := builder.GetBuilder(builderImage)
+			h.AssertNil(t, err)
+			opts.Builder = bldr
+
+			err = subject.Execute(context.Background(), opts)
+			h.AssertError(t, err, "lifecycle version 'unknown' keeps the build cache in an image, which needs a Docker daemon")
+			h.AssertEq(t, len(runtime.Containers()), 0)
+		})
+
+		it("reports the ID of the exported image", func() {
+			runtime.AddImage("index.docker.io/some/app:latest", types.ImageInspect{ID: "sha256:some-image-id"})
+			var digests []string
+			opts.EventHandler = func(e build.Event) {
+				if e.Type == build.EventImageExported {
+					digests = append(digests, e.Digest)
+				}
+			}
+
+			h.AssertNil(t, subject.Execute(context.Background(), opts))
+			h.AssertEq(t, digests, []string{"sha256:some-image-id"})
+		})
+
+		it("exports the image under its name only and tags it with each additional tag in the daemon", func() {
+			runtime.AddImage("index.docker.io/some/app:latest", types.ImageInspect{ID: "sha256:some-image-id"})
+			opts.AdditionalTags = []string{"index.docker.io/some/app:v1", "index.docker.io/some/app:main"}
+			exported := map[string]string{}
+			opts.EventHandler = func(e build.Event) {
+				if e.Type == build.EventImageExported {
+					exported[e.Image] = e.Digest
+				}
+			}
+
+			h.AssertNil(t, subject.Execute(context.Background(), opts))
+
+			exporter := phases()[4]
+			h.AssertEq(t, exporter.Config.Cmd[0], "/lifecycle/exporter")
+			h.AssertEq(t, exporter.Config.Cmd[len(exporter.Config.Cmd)-1], "index.docker.io/some/app:latest")
+			h.AssertNotContains(t, strings.Join(exporter.Config.Cmd, " "), "some/app:v1")
+			h.AssertEq(t, exported, map[string]string{
+				"index.docker.io/some/app:latest": "sha256:some-image-id",
+				"index.docker.io/some/app:v1":     "sha256:some-image-id",
+				"index.docker.io/some/app:main":   "sha256:some-image-id",
+			})
+		})
+
+		it("labels the exported image by committing a container of it before tagging and reporting it", func() {
+			runtime.AddImage("index.docker.io/some/app:latest", types.ImageInspect{ID: "sha256:some-image-id"})
+			opts.AdditionalTags = []string{"index.docker.io/some/app:v1"}
+			opts.Labels = map[string]string{"some.label": "some-value"}
+			exported := map[string]string{}
+			opts.EventHandler = func(e build.Event) {
+				if e.Type == build.EventImageExported {
+					exported[e.Image] = e.Digest
+				}
+			}
+
+			h.AssertNil(t, subject.Execute(context.Background(), opts))
+
+			labelled, _, err := runtime.ImageInspectWithRaw(context.Background(), "index.docker.io/some/app:v1")
+			h.AssertNil(t, err)
+			h.AssertEq(t, labelled.Parent, "sha256:some-image-id")
+			h.AssertEq(t, labelled.Config.Labels["some.label"], "some-value")
+			h.AssertEq(t, exported, map[string]string{
+				"index.docker.io/some/app:latest": labelled.ID,
+				"index.docker.io/some/app:v1":     labelled.ID,
+			})
+			for _, ctr := range runtime.Containers() {
+				h.AssertEq(t, ctr.Removed, true)
+			}
+		})
+
+		it("passes -skip-layers to the analyzer and skips the restorer when clearing the cache", func() {
+			opts.ClearCache = true
+			h.AssertNil(t, subject.Execute(context.Background(), opts))
+
+			h.AssertEq(t, phaseNames(), []string{
+				"/lifecycle/detector",
+				"/lifecycle/analyzer",
+				"/lifecycle/builder",
+				"/lifecycle/exporter",
+				"/lifecycle/cacher",
+			})
+			h.AssertSliceContains(t, phases()[1].Config.Cmd, "-skip-layers")
+		})
+
+		when("phases have daemon access", func() {
+			probes := func() []*mocks.FakeContainer {
+				var ctrs []*mocks.FakeContainer
+				for _, ctr := range runtime.Containers() {
+					if ctr.Config.Cmd[0] == "/bin/sh" {
+						ctrs = append(ctrs, ctr)
+					}
+				}
+				return ctrs
+			}
+
+			it("probes the daemon once, the way the phases reach it, before the first of them", func() {
+				h.AssertNil(t, subject.Execute(context.Background(), opts))
+
+				h.AssertEq(t, len(probes()), 1)
+				probe := probes()[0]
+				h.AssertEq(t, runtime.Containers()[1].ID, probe.ID)
+				h.AssertEq(t, probe.Config.Env, []string{"DOCKER_HOST=tcp://127.0.0.1:2375"})
+				h.AssertEq(t, string(probe.HostConfig.NetworkMode), "host")
+				h.AssertEq(t, probe.Removed, true)
+			})
+
+			it("fails before running them when the probe cannot reach the daemon", func() {
+				runtime.OnStart = func(ctr *mocks.FakeContainer) {
+					if ctr.Config.Cmd[0] == "/bin/sh" {
+						ctr.ExitCode = 1
+					}
+				}
+
+				err := subject.Execute(context.Background(), opts)
+				h.AssertError(t, err, "docker daemon at 'tcp://127.0.0.1:2375' cannot be reached from lifecycle containers")
+				h.AssertEq(t, phaseNames(), []string{"/lifecycle/detector"})
+			})
+
+			it("runs them when the builder has no bash to probe with", func() {
+				runtime.OnStart = func(ctr *mocks.FakeContainer) {
+					if ctr.Config.Cmd[0] == "/bin/sh" {
+						ctr.ExitCode = 127
+					}
+				}
+
+				h.AssertNil(t, subject.Execute(context.Background(), opts))
+				h.AssertEq(t, len(phases()), 6)
+			})
+		})
+
+		when("there is build-time env", func() {
+			it.Before(func() {
+				opts.Env = map[string]string{"SOME_KEY.override": "some-val", "PATH.append": "/some/bin"}
+			})
+
+			it("mounts it at /platform/env for the detector and builder only", func() {
+				h.AssertNil(t, subject.Execute(context.Background(), opts))
+
+				for _, ctr := range runtime.Containers() {
+					mounted := false
+					for _, bind := range ctr.HostConfig.Binds {
+						if bind == subject.EnvVolume+":/platform/env" {
+							mounted = true
+						}
+					}
+					phase := ctr.Config.Cmd[0]
+					h.AssertEq(t, mounted, phase == "/lifecycle/detector" || phase == "/lifecycle/builder")
+				}
+			})
+
+			it("copies the env files to the volume once", func() {
+				h.AssertNil(t, subject.Execute(context.Background(), opts))
+
+				detector := runtime.Containers()[0]
+				h.AssertEq(t, string(detector.Files["/platform/env/SOME_KEY.override"]), "some-val")
+				h.AssertEq(t, string(detector.Files["/platform/env/PATH.append"]), "/some/bin")
+				h.AssertEq(t, string(detector.Files["/platform/env/PATH.delim"]), ":")
+				buildPhase := phases()[3]
+				h.AssertEq(t, len(buildPhase.Files), 0)
+			})
+
+			it("removes the env volume and the failed container mounting it when keeping a failed build", func() {
+				opts.KeepOnFailure = true
+				runtime.OnStart = func(ctr *mocks.FakeContainer) {
+					if ctr.Config.Cmd[0] == "/lifecycle/builder" {
+						ctr.ExitCode = 1
+					}
+				}
+				h.AssertNotNil(t, subject.Execute(context.Background(), opts))
+
+				for _, name := range runtime.Volumes() {
+					h.AssertNotContains(t, name, "pack-env-")
+				}
+				h.AssertEq(t, len(runtime.Volumes()), 4)
+				for _, ctr := range runtime.Containers() {
+					h.AssertEq(t, ctr.Removed, true)
+				}
+			})
+
+			it("keeps a failed container that does not mount it", func() {
+				opts.KeepOnFailure = true
+				runtime.OnStart = func(ctr *mocks.FakeContainer) {
+					if ctr.Config.Cmd[0] == "/lifecycle/exporter" {
+						ctr.ExitCode = 1
+					}
+				}
+				h.AssertNotNil(t, subject.Execute(context.Background(), opts))
+
+				containers := runtime.Containers()
+				h.AssertEq(t, containers[len(containers)-1].Removed, false)
+				for _, name := range runtime.Volumes() {
+					h.AssertNotContains(t, name, "pack-env-")
+				}
+			})
+		})
+
+		when("there are bindings", func() {
+			it("mounts them read-only below /platform/bindings for the detector and builder only", func() {
+				opts.Bindings = map[string]string{
+					"maven/settings.xml": "/some/settings.xml",
+					"db":                 "/some/binding",
+				}
+				h.AssertNil(t, subject.Execute(context.Background(), opts))
+
+				for _, ctr := range runtime.Containers() {
+					var bindingBinds []string
+					for _, bind := range ctr.HostConfig.Binds {
+						if strings.Contains(bind, "/platform/bindings/") {
+							bindingBinds = append(bindingBinds, bind)
+						}
+					}
+					phase := ctr.Config.Cmd[0]
+					if phase == "/lifecycle/detector" || phase == "/lifecycle/builder" {
+						h.AssertEq(t, bindingBinds, []string{
+							"/some/binding:/platform/bindings/db:ro",
+							"/some/settings.xml:/platform/bindings/maven/settings.xml:ro",
+						})
+					} else {
+						h.AssertEq(t, len(bindingBinds), 0)
+					}
+				}
+			})
+
+			it("fails for a remote daemon, which cannot mount them", func() {
+				runtime.Host = "tcp://docker.example.com:2375"
+				opts.Bindings = map[string]string{"db": "/some/binding"}
+
+				err := subject.Execute(context.Background(), opts)
+				h.AssertError(t, err, "cannot be used with the remote docker daemon at 'tcp://docker.example.com:2375'")
+				h.AssertEq(t, len(runtime.Containers()), 0)
+			})
+		})
+
+		when("there are CA certs", func() {
+			it.Before(func() {
+				opts.CACerts = []byte("some-cert\n")
+			})
+
+			it("makes every phase trust them", func() {
+				h.AssertNil(t, subject.Execute(context.Background(), opts))
+
+				for _, ctr := range phases() {
+					h.AssertSliceContains(t, ctr.HostConfig.Binds, subject.CACertsVolume+":/platform/ca-certs")
+					h.AssertSliceContains(t, ctr.Config.Env, "SSL_CERT_FILE=/platform/ca-certs/ca-certificates.crt")
+					h.AssertSliceContains(t, ctr.Config.Env, "SSL_CERT_DIR=/platform/ca-certs")
+				}
+				h.AssertEq(t, string(runtime.Containers()[0].Files["/platform/ca-certs/ca-certificates.crt"]), "some-cert\n")
+			})
+
+			it("adds them to the CA certs of the builder", func() {
+				runtime.ImageFiles["/etc/ssl/certs/ca-certificates.crt"] = []byte("system-cert")
+				h.AssertNil(t, subject.Execute(context.Background(), opts))
+
+				h.AssertEq(t, string(runtime.Containers()[0].Files["/platform/ca-certs/ca-certificates.crt"]), "system-cert\nsome-cert\n")
+			})
+
+			it("removes the CA certs volume", func() {
+				h.AssertNil(t, subject.Execute(context.Background(), opts))
+
+				for _, name := range runtime.Volumes() {
+					h.AssertNotContains(t, name, "pack-ca-certs-")
+				}
+			})
+
+			it("keeps the CA certs volume with a failed build, as the failed container mounts it", func() {
+				opts.KeepOnFailure = true
+				runtime.OnStart = func(ctr *mocks.FakeContainer) {
+					if ctr.Config.Cmd[0] == "/lifecycle/builder" {
+						ctr.ExitCode = 1
+					}
+				}
+				h.AssertNotNil(t, subject.Execute(context.Background(), opts))
+				h.AssertSliceContains(t, runtime.Volumes(), subject.CACertsVolume)
+				v, err := runtime.VolumeInspect(context.Background(), subject.CACertsVolume)
+				h.AssertNil(t, err)
+				h.AssertEq(t, v.Labels["io.buildpacks.pack.keep"], "true")
+
+				runtime.OnStart = nil
+				h.AssertNil(t, subject.Execute(context.Background(), opts))
+				for _, name := range runtime.Volumes() {
+					h.AssertNotContains(t, name, "pack-ca-certs-")
+				}
+			})
+		})
+
+		when("a phase fails", func() {
+			it.Before(func() {
+				runtime.OnStart = func(ctr *mocks.FakeContainer) {
+					if ctr.Config.Cmd[0] == "/lifecycle/builder" {
+						ctr.Stderr = "some build error"
+						ctr.ExitCode = 3
+					}
+				}
+			})
+
+			it("returns the error and runs no later phases", func() {
+				err := subject.Execute(context.Background(), opts)
+				h.AssertError(t, err, "failed with status code: 3")
+
+				h.AssertEq(t, phaseNames(), []string{
+					"/lifecycle/detector",
+					"/lifecycle/restorer",
+					"/lifecycle/analyzer",
+					"/lifecycle/builder",
+				})
+				h.AssertEq(t, len(runtime.Volumes()), 2)
+			})
+
+			it("does not blame the daemon", func() {
+				err := subject.Execute(context.Background(), opts)
+				h.AssertNotContains(t, err.Error(), "docker daemon")
+			})
+
+			it("keeps the volumes and failed container when keeping failed builds", func() {
+				opts.KeepOnFailure = true
+				h.AssertNotNil(t, subject.Execute(context.Background(), opts))
+
+				containers := runtime.Containers()
+				h.AssertEq(t, containers[len(containers)-1].Removed, false)
+				h.AssertEq(t, len(runtime.Volumes()), 4)
+			})
+
+			when("a failed build was kept", func() {
+				var keptContainer *mocks.FakeContainer
+
+				it.Before(func() {
+					opts.KeepOnFailure = true
+					h.AssertNotNil(t, subject.Execute(context.Background(), opts))
+					containers := runtime.Containers()
+					keptContainer = containers[len(containers)-1]
+					h.AssertEq(t, keptContainer.Removed, false)
+					runtime.OnStart = nil
+				})
+
+				it("cannot remove the volumes the kept container mounts", func() {
+					err := runtime.VolumeRemove(context.Background(), subject.LayersVolume, true)
+					h.AssertError(t, err, "volume is in use")
+					h.AssertEq(t, len(runtime.Volumes()), 4)
+				})
+
+				it("names the kept container after the image", func() {
+					h.AssertContains(t, keptContainer.Name, "pack-kept-")
+					h.AssertContains(t, keptContainer.Name, "-builder")
+				})
+
+				it("removes the kept container and volumes before building the image again", func() {
+					runtime.OnStart = func(ctr *mocks.FakeContainer) {
+						if ctr.Config.Cmd[0] == "/lifecycle/detector" {
+							h.AssertEq(t, keptContainer.Removed, true)
+						}
+					}
+					h.AssertNil(t, subject.Execute(context.Background(), opts))
+
+					h.AssertEq(t, keptContainer.Removed, true)
+					h.AssertEq(t, len(runtime.Volumes()), 2)
+				})
+
+				it("removes the kept container when resuming, and the volumes once the build succeeds", func() {
+					opts.ResumeFrom = "build"
+					h.AssertNil(t, subject.Execute(context.Background(), opts))
+
+					for _, ctr := range runtime.Containers() {
+						h.AssertEq(t, ctr.Removed, true)
+					}
+					h.AssertEq(t, len(runtime.Volumes()), 2)
+				})
+
+				it("replaces the kept container when the resumed build fails again", func() {
+					opts.ResumeFrom = "build"
+					runtime.OnStart = func(ctr *mocks.FakeContainer) {
+						ctr.ExitCode = 1
+					}
+					h.AssertNotNil(t, subject.Execute(context.Background(), opts))
+
+					var kept []string
+					for _, ctr := range runtime.Containers() {
+						if !ctr.Removed {
+							kept = append(kept, ctr.Name)
+						}
+					}
+					h.AssertEq(t, kept, []string{keptContainer.Name})
+					h.AssertEq(t, len(runtime.Volumes()), 4)
+				})
+			})
+		})
+	})
+
+	when("#DetectPlan", func() {
+		it("reads the group and plan written by the detector", func() {
+			runtime.OnStart = func(ctr *mocks.FakeContainer) {
+				ctr.Files["/layers/group.toml"] = []byte("[[group]]\nid = \"some.bp\"\nversion = \"1.0\"\n")
+				ctr.Files["/layers/plan.toml"] = []byte("some-plan")
+			}
+			subject.Setup(opts)
+
+			result, err := subject.DetectPlan(context.Background())
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(result.Group), 1)
+			h.AssertEq(t, result.Group[0].ID, "some.bp")
+			h.AssertEq(t, result.Plan, "some-plan")
+		})
+	})
+}
@@ -1,41 +1,69 @@
 package build
 
 import (
+	"archive/tar"
+	"bytes"
 	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"path"
 	"runtime"
 	"sync"
+	"time"
 
 	"github.com/buildpack/lifecycle/image/auth"
 	"github.com/docker/docker/api/types"
 	dcontainer "github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/client"
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/pkg/errors"
 
+	"github.com/buildpack/pack/builder"
 	"github.com/buildpack/pack/container"
 	"github.com/buildpack/pack/internal/archive"
+	"github.com/buildpack/pack/internal/resource"
 	"github.com/buildpack/pack/logging"
+	"github.com/buildpack/pack/style"
 )
 
 type Phase struct {
 	name     string
 	logger   logging.Logger
-	docker   *client.Client
+	runtime  container.Runtime
 	ctrConf  *dcontainer.Config
 	hostConf *dcontainer.HostConfig
 	ctr      dcontainer.ContainerCreateCreatedBody
+	ctrName  string // empty unless keeping, when a later build of the image removes the container by its name
 	uid, gid int
 	appPath  string
+	exclude  []string
+	include  []string
 	appOnce  *sync.Once
+	env      map[string]string // build-time env copied to the env volume, when the phase mounts it
+	envOnce  *sync.Once
+	mountEnv bool
+	keep     bool // keep the container if the phase fails
+	failed   bool
+	stdin    io.Reader // when set the phase is run interactively
+	stdout   io.Writer
+
+	dockerCertPath string        // TLS certs the phase needs to reach a TCP daemon
+	daemonAccess   *daemonAccess // how the phase reaches the daemon, nil when it has no daemon access
+	daemonOnce     *sync.Once    // probes the daemon before the first phase with daemon access runs
+	envVolume      string        // build-time env volume, empty when there is no env
+	caCertsVolume  string        // CA certs volume, mounted by every phase, empty when there are no extra CA certs
+	caCerts        []byte        // extra CA certs, added to those of the builder in the CA certs volume
+	caCertsOnce    *sync.Once
 }
 
 func (l *Lifecycle) NewPhase(name string, ops ...func(*Phase) (*Phase, error)) (*Phase, error) {
 	ctrConf := &dcontainer.Config{
 		Image:  l.builder.Name(),
-		Labels: map[string]string{"author": "pack"},
+		Labels: resource.Labels(resource.PurposePhase),
+	}
+	if l.keepOnFailure {
+		ctrConf.Labels[resource.KeepLabel] = "true"
 	}
 	hostConf := &dcontainer.HostConfig{
 		Binds: []string{
@@ -45,15 +73,25 @@ func (l *Lifecycle) NewPhase(name string, ops ...func(*Phase) (*Phase, error)) (
 	}
 	ctrConf.Cmd = []string{"/lifecycle/" + name}
 	phase := &Phase{
-		ctrConf:  ctrConf,
-		hostConf: hostConf,
-		name:     name,
-		docker:   l.docker,
-		logger:   l.logger,
-		uid:      l.builder.UID,
-		gid:      l.builder.GID,
-		appPath:  l.appPath,
-		appOnce:  l.appOnce,
+		ctrConf:    ctrConf,
+		hostConf:   hostConf,
+		name:       name,
+		runtime:    l.runtime,
+		logger:     l.logger,
+		uid:        l.builder.UID,
+		gid:        l.builder.GID,
+		appPath:    l.appPath,
+		exclude:    l.appExclude,
+		include:    l.appInclude,
+		appOnce:    l.appOnce,
+		env:        l.env,
+		envOnce:    l.envOnce,
+		envVolume:  l.EnvVolume,
+		keep:       l.keepOnFailure,
+		daemonOnce: l.daemonOnce,
+	}
+	if l.keepOnFailure {
+		phase.ctrName = l.keptContainerName(name)
 	}
 
 	if l.httpProxy != "" {
@@ -69,6 +107,14 @@ func (l *Lifecycle) NewPhase(name string, ops ...func(*Phase) (*Phase, error)) (
 		phase.ctrConf.Env = append(phase.ctrConf.Env, "no_proxy="+l.noProxy)
 	}
 
+	if l.CACertsVolume != "" {
+		phase.caCertsVolume = l.CACertsVolume
+		phase.caCerts = l.caCerts
+		phase.caCertsOnce = l.caCertsOnce
+		phase.hostConf.Binds = append(phase.hostConf.Binds, fmt.Sprintf("%s:%s", l.CACertsVolume, platformCACertsDir))
+		phase.ctrConf.Env = append(phase.ctrConf.Env, "SSL_CERT_FILE="+caCertsBundle, "SSL_CERT_DIR="+platformCACertsDir)
+	}
+
 	var err error
 	for _, op := range ops {
 		phase, err = op(phase)
@@ -86,10 +132,61 @@ func WithArgs(args ...string) func(*Phase) (*Phase, error) {
 	}
 }
 
+// WithCmd replaces the lifecycle binary the phase runs with cmd
+func WithCmd(cmd ...string) func(*Phase) (*Phase, error) {
+	return func(phase *Phase) (*Phase, error) {
+		phase.ctrConf.Cmd = cmd
+		return phase, nil
+	}
+}
+
+// WithInteractive runs the phase with a TTY connected to in and out instead of logging its output
+func WithInteractive(in io.Reader, out io.Writer) func(*Phase) (*Phase, error) {
+	return func(phase *Phase) (*Phase, error) {
+		phase.ctrConf.Tty = true
+		phase.ctrConf.OpenStdin = true
+		phase.ctrConf.StdinOnce = true
+		phase.ctrConf.AttachStdin = true
+		phase.ctrConf.AttachStdout = true
+		phase.ctrConf.AttachStderr = true
+		phase.ctrConf.WorkingDir = appDir
+		phase.stdin = in
+		phase.stdout = out
+		return phase, nil
+	}
+}
+
+// WithDaemonAccess gives the phase access to the daemon pack is using, whether it is reached through a unix socket,
+// such as a rootless or Podman socket, or over TCP. Before the first such phase runs, a probe container checks that the
+// daemon can be reached from inside a container.
 func WithDaemonAccess() func(*Phase) (*Phase, error) {
 	return func(phase *Phase) (*Phase, error) {
+		access, err := newDaemonAccess(phase.runtime.DaemonHost(), os.Getenv("DOCKER_CERT_PATH"), os.Getenv("DOCKER_TLS_VERIFY") != "")
+		if err != nil {
+			return nil, err
+		}
 		phase.ctrConf.User = "root"
-		phase.hostConf.Binds = append(phase.hostConf.Binds, "/var/run/docker.sock:/var/run/docker.sock")
+		phase.ctrConf.Env = append(phase.ctrConf.Env, access.env...)
+		phase.hostConf.Binds = append(phase.hostConf.Binds, access.binds...)
+		if access.networkMode != "" {
+			phase.hostConf.NetworkMode = dcontainer.NetworkMode(access.networkMode)
+		}
+		phase.dockerCertPath = access.certPath
+		phase.daemonAccess = access
+		return phase, nil
+	}
+}
+
+// WithPlatformEnv mounts the volume holding the build-time env at /platform/env, where buildpacks read it from.
+// Phases without it see an empty /platform/env. A volume is used rather than a tmpfs because files cannot be copied
+// to a tmpfs before the container starts.
+func WithPlatformEnv() func(*Phase) (*Phase, error) {
+	return func(phase *Phase) (*Phase, error) {
+		if phase.envVolume == "" {
+			return phase, nil
+		}
+		phase.mountEnv = true
+		phase.hostConf.Binds = append(phase.hostConf.Binds, fmt.Sprintf("%s:%s", phase.envVolume, platformEnvDir))
 		return phase, nil
 	}
 }
@@ -116,17 +213,50 @@ func WithRegistryAccess(repos ...string) func(*Phase) (*Phase, error) {
 func (p *Phase) Run(ctx context.Context) error {
 	var err error
 
-	p.ctr, err = p.docker.ContainerCreate(ctx, p.ctrConf, p.hostConf, nil, "")
+	if p.daemonAccess != nil && p.daemonOnce != nil {
+		p.daemonOnce.Do(func() { err = p.probeDaemon(ctx) })
+		if err != nil {
+			return err
+		}
+	}
+
+	p.ctr, err = p.runtime.ContainerCreate(ctx, p.ctrConf, p.hostConf, nil, p.ctrName)
 	if err != nil {
 		return errors.Wrapf(err, "failed to create '%s' container", p.name)
 	}
 
+	if p.dockerCertPath != "" {
+		if err := p.copyDockerCerts(ctx); err != nil {
+			return err
+		}
+	}
+
+	if p.caCertsVolume != "" {
+		p.caCertsOnce.Do(func() { err = p.copyCACerts(ctx) })
+		if err != nil {
+			return errors.Wrapf(err, "failed to copy CA certs to '%s' container", p.name)
+		}
+	}
+
+	if p.mountEnv {
+		p.envOnce.Do(func() { err = p.copyEnv(ctx) })
+		if err != nil {
+			return errors.Wrapf(err, "failed to copy env to '%s' container", p.name)
+		}
+	}
+
 	p.appOnce.Do(func() {
 		var (
 			appReader io.ReadCloser
+			filter    *archive.Filter
 			clientErr error
 		)
-		appReader, err = p.createAppReader()
+		filter, err = archive.NewFilter(p.exclude, p.include)
+		if err != nil {
+			err = errors.Wrap(err, "invalid app exclude or include")
+			return
+		}
+		appReader, err = p.createAppReader(filter)
 		if err != nil {
 			err = errors.Wrapf(err, "create tar archive from '%s'", p.appPath)
 			return
@@ -136,7 +266,7 @@ func (p *Phase) Run(ctx context.Context) error {
 		doneChan := make(chan interface{})
 		pr, pw := io.Pipe()
 		go func() {
-			clientErr = p.docker.CopyToContainer(ctx, p.ctr.ID, "/", pr, types.CopyToContainerOptions{})
+			clientErr = p.runtime.CopyToContainer(ctx, p.ctr.ID, "/", pr, types.CopyToContainerOptions{})
 			close(doneChan)
 		}()
 		func() {
@@ -148,26 +278,136 @@ func (p *Phase) Run(ctx context.Context) error {
 		if err == nil {
 			err = clientErr
 		}
+		if err == nil && filter.SkippedFiles > 0 {
+			p.logger.Debugf("Skipped %d app files (%d bytes) matching exclude rules", filter.SkippedFiles, filter.SkippedBytes)
+		}
 	})
 
 	if err != nil {
 		return errors.Wrapf(err, "failed to copy files to '%s' container", p.name)
 	}
 
-	return container.Run(
+	if p.stdin != nil {
+		err = container.RunInteractive(ctx, p.runtime, p.ctr.ID, p.stdin, p.stdout)
+		p.failed = err != nil
+		return err
+	}
+
+	err = container.Run(
 		ctx,
-		p.docker,
+		p.runtime,
 		p.ctr.ID,
 		logging.NewPrefixWriter(logging.GetDebugWriter(p.logger), p.name),
 		logging.NewPrefixWriter(logging.GetDebugErrorWriter(p.logger), p.name),
 	)
+	p.failed = err != nil
+	return err
+}
+
+// ReadFile returns the contents of a single file from the phase container. The container must have been run.
+func (p *Phase) ReadFile(ctx context.Context, path string) ([]byte, error) {
+	rc, _, err := p.runtime.CopyFromContainer(ctx, p.ctr.ID, path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "copy %s from '%s' container", path, p.name)
+	}
+	defer rc.Close()
+
+	tr := tar.NewReader(rc)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "read %s from '%s' container", path, p.name)
+		}
+		if header.Typeflag == tar.TypeReg {
+			return ioutil.ReadAll(tr)
+		}
+	}
+	return nil, fmt.Errorf("file %s not found in '%s' container", path, p.name)
 }
 
 func (p *Phase) Cleanup() error {
-	return p.docker.ContainerRemove(context.Background(), p.ctr.ID, types.ContainerRemoveOptions{Force: true})
+	if p.keep && p.failed {
+		if !p.mountEnv {
+			p.logger.Infof("Keeping failed '%s' container %s for inspection", p.name, style.Symbol(p.ctrName))
+			return nil
+		}
+		// the env volume may hold secrets and cannot be removed while a container mounts it
+		p.logger.Infof("Removing failed '%s' container rather than keeping it, as it mounts the build-time env", p.name)
+	}
+	return p.runtime.ContainerRemove(context.Background(), p.ctr.ID, types.ContainerRemoveOptions{Force: true})
+}
+
+// copyEnv writes the build-time env to the env volume through the phase container, once for the whole build
+func (p *Phase) copyEnv(ctx context.Context) error {
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	now := time.Now()
+	for name, value := range builder.EnvFiles(p.env) {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    path.Join(platformEnvDir, name),
+			Size:    int64(len(value)),
+			Mode:    0644,
+			ModTime: now,
+		}); err != nil {
+			return err
+		}
+		if _, err := tw.Write([]byte(value)); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return p.runtime.CopyToContainer(ctx, p.ctr.ID, "/", buf, types.CopyToContainerOptions{})
 }
 
-func (p *Phase) createAppReader() (io.ReadCloser, error) {
+// systemCACertsBundles are where the CA cert bundle is found on common distributions, the first found is used
+var systemCACertsBundles = []string{
+	"/etc/ssl/certs/ca-certificates.crt", // Debian, Ubuntu
+	"/etc/pki/tls/certs/ca-bundle.crt",   // Fedora, RHEL
+	"/etc/ssl/ca-bundle.pem",             // openSUSE
+	"/etc/ssl/cert.pem",                  // Alpine
+}
+
+// copyCACerts writes the CA cert bundle of the builder with the extra CA certs added to the CA certs volume through
+// the phase container, once for the whole build. SSL_CERT_FILE points at it, so it has to include the certs of the
+// builder as well.
+func (p *Phase) copyCACerts(ctx context.Context) error {
+	var bundle []byte
+	for _, systemBundle := range systemCACertsBundles {
+		if contents, err := p.ReadFile(ctx, systemBundle); err == nil {
+			bundle = append(contents, '\n')
+			break
+		}
+	}
+	if bundle == nil {
+		p.logger.Debug("No CA cert bundle found in builder, only the extra CA certs will be trusted")
+	}
+	bundle = append(bundle, p.caCerts...)
+
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    caCertsBundle,
+		Size:    int64(len(bundle)),
+		Mode:    0644,
+		ModTime: time.Now(),
+	}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(bundle); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return p.runtime.CopyToContainer(ctx, p.ctr.ID, "/", buf, types.CopyToContainerOptions{})
+}
+
+func (p *Phase) createAppReader(filter *archive.Filter) (io.ReadCloser, error) {
 	fi, err := os.Stat(p.appPath)
 	if err != nil {
 		return nil, err
@@ -179,8 +419,23 @@ func (p *Phase) createAppReader() (io.ReadCloser, error) {
 			mode = 0777
 		}
 
-		return archive.ReadDirAsTar(p.appPath, appDir, p.uid, p.gid, mode), nil
+		return archive.ReadDirAsTar(p.appPath, appDir, p.uid, p.gid, mode, filter), nil
+	}
+
+	fh, err := os.Open(p.appPath)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	isZip, err := archive.IsZip(fh)
+	if err != nil {
+		return nil, err
+	}
+
+	if isZip {
+		return archive.ReadZipAsTar(p.appPath, appDir, p.uid, p.gid, -1, filter), nil
 	}
 
-	return archive.ReadZipAsTar(p.appPath, appDir, p.uid, p.gid, -1), nil
+	return archive.ReadTarAsTar(p.appPath, appDir, p.uid, p.gid, -1, filter), nil
 }
@@ -237,6 +237,40 @@ func testPhase(t *testing.T, when spec.G, it spec.S) {
 				})
 			})
 
+			when("#WithPlatformEnv", func() {
+				it.Before(func() {
+					bldr, err := builder.GetBuilder(mustLocalImage(t, docker, repoName))
+					h.AssertNil(t, err)
+					subject.Setup(build.LifecycleOptions{
+						AppPath: filepath.Join("testdata", "fake-app"),
+						Builder: bldr,
+						Env:     map[string]string{"SOME_KEY.override": "some-val"},
+					})
+				})
+
+				it.After(func() {
+					docker.VolumeRemove(context.TODO(), subject.EnvVolume, true)
+				})
+
+				it("provides the env in /platform/env", func() {
+					phase, err := subject.NewPhase(
+						"phase",
+						build.WithArgs("env"),
+						build.WithPlatformEnv(),
+					)
+					h.AssertNil(t, err)
+					assertRunSucceeds(t, phase, &outBuf, &errBuf)
+					h.AssertContains(t, outBuf.String(), "[phase] SOME_KEY.override=some-val")
+				})
+
+				it("leaves /platform/env empty for other phases", func() {
+					phase, err := subject.NewPhase("phase", build.WithArgs("env"))
+					h.AssertNil(t, err)
+					assertRunSucceeds(t, phase, &outBuf, &errBuf)
+					h.AssertNotContains(t, outBuf.String(), "SOME_KEY")
+				})
+			})
+
 			when("#WithRegistryAccess", func() {
 				var registry *h.TestRegistryConfig
 
@@ -308,7 +342,7 @@ func CreateFakeLifecycleImage(t *testing.T, dockerCli *client.Client, repoName s
 
 	wd, err := os.Getwd()
 	h.AssertNil(t, err)
-	buildContext := archive.ReadDirAsTar(filepath.Join(wd, "testdata", "fake-lifecycle"), "/", 0, 0, -1)
+	buildContext := archive.ReadDirAsTar(filepath.Join(wd, "testdata", "fake-lifecycle"), "/", 0, 0, -1, nil)
 
 	res, err := dockerCli.ImageBuild(ctx, buildContext, dockertypes.ImageBuildOptions{
 		Tags:        []string{repoName},
@@ -321,6 +355,13 @@ func CreateFakeLifecycleImage(t *testing.T, dockerCli *client.Client, repoName s
 	res.Body.Close()
 }
 
+func mustLocalImage(t *testing.T, docker *client.Client, name string) imgutil.Image {
+	t.Helper()
+	img, err := imgutil.NewLocalImage(name, docker)
+	h.AssertNil(t, err)
+	return img
+}
+
 func CreateFakeLifecycle(appDir string, docker *client.Client, logger logging.Logger) (*build.Lifecycle, error) {
 	subject := build.NewLifecycle(docker, logger)
 	builderImage, err := imgutil.NewLocalImage(repoName, docker)
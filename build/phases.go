@@ -3,61 +3,161 @@ package build
 import (
 	"context"
 	"fmt"
+	"io"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+
+	"github.com/buildpack/pack/buildpack"
+	"github.com/buildpack/pack/cache"
 )
 
 const (
-	layersDir      = "/layers"
-	appDir         = "/workspace"
-	cacheDir       = "/cache"
-	launchCacheDir = "/launch-cache"
-	platformDir    = "/platform"
+	layersDir           = "/layers"
+	appDir              = "/workspace"
+	cacheDir            = "/cache"
+	launchCacheDir      = "/launch-cache"
+	platformDir         = "/platform"
+	platformEnvDir      = platformDir + "/env"
+	platformBindingsDir = platformDir + "/bindings"
+	platformCACertsDir  = platformDir + "/ca-certs"
+	caCertsBundle       = platformCACertsDir + "/ca-certificates.crt"
+	groupPath           = layersDir + "/group.toml"
+	planPath            = layersDir + "/plan.toml"
 )
 
+// DetectResult is the outcome of the detect phase
+type DetectResult struct {
+	Group []buildpack.BuildpackInfo // buildpacks selected to build the app, in order
+	Plan  string                    // contents of the build plan written by the detector
+}
+
 func (l *Lifecycle) Detect(ctx context.Context) error {
-	detect, err := l.NewPhase(
+	detect, err := l.newDetect()
+	if err != nil {
+		return err
+	}
+	defer detect.Cleanup()
+	if err := detect.Run(ctx); err != nil {
+		return err
+	}
+
+	if l.events != nil {
+		group, err := readGroup(ctx, detect)
+		if err != nil {
+			l.logger.Debugf("Unable to read detected group: %s", err)
+			return nil
+		}
+		l.events.Emit(Event{Type: EventGroupDetected, Buildpacks: group})
+	}
+	return nil
+}
+
+// DetectPlan runs the detector and reads back the selected group and build plan
+func (l *Lifecycle) DetectPlan(ctx context.Context) (*DetectResult, error) {
+	detect, err := l.newDetect()
+	if err != nil {
+		return nil, err
+	}
+	defer detect.Cleanup()
+	if err := detect.Run(ctx); err != nil {
+		return nil, err
+	}
+
+	group, err := readGroup(ctx, detect)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading detected group")
+	}
+	l.events.Emit(Event{Type: EventGroupDetected, Buildpacks: group})
+
+	plan, err := detect.ReadFile(ctx, planPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading build plan")
+	}
+	return &DetectResult{Group: group, Plan: string(plan)}, nil
+}
+
+func (l *Lifecycle) newDetect() (*Phase, error) {
+	return l.NewPhase(
 		"detector",
 		WithArgs(
 			"-app", appDir,
 			"-platform", platformDir,
 		),
+		WithPlatformEnv(),
+		WithBinds(l.bindingBinds()...),
+	)
+}
+
+type groupTOML struct {
+	Group      []buildpack.BuildpackInfo `toml:"group"`
+	Buildpacks []buildpack.BuildpackInfo `toml:"buildpacks"` // lifecycle < 0.4.0
+}
+
+func readGroup(ctx context.Context, detect *Phase) ([]buildpack.BuildpackInfo, error) {
+	contents, err := detect.ReadFile(ctx, groupPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var group groupTOML
+	if _, err := toml.Decode(string(contents), &group); err != nil {
+		return nil, errors.Wrapf(err, "decode %s", groupPath)
+	}
+	if len(group.Group) > 0 {
+		return group.Group, nil
+	}
+	return group.Buildpacks, nil
+}
+
+// Shell runs an interactive shell in the build environment, bash if the builder has it and sh otherwise
+func (l *Lifecycle) Shell(ctx context.Context, in io.Reader, out io.Writer) error {
+	shell, err := l.NewPhase(
+		"shell",
+		WithCmd("/bin/sh", "-c", "if [ -x /bin/bash ]; then exec /bin/bash; else exec /bin/sh; fi"),
+		WithInteractive(in, out),
+		WithPlatformEnv(),
+		WithBinds(l.bindingBinds()...),
 	)
 	if err != nil {
 		return err
 	}
-	defer detect.Cleanup()
-	return detect.Run(ctx)
+	defer shell.Cleanup()
+	return shell.Run(ctx)
 }
 
-func (l *Lifecycle) Restore(ctx context.Context, useVolumeCache bool, cacheName string) error {
-	var restore *Phase
-	var err error
+func (l *Lifecycle) Restore(ctx context.Context, buildCache Cache) error {
+	restore, err := l.newCachePhase("restorer", buildCache)
+	if err != nil {
+		return err
+	}
+	defer restore.Cleanup()
+	return restore.Run(ctx)
+}
 
-	if useVolumeCache {
-		restore, err = l.NewPhase(
-			"restorer",
+// newCachePhase creates a phase that reads or writes buildCache
+func (l *Lifecycle) newCachePhase(name string, buildCache Cache) (*Phase, error) {
+	switch buildCache.Type() {
+	case cache.Volume:
+		return l.NewPhase(
+			name,
 			WithDaemonAccess(),
 			WithArgs(
 				"-path", cacheDir,
 				"-layers", layersDir,
 			),
-			WithBinds(fmt.Sprintf("%s:%s", cacheName, cacheDir)),
+			WithBinds(fmt.Sprintf("%s:%s", buildCache.Name(), cacheDir)),
 		)
-	} else {
-		restore, err = l.NewPhase(
-			"restorer",
+	default:
+		return l.NewPhase(
+			name,
 			WithDaemonAccess(),
 			WithArgs(
-				"-image", cacheName,
+				"-image", buildCache.Name(),
 				"-layers", layersDir,
 			),
 		)
 	}
-
-	if err != nil {
-		return err
-	}
-	defer restore.Cleanup()
-	return restore.Run(ctx)
 }
 
 func (l *Lifecycle) Analyze(ctx context.Context, repoName string, publish, clearCache bool) error {
@@ -75,7 +175,8 @@ func (l *Lifecycle) newAnalyze(repoName string, publish, clearCache bool) (*Phas
 		repoName,
 	}
 	if clearCache {
-		args = prependArg("-skip-layers", args)
+		skipLayersArgs, _ := l.platform.SkipLayersArgs()
+		args = append(skipLayersArgs, args...)
 	}
 
 	if publish {
@@ -108,6 +209,8 @@ func (l *Lifecycle) Build(ctx context.Context) error {
 			"-app", appDir,
 			"-platform", platformDir,
 		),
+		WithPlatformEnv(),
+		WithBinds(l.bindingBinds()...),
 	)
 	if err != nil {
 		return err
@@ -125,77 +228,45 @@ func (l *Lifecycle) Export(ctx context.Context, repoName string, runImage string
 	return export.Run(ctx)
 }
 
+// newExport creates the exporter phase. The exporter takes a single image name, so additional tags are applied once it
+// has run.
 func (l *Lifecycle) newExport(repoName, runImage string, publish bool, launchCacheName string) (*Phase, error) {
+	args := []string{
+		"-image", runImage,
+		"-layers", layersDir,
+		"-app", appDir,
+	}
+
 	if publish {
 		return l.NewPhase(
 			"exporter",
 			WithRegistryAccess(repoName, runImage),
-			WithArgs(
-				"-image", runImage,
-				"-layers", layersDir,
-				"-app", appDir,
-				repoName,
-			),
+			WithArgs(append(args, repoName)...),
 		)
-	} else {
-		if launchCacheName != "" {
-			return l.NewPhase(
-				"exporter",
-				WithDaemonAccess(),
-				WithArgs(
-					"-image", runImage,
-					"-layers", layersDir,
-					"-app", appDir,
-					"-daemon",
-					"-launch-cache", launchCacheDir,
-					repoName,
-				),
-				WithBinds(fmt.Sprintf("%s:%s", launchCacheName, launchCacheDir)),
-			)
-		} else {
-			return l.NewPhase(
-				"exporter",
-				WithDaemonAccess(),
-				WithArgs(
-					"-image", runImage,
-					"-layers", layersDir,
-					"-app", appDir,
-					"-daemon",
-					repoName,
-				),
-			)
-		}
 	}
-}
-
-func (l *Lifecycle) Cache(ctx context.Context, useVolumeCache bool, cacheName string) error {
-	var cache *Phase
-	var err error
 
-	if useVolumeCache {
-		cache, err = l.NewPhase(
-			"cacher",
-			WithDaemonAccess(),
-			WithArgs(
-				"-path", cacheDir,
-				"-layers", layersDir,
-			),
-			WithBinds(fmt.Sprintf("%s:%s", cacheName, cacheDir)),
-		)
-	} else {
-		cache, err = l.NewPhase(
-			"cacher",
+	args = append(args, "-daemon")
+	if launchCacheName != "" {
+		return l.NewPhase(
+			"exporter",
 			WithDaemonAccess(),
-			WithArgs(
-				"-image", cacheName,
-				"-layers", layersDir,
-			),
+			WithArgs(append(args, "-launch-cache", launchCacheDir, repoName)...),
+			WithBinds(fmt.Sprintf("%s:%s", launchCacheName, launchCacheDir)),
 		)
 	}
 
+	return l.NewPhase(
+		"exporter",
+		WithDaemonAccess(),
+		WithArgs(append(args, repoName)...),
+	)
+}
+
+func (l *Lifecycle) Cache(ctx context.Context, buildCache Cache) error {
+	cacher, err := l.newCachePhase("cacher", buildCache)
 	if err != nil {
 		return err
 	}
-	defer cache.Cleanup()
-	return cache.Run(ctx)
+	defer cacher.Cleanup()
+	return cacher.Run(ctx)
 }
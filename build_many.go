@@ -0,0 +1,142 @@
+package pack
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/buildpack/pack/logging"
+	"github.com/buildpack/pack/style"
+)
+
+type BuildManyOptions struct {
+	Apps        []BuildOptions
+	Concurrency int // maximum number of builds to run at once, defaults to 1
+}
+
+type BuildResult struct {
+	Image    string
+	Duration time.Duration
+	Err      error
+}
+
+// BuildMany builds apps concurrently. Builder and run images shared between apps are pulled once up front, and the
+// output of each build is prefixed with its image name. A result is returned for every app, in the order provided.
+func (c *Client) BuildMany(ctx context.Context, opts BuildManyOptions) []BuildResult {
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	apps := make([]BuildOptions, len(opts.Apps))
+	copy(apps, opts.Apps)
+	prefetchErrs := c.prefetchImages(ctx, apps)
+
+	results := make([]BuildResult, len(apps))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, app := range apps {
+		results[i].Image = app.Image
+		if err := prefetchErrs[i]; err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, app BuildOptions) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				results[i].Err = ctx.Err()
+				return
+			}
+
+			start := time.Now()
+			results[i].Err = c.forApp(app.Image).Build(ctx, app)
+			results[i].Duration = time.Since(start)
+		}(i, app)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// forApp returns a copy of the client whose output is prefixed with name
+func (c *Client) forApp(name string) *Client {
+	appClient := *c
+	appClient.logger = logging.NewPrefixLogger(c.logger, name)
+	appClient.lifecycle = c.newLifecycle(appClient.logger)
+	return &appClient
+}
+
+// prefetchImages pulls each distinct builder and run image used by apps once, and then disables pulling for the
+// individual builds. It returns any error encountered for each app.
+func (c *Client) prefetchImages(ctx context.Context, apps []BuildOptions) []error {
+	errs := make([]error, len(apps))
+	fetched := map[string]error{}
+	fetch := func(name string, daemon bool) error {
+		key := name
+		if !daemon {
+			key = "remote:" + name
+		}
+		if err, ok := fetched[key]; ok {
+			return err
+		}
+		c.logger.Debugf("Pulling %s", style.Symbol(name))
+		_, err := c.imageFetcher.Fetch(ctx, name, daemon, true)
+		fetched[key] = err
+		return err
+	}
+
+	for i, app := range apps {
+		if app.NoPull || app.Builder == "" {
+			continue
+		}
+
+		builderRef, err := c.processBuilderName(app.Builder)
+		if err != nil {
+			errs[i] = errors.Wrapf(err, "invalid builder '%s'", app.Builder)
+			continue
+		}
+
+		if err := fetch(builderRef.Name(), true); err != nil {
+			errs[i] = errors.Wrapf(err, "failed to fetch builder image '%s'", builderRef.Name())
+			continue
+		}
+
+		if runImage, err := c.resolveAppRunImage(ctx, app, builderRef.Name()); err != nil {
+			errs[i] = err
+			continue
+		} else if err := fetch(runImage, !app.Publish); err != nil {
+			errs[i] = errors.Wrapf(err, "failed to fetch run image '%s'", runImage)
+			continue
+		}
+
+		apps[i].NoPull = true
+	}
+	return errs
+}
+
+func (c *Client) resolveAppRunImage(ctx context.Context, app BuildOptions, builderName string) (string, error) {
+	imageRef, err := c.parseTagReference(app.Image)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid image name '%s'", app.Image)
+	}
+
+	rawBuilderImage, err := c.imageFetcher.Fetch(ctx, builderName, true, false)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to fetch builder image '%s'", builderName)
+	}
+
+	builderImage, err := c.processBuilderImage(rawBuilderImage)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid builder '%s'", app.Builder)
+	}
+
+	return c.resolveRunImage(app.RunImage, imageRef.Context().RegistryStr(), builderImage.GetStackInfo(), app.AdditionalMirrors), nil
+}
@@ -0,0 +1,135 @@
+package pack
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/buildpack/imgutil/fakes"
+	"github.com/docker/docker/client"
+	"github.com/fatih/color"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpack/pack/builder"
+	"github.com/buildpack/pack/buildpack"
+	"github.com/buildpack/pack/internal/mocks"
+	"github.com/buildpack/pack/logging"
+	h "github.com/buildpack/pack/testhelpers"
+)
+
+func TestBuildMany(t *testing.T) {
+	color.NoColor = true
+	spec.Run(t, "BuildMany", testBuildMany, spec.Report(report.Terminal{}))
+}
+
+func testBuildMany(t *testing.T, when spec.G, it spec.S) {
+	var (
+		subject          *Client
+		fakeImageFetcher *mocks.FakeImageFetcher
+		fakeLifecycles   map[string]*mocks.FakeLifecycle
+		builderImage     *fakes.Image
+		runImage         *fakes.Image
+		tmpDir           string
+		outBuf           bytes.Buffer
+	)
+
+	it.Before(func() {
+		fakeImageFetcher = mocks.NewFakeImageFetcher()
+
+		builderImage = mocks.NewFakeBuilderImage(t,
+			"example.com/some/builder:tag",
+			[]builder.BuildpackMetadata{
+				{BuildpackInfo: buildpack.BuildpackInfo{ID: "buildpack.id", Version: "buildpack.version"}, Latest: true},
+			},
+			builder.Config{
+				Stack: builder.StackConfig{ID: "some.stack", RunImage: "some/run"},
+			},
+		)
+		fakeImageFetcher.RemoteImages[builderImage.Name()] = builderImage
+
+		runImage = fakes.NewImage("some/run", "", "")
+		h.AssertNil(t, runImage.SetLabel("io.buildpacks.stack.id", "some.stack"))
+		fakeImageFetcher.RemoteImages[runImage.Name()] = runImage
+
+		var err error
+		tmpDir, err = ioutil.TempDir("", "build-many-test")
+		h.AssertNil(t, err)
+
+		docker, err := client.NewClientWithOpts(client.FromEnv, client.WithVersion("1.38"))
+		h.AssertNil(t, err)
+
+		logger := mocks.NewMockLogger(&outBuf)
+		fakeLifecycles = map[string]*mocks.FakeLifecycle{}
+		subject = &Client{
+			logger:           logger,
+			imageFetcher:     fakeImageFetcher,
+			buildpackFetcher: buildpack.NewFetcher(NewDownloader(logger, tmpDir)),
+			lifecycle:        &mocks.FakeLifecycle{},
+			newLifecycle: func(logger logging.Logger) Lifecycle {
+				lifecycle := &mocks.FakeLifecycle{}
+				logger.Info("new lifecycle")
+				fakeLifecycles[outBuf.String()] = lifecycle
+				return lifecycle
+			},
+			docker: docker,
+		}
+	})
+
+	it.After(func() {
+		builderImage.Cleanup()
+		runImage.Cleanup()
+		os.RemoveAll(tmpDir)
+	})
+
+	when("#BuildMany", func() {
+		it("builds every app with its own lifecycle", func() {
+			results := subject.BuildMany(context.TODO(), BuildManyOptions{
+				Apps: []BuildOptions{
+					{Image: "some/app-a", Builder: "example.com/some/builder:tag", AppPath: filepath.Join("testdata", "some-app")},
+					{Image: "some/app-b", Builder: "example.com/some/builder:tag", AppPath: filepath.Join("testdata", "some-app")},
+				},
+			})
+
+			h.AssertEq(t, len(results), 2)
+			h.AssertEq(t, results[0].Image, "some/app-a")
+			h.AssertNil(t, results[0].Err)
+			h.AssertEq(t, results[1].Image, "some/app-b")
+			h.AssertNil(t, results[1].Err)
+			h.AssertEq(t, len(fakeLifecycles), 2)
+			h.AssertContains(t, outBuf.String(), "[some/app-a] new lifecycle")
+			h.AssertContains(t, outBuf.String(), "[some/app-b] new lifecycle")
+		})
+
+		it("pulls the builder and run image once before building", func() {
+			results := subject.BuildMany(context.TODO(), BuildManyOptions{
+				Apps: []BuildOptions{
+					{Image: "some/app-a", Builder: "example.com/some/builder:tag", AppPath: filepath.Join("testdata", "some-app")},
+				},
+			})
+			h.AssertNil(t, results[0].Err)
+
+			// the build itself no longer pulls
+			h.AssertEq(t, fakeImageFetcher.FetchCalls["example.com/some/builder:tag"].Pull, false)
+			h.AssertEq(t, fakeImageFetcher.FetchCalls["some/run"].Pull, false)
+			_, pulled := fakeImageFetcher.LocalImages["some/run"]
+			h.AssertEq(t, pulled, true)
+		})
+
+		it("reports failures per app", func() {
+			results := subject.BuildMany(context.TODO(), BuildManyOptions{
+				Apps: []BuildOptions{
+					{Image: "some/app-a", Builder: "missing/builder", AppPath: filepath.Join("testdata", "some-app")},
+					{Image: "some/app-b", Builder: "example.com/some/builder:tag", AppPath: filepath.Join("testdata", "some-app")},
+				},
+				Concurrency: 2,
+			})
+
+			h.AssertError(t, results[0].Err, "failed to fetch builder image 'index.docker.io/missing/builder:latest'")
+			h.AssertNil(t, results[1].Err)
+		})
+	})
+}
@@ -1,17 +1,17 @@
 package pack
 
 import (
-	"archive/tar"
 	"bytes"
 	"context"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"math/rand"
 	"net/http"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 
@@ -22,6 +22,7 @@ import (
 	"github.com/sclevine/spec"
 	"github.com/sclevine/spec/report"
 
+	"github.com/buildpack/pack/build"
 	"github.com/buildpack/pack/builder"
 	"github.com/buildpack/pack/buildpack"
 	"github.com/buildpack/pack/internal/mocks"
@@ -166,8 +167,10 @@ func testBuild(t *testing.T, when spec.G, it spec.S) {
 				h.AssertEq(t, fakeLifecycle.Opts.AppPath, resolvedWd)
 			})
 			for fileDesc, appPath := range map[string]string{
-				"zip": filepath.Join("testdata", "zip-file.zip"),
-				"jar": filepath.Join("testdata", "jar-file.jar"),
+				"zip":    filepath.Join("testdata", "zip-file.zip"),
+				"jar":    filepath.Join("testdata", "jar-file.jar"),
+				"tar":    filepath.Join("testdata", "tar-file.tar"),
+				"tar.gz": filepath.Join("testdata", "tar-file.tar.gz"),
 			} {
 				fileDesc := fileDesc
 				appPath := appPath
@@ -182,10 +185,37 @@ func testBuild(t *testing.T, when spec.G, it spec.S) {
 				})
 			}
 
+			when("AppReader is provided", func() {
+				it("builds from the tar stream", func() {
+					fh, err := os.Open(filepath.Join("testdata", "tar-file.tar.gz"))
+					h.AssertNil(t, err)
+					defer fh.Close()
+
+					h.AssertNil(t, subject.Build(context.TODO(), BuildOptions{
+						Image:     "some/app",
+						Builder:   builderName,
+						AppPath:   "-",
+						AppReader: fh,
+					}))
+
+					_, err = os.Stat(fakeLifecycle.Opts.AppPath)
+					h.AssertEq(t, os.IsNotExist(err), true)
+				})
+
+				it("fails when the stream is not a tar", func() {
+					h.AssertError(t, subject.Build(context.TODO(), BuildOptions{
+						Image:     "some/app",
+						Builder:   builderName,
+						AppPath:   "-",
+						AppReader: strings.NewReader("not a tar"),
+					}), "app path must be a directory, zip or tar")
+				})
+			})
+
 			for fileDesc, testData := range map[string][]string{
 				"non-existent": {"not/exist/path", "does not exist"},
-				"empty":        {filepath.Join("testdata", "empty-file"), "app path must be a directory or zip"},
-				"non-zip":      {filepath.Join("testdata", "non-zip-file"), "app path must be a directory or zip"},
+				"empty":        {filepath.Join("testdata", "empty-file"), "app path must be a directory, zip or tar"},
+				"non-zip":      {filepath.Join("testdata", "non-zip-file"), "app path must be a directory, zip or tar"},
 			} {
 				fileDesc := fileDesc
 				appPath := testData[0]
@@ -482,6 +512,32 @@ func testBuild(t *testing.T, when spec.G, it spec.S) {
 			})
 		})
 
+		when("KeepOnFailure and ResumeFrom options", func() {
+			it("passes them through to lifecycle", func() {
+				h.AssertNil(t, subject.Build(context.TODO(), BuildOptions{
+					Image:         "some/app",
+					Builder:       builderName,
+					KeepOnFailure: true,
+					ResumeFrom:    "build",
+				}))
+				h.AssertEq(t, fakeLifecycle.Opts.KeepOnFailure, true)
+				h.AssertEq(t, fakeLifecycle.Opts.ResumeFrom, "build")
+			})
+		})
+
+		when("CacheName and CacheFallbacks options", func() {
+			it("passes them through to lifecycle", func() {
+				h.AssertNil(t, subject.Build(context.TODO(), BuildOptions{
+					Image:          "some/app:feature",
+					Builder:        builderName,
+					CacheName:      "some-app-feature",
+					CacheFallbacks: []string{"some-app-main"},
+				}))
+				h.AssertEq(t, fakeLifecycle.Opts.CacheName, "some-app-feature")
+				h.AssertEq(t, fakeLifecycle.Opts.CacheFallbacks, []string{"some-app-main"})
+			})
+		})
+
 		when("Buildpacks option", func() {
 			it("builder order is overwritten", func() {
 				h.AssertNil(t, subject.Build(context.TODO(), BuildOptions{
@@ -717,19 +773,438 @@ func testBuild(t *testing.T, when spec.G, it spec.S) {
 		})
 
 		when("Env option", func() {
-			it("should set the env on the ephemeral builder", func() {
+			it("passes the env to the lifecycle without adding it to the builder", func() {
 				h.AssertNil(t, subject.Build(context.TODO(), BuildOptions{
 					Image:   "some/app",
 					Builder: builderName,
 					Env: map[string]string{
-						"key1": "value1",
-						"key2": "value2",
+						"key1.override": "value1",
+						"key2.append":   "value2",
 					},
 				}))
-				layerTar, err := defaultBuilderImage.FindLayerWithPath("/platform/env/key1")
+				h.AssertEq(t, fakeLifecycle.Opts.Env, map[string]string{
+					"key1.override": "value1",
+					"key2.append":   "value2",
+				})
+				h.AssertEq(t, fakeLifecycle.Opts.Builder.Name(), builderName)
+				h.AssertEq(t, defaultBuilderImage.IsSaved(), false)
+			})
+
+			it("fails for a name that is not a platform env file", func() {
+				err := subject.Build(context.TODO(), BuildOptions{
+					Image:   "some/app",
+					Builder: builderName,
+					Env:     map[string]string{"../../etc/some-file": "value"},
+				})
+				h.AssertError(t, err, "invalid env var name '../../etc/some-file'")
+			})
+		})
+
+		when("Secrets and Bindings options", func() {
+			var bindingsDir string
+
+			it.Before(func() {
+				var err error
+				bindingsDir, err = ioutil.TempDir("", "build-bindings")
+				h.AssertNil(t, err)
+				h.AssertNil(t, os.MkdirAll(filepath.Join(bindingsDir, "some-binding"), 0755))
+				h.AssertNil(t, ioutil.WriteFile(filepath.Join(bindingsDir, "settings.xml"), []byte("some-secret"), 0600))
+			})
+
+			it.After(func() {
+				os.RemoveAll(bindingsDir)
+			})
+
+			it("passes the paths to mount below /platform/bindings to the lifecycle", func() {
+				h.AssertNil(t, subject.Build(context.TODO(), BuildOptions{
+					Image:    "some/app",
+					Builder:  builderName,
+					Secrets:  map[string]string{"maven": filepath.Join(bindingsDir, "settings.xml")},
+					Bindings: map[string]string{"db": filepath.Join(bindingsDir, "some-binding")},
+				}))
+				h.AssertEq(t, fakeLifecycle.Opts.Bindings, map[string]string{
+					"maven/settings.xml": filepath.Join(bindingsDir, "settings.xml"),
+					"db":                 filepath.Join(bindingsDir, "some-binding"),
+				})
+				h.AssertNotContains(t, outBuf.String(), "some-secret")
+			})
+
+			it("errors when a secret is a directory", func() {
+				h.AssertError(t, subject.Build(context.TODO(), BuildOptions{
+					Image:   "some/app",
+					Builder: builderName,
+					Secrets: map[string]string{"maven": bindingsDir},
+				}), "secret 'maven' must be a file")
+			})
+
+			it("errors when a binding is a file", func() {
+				h.AssertError(t, subject.Build(context.TODO(), BuildOptions{
+					Image:    "some/app",
+					Builder:  builderName,
+					Bindings: map[string]string{"db": filepath.Join(bindingsDir, "settings.xml")},
+				}), "binding 'db' must be a directory")
+			})
+
+			it("errors when a name is used twice", func() {
+				h.AssertError(t, subject.Build(context.TODO(), BuildOptions{
+					Image:    "some/app",
+					Builder:  builderName,
+					Secrets:  map[string]string{"db": filepath.Join(bindingsDir, "settings.xml")},
+					Bindings: map[string]string{"db": filepath.Join(bindingsDir, "some-binding")},
+				}), "binding name 'db' is already used")
+			})
+
+			it("errors when a name is a path", func() {
+				h.AssertError(t, subject.Build(context.TODO(), BuildOptions{
+					Image:    "some/app",
+					Builder:  builderName,
+					Bindings: map[string]string{"../db": filepath.Join(bindingsDir, "some-binding")},
+				}), "invalid binding name '../db'")
+			})
+		})
+
+		when("CACerts option", func() {
+			it("passes the certs in the files to the lifecycle as one bundle", func() {
+				certFile := filepath.Join("testdata", "ca-cert.pem")
+				h.AssertNil(t, subject.Build(context.TODO(), BuildOptions{
+					Image:   "some/app",
+					Builder: builderName,
+					CACerts: []string{certFile, certFile},
+				}))
+				cert, err := ioutil.ReadFile(certFile)
 				h.AssertNil(t, err)
-				assertTarFileContents(t, layerTar, "/platform/env/key1", `value1`)
-				assertTarFileContents(t, layerTar, "/platform/env/key2", `value2`)
+				h.AssertEq(t, string(fakeLifecycle.Opts.CACerts), string(cert)+string(cert))
+			})
+
+			it("errors when a file holds no certs", func() {
+				h.AssertError(t, subject.Build(context.TODO(), BuildOptions{
+					Image:   "some/app",
+					Builder: builderName,
+					CACerts: []string{filepath.Join("testdata", "just-a-file.txt")},
+				}), "no PEM encoded CA certs found in")
+			})
+		})
+
+		when("AppPath is a git repository", func() {
+			var (
+				repoDir string
+				commit  string
+			)
+
+			it.Before(func() {
+				if _, err := exec.LookPath("git"); err != nil {
+					t.Skip("git not installed")
+				}
+
+				var err error
+				repoDir, err = ioutil.TempDir("", "build-git-repo")
+				h.AssertNil(t, err)
+				h.AssertNil(t, os.MkdirAll(filepath.Join(repoDir, "apps", "web"), 0755))
+				h.AssertNil(t, ioutil.WriteFile(filepath.Join(repoDir, "apps", "web", "app.txt"), []byte("some-app"), 0644))
+
+				for _, args := range [][]string{
+					{"init", "--quiet"},
+					{"add", "."},
+					{"-c", "user.name=pack", "-c", "user.email=pack@example.com", "commit", "--quiet", "-m", "initial"},
+				} {
+					cmd := exec.Command("git", args...)
+					cmd.Dir = repoDir
+					_, err := cmd.CombinedOutput()
+					h.AssertNil(t, err)
+				}
+				cmd := exec.Command("git", "rev-parse", "HEAD")
+				cmd.Dir = repoDir
+				out, err := cmd.Output()
+				h.AssertNil(t, err)
+				commit = strings.TrimSpace(string(out))
+			})
+
+			it.After(func() {
+				os.RemoveAll(repoDir)
+			})
+
+			it("builds the subdir of a clean checkout", func() {
+				h.AssertNil(t, subject.Build(context.TODO(), BuildOptions{
+					Image:   "some/app",
+					Builder: builderName,
+					AppPath: "git+file://" + repoDir + "#HEAD:apps/web",
+				}))
+				h.AssertEq(t, filepath.Base(fakeLifecycle.Opts.AppPath), "web")
+
+				_, err := os.Stat(fakeLifecycle.Opts.AppPath)
+				h.AssertEq(t, os.IsNotExist(err), true)
+			})
+
+			it("passes the commit to lifecycle as an image label", func() {
+				h.AssertNil(t, subject.Build(context.TODO(), BuildOptions{
+					Image:   "some/app",
+					Builder: builderName,
+					AppPath: "git+file://" + repoDir,
+				}))
+
+				h.AssertEq(t, fakeLifecycle.Opts.Labels, map[string]string{
+					"io.buildpacks.project.metadata": fmt.Sprintf(`{"source":{"type":"git","version":{"commit":"%s"},"metadata":{"repository":"file://%s"}}}`, commit, repoDir),
+				})
+			})
+
+			it("fails for an unknown ref", func() {
+				h.AssertError(t, subject.Build(context.TODO(), BuildOptions{
+					Image:   "some/app",
+					Builder: builderName,
+					AppPath: "git+file://" + repoDir + "#missing",
+				}), "ref 'missing' not found")
+			})
+		})
+
+		when("Exclude option", func() {
+			var appDir string
+
+			it.Before(func() {
+				var err error
+				appDir, err = ioutil.TempDir("", "build-exclude")
+				h.AssertNil(t, err)
+			})
+
+			it.After(func() {
+				os.RemoveAll(appDir)
+			})
+
+			it("is passed to the lifecycle", func() {
+				h.AssertNil(t, subject.Build(context.TODO(), BuildOptions{
+					Image:   "some/app",
+					Builder: builderName,
+					AppPath: appDir,
+					Exclude: []string{"*.log"},
+					Include: []string{"src/"},
+				}))
+				h.AssertEq(t, fakeLifecycle.Opts.AppExclude, []string{"*.log"})
+				h.AssertEq(t, fakeLifecycle.Opts.AppInclude, []string{"src/"})
+			})
+
+			when("the app dir has a .packignore file", func() {
+				it("adds the patterns before the provided ones", func() {
+					h.AssertNil(t, ioutil.WriteFile(filepath.Join(appDir, ".packignore"), []byte("node_modules/\n.git/"), 0644))
+					h.AssertNil(t, subject.Build(context.TODO(), BuildOptions{
+						Image:   "some/app",
+						Builder: builderName,
+						AppPath: appDir,
+						Exclude: []string{"*.log"},
+					}))
+					h.AssertEq(t, fakeLifecycle.Opts.AppExclude, []string{"node_modules/", ".git/", "*.log"})
+				})
+			})
+		})
+
+		when("the app dir has a project descriptor", func() {
+			var appDir string
+
+			it.Before(func() {
+				var err error
+				appDir, err = ioutil.TempDir("", "build-project-descriptor")
+				h.AssertNil(t, err)
+				h.AssertNil(t, ioutil.WriteFile(filepath.Join(appDir, "project.toml"), []byte(fmt.Sprintf(`
+[build]
+builder = "%s"
+run-image = "registry1.example.com/run/mirror"
+
+[[build.buildpacks]]
+id = "buildpack.id"
+version = "buildpack.version"
+
+[[build.env]]
+name = "key1"
+value = "descriptor-value1"
+
+[[build.env]]
+name = "key2"
+value = "descriptor-value2"
+`, builderName)), 0644))
+			})
+
+			it.After(func() {
+				os.RemoveAll(appDir)
+			})
+
+			it("uses the values from the descriptor", func() {
+				h.AssertNil(t, subject.Build(context.TODO(), BuildOptions{
+					Image:   "some/app",
+					AppPath: appDir,
+				}))
+				h.AssertEq(t, fakeLifecycle.Opts.Builder.Name(), defaultBuilderImage.Name())
+				h.AssertEq(t, fakeLifecycle.Opts.RunImage, "registry1.example.com/run/mirror")
+
+				bldr, err := builder.GetBuilder(defaultBuilderImage)
+				h.AssertNil(t, err)
+				h.AssertEq(t, bldr.GetOrder(), builder.Order{
+					{Group: []builder.BuildpackRef{{
+						BuildpackInfo: buildpack.BuildpackInfo{ID: "buildpack.id", Version: "buildpack.version"},
+					}}},
+				})
+
+				h.AssertEq(t, fakeLifecycle.Opts.Env, map[string]string{
+					"key1.override": "descriptor-value1",
+					"key2.override": "descriptor-value2",
+				})
+			})
+
+			it("prefers the provided options", func() {
+				h.AssertNil(t, subject.Build(context.TODO(), BuildOptions{
+					Image:    "some/app",
+					AppPath:  appDir,
+					RunImage: "registry2.example.com/run/mirror",
+					Env:      map[string]string{"key1.override": "value1"},
+				}))
+				h.AssertEq(t, fakeLifecycle.Opts.RunImage, "registry2.example.com/run/mirror")
+
+				h.AssertEq(t, fakeLifecycle.Opts.Env, map[string]string{
+					"key1.override": "value1",
+					"key2.override": "descriptor-value2",
+				})
+			})
+
+			when("the descriptor is invalid", func() {
+				it("returns an error", func() {
+					h.AssertNil(t, ioutil.WriteFile(filepath.Join(appDir, "project.toml"), []byte(`[build`), 0644))
+					h.AssertError(t, subject.Build(context.TODO(), BuildOptions{
+						Image:   "some/app",
+						AppPath: appDir,
+					}), "parse contents of")
+				})
+			})
+		})
+
+		when("EventHandler option", func() {
+			it("emits fetch and ephemeral builder events", func() {
+				var events []build.Event
+				h.AssertNil(t, subject.Build(context.TODO(), BuildOptions{
+					Image:      "some/app",
+					Builder:    builderName,
+					Buildpacks: []string{"buildpack.id@buildpack.version"},
+					EventHandler: func(e build.Event) {
+						events = append(events, e)
+					},
+				}))
+
+				h.AssertEq(t, len(events), 5)
+				h.AssertEq(t, events[0].Type, build.EventBuilderFetchStarted)
+				h.AssertEq(t, events[0].Image, builderName)
+				h.AssertEq(t, events[1].Type, build.EventBuilderFetchFinished)
+				h.AssertEq(t, events[1].Image, builderName)
+				h.AssertNil(t, events[1].Err)
+				h.AssertEq(t, events[2].Type, build.EventRunImageFetchStarted)
+				h.AssertEq(t, events[2].Image, "default/run")
+				h.AssertEq(t, events[3].Type, build.EventRunImageFetchFinished)
+				h.AssertEq(t, events[4].Type, build.EventEphemeralBuilderCreated)
+				h.AssertEq(t, events[4].Image, defaultBuilderImage.Name())
+				for _, e := range events {
+					h.AssertEq(t, e.Time.IsZero(), false)
+				}
+			})
+
+			it("reports failed fetches", func() {
+				var events []build.Event
+				err := subject.Build(context.TODO(), BuildOptions{
+					Image:   "some/app",
+					Builder: "not/exist",
+					EventHandler: func(e build.Event) {
+						events = append(events, e)
+					},
+				})
+				h.AssertNotNil(t, err)
+				h.AssertEq(t, len(events), 2)
+				h.AssertEq(t, events[1].Type, build.EventBuilderFetchFinished)
+				h.AssertNotNil(t, events[1].Err)
+			})
+
+			it("passes the handler through to lifecycle", func() {
+				called := false
+				h.AssertNil(t, subject.Build(context.TODO(), BuildOptions{
+					Image:   "some/app",
+					Builder: builderName,
+					EventHandler: func(e build.Event) {
+						called = true
+					},
+				}))
+				called = false
+				fakeLifecycle.Opts.EventHandler.Emit(build.Event{Type: build.EventPhaseStarted})
+				h.AssertEq(t, called, true)
+			})
+		})
+
+		when("Report option", func() {
+			it("records the builder and run image", func() {
+				report := &BuildReport{}
+				h.AssertNil(t, subject.Build(context.TODO(), BuildOptions{
+					Image:   "registry1.example.com/some/app",
+					Builder: builderName,
+					Report:  report,
+				}))
+
+				h.AssertEq(t, report.Builder.Name, builderName)
+				h.AssertEq(t, report.RunImage.Image, "default/run")
+				h.AssertEq(t, report.RunImage.Mirror, "registry1.example.com/run/mirror")
+			})
+
+			it("records events from the lifecycle", func() {
+				report := &BuildReport{}
+				h.AssertNil(t, subject.Build(context.TODO(), BuildOptions{
+					Image:   "some/app",
+					Builder: builderName,
+					Report:  report,
+				}))
+				h.AssertEq(t, report.RunImage.Mirror, "")
+
+				fakeLifecycle.Opts.EventHandler.Emit(build.Event{Type: build.EventCacheSelected, Cache: "some-cache"})
+				fakeLifecycle.Opts.EventHandler.Emit(build.Event{
+					Type:       build.EventGroupDetected,
+					Buildpacks: []buildpack.BuildpackInfo{{ID: "some.bp", Version: "1.2.3"}},
+				})
+				fakeLifecycle.Opts.EventHandler.Emit(build.Event{Type: build.EventPhaseFinished, Phase: "detector", Duration: 2 * time.Second, ExitCode: 0})
+				fakeLifecycle.Opts.EventHandler.Emit(build.Event{Type: build.EventImageExported, Image: "some/app", Digest: "sha256:abc"})
+
+				h.AssertEq(t, report.Caches, []string{"some-cache"})
+				h.AssertEq(t, report.Buildpacks, []buildpack.BuildpackInfo{{ID: "some.bp", Version: "1.2.3"}})
+				h.AssertEq(t, len(report.Phases), 1)
+				h.AssertEq(t, report.Phases[0].Name, "detector")
+				h.AssertEq(t, report.Phases[0].DurationSeconds, 2.0)
+				h.AssertEq(t, report.Image, ImageReport{Name: "some/app", Digest: "sha256:abc"})
+				h.AssertEq(t, len(report.AdditionalTags), 0)
+
+				fakeLifecycle.Opts.EventHandler.Emit(build.Event{Type: build.EventImageExported, Image: "some/app:v1", Digest: "sha256:abc"})
+				h.AssertEq(t, report.AdditionalTags, []ImageReport{{Name: "some/app:v1", Digest: "sha256:abc"}})
+			})
+		})
+
+		when("AdditionalTags option", func() {
+			it("passes the tags to the lifecycle", func() {
+				h.AssertNil(t, subject.Build(context.TODO(), BuildOptions{
+					Image:          "some/app",
+					Builder:        builderName,
+					AdditionalTags: []string{"some/app:v1", "other.registry.io/some/app:latest"},
+				}))
+				h.AssertEq(t, fakeLifecycle.Opts.AdditionalTags, []string{
+					"index.docker.io/some/app:v1",
+					"other.registry.io/some/app:latest",
+				})
+			})
+
+			it("must be valid tag references", func() {
+				h.AssertError(t, subject.Build(context.TODO(), BuildOptions{
+					Image:          "some/app",
+					Builder:        builderName,
+					AdditionalTags: []string{"some/app@sha256:a7c5e9d3e1a0c7b1c6f8d3e9e1b0f1a2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8"},
+				}), "invalid additional tag")
+			})
+
+			when("publishing", func() {
+				it("requires the tags to be on the same registry", func() {
+					h.AssertError(t, subject.Build(context.TODO(), BuildOptions{
+						Image:          "registry1.example.com/some/app",
+						Builder:        builderName,
+						Publish:        true,
+						AdditionalTags: []string{"registry1.example.com/some/app:v1", "registry2.example.com/some/app:v1"},
+					}), "additional tag 'registry2.example.com/some/app:v1' must be on registry 'registry1.example.com' when publishing")
+				})
 			})
 		})
 
@@ -883,35 +1358,3 @@ func testBuild(t *testing.T, when spec.G, it spec.S) {
 		})
 	})
 }
-
-func assertTarFileContents(t *testing.T, tarfile, path, expected string) {
-	t.Helper()
-	exist, contents := tarFileContents(t, tarfile, path)
-	if !exist {
-		t.Fatalf("%s does not exist in %s", path, tarfile)
-	}
-	h.AssertEq(t, contents, expected)
-}
-
-func tarFileContents(t *testing.T, tarfile, path string) (exist bool, contents string) {
-	t.Helper()
-	r, err := os.Open(tarfile)
-	h.AssertNil(t, err)
-	defer r.Close()
-
-	tr := tar.NewReader(r)
-	for {
-		header, err := tr.Next()
-		if err == io.EOF {
-			break
-		}
-		h.AssertNil(t, err)
-
-		if header.Name == path {
-			buf, err := ioutil.ReadAll(tr)
-			h.AssertNil(t, err)
-			return true, string(buf)
-		}
-	}
-	return false, ""
-}
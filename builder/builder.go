@@ -45,7 +45,6 @@ type Builder struct {
 	lifecyclePath        string
 	additionalBuildpacks []buildpack.Buildpack
 	metadata             Metadata
-	env                  map[string]string
 	UID, GID             int
 	StackID              string
 	replaceOrder         bool
@@ -138,7 +137,6 @@ func New(img imgutil.Image, name string) (*Builder, error) {
 		UID:      uid,
 		GID:      gid,
 		StackID:  stackID,
-		env:      map[string]string{},
 	}, nil
 }
 
@@ -150,6 +148,11 @@ func (b *Builder) GetLifecycleVersion() *semver.Version {
 	return b.metadata.Lifecycle.Version
 }
 
+// Platform returns how pack drives the lifecycle on the builder
+func (b *Builder) Platform() lifecycle.Platform {
+	return lifecycle.PlatformFor(b.GetLifecycleVersion())
+}
+
 func (b *Builder) GetBuildpacks() []BuildpackMetadata {
 	return b.metadata.Buildpacks
 }
@@ -179,10 +182,6 @@ func (b *Builder) SetLifecycle(md lifecycle.Metadata) error {
 	return nil
 }
 
-func (b *Builder) SetEnv(env map[string]string) {
-	b.env = env
-}
-
 func (b *Builder) SetOrder(order Order) {
 	b.order = order
 	b.replaceOrder = true
@@ -275,14 +274,6 @@ func (b *Builder) Save() error {
 		return errors.Wrap(err, "adding compat.tar layer")
 	}
 
-	envTar, err := b.envLayer(tmpDir, b.env)
-	if err != nil {
-		return err
-	}
-	if err := b.image.AddLayer(envTar); err != nil {
-		return errors.Wrap(err, "adding env layer")
-	}
-
 	label, err := json.Marshal(b.metadata)
 	if err != nil {
 		return errors.Wrap(err, "failed marshal builder image metadata")
@@ -483,13 +474,11 @@ func (b *Builder) orderLayer(dest string) (string, error) {
 
 func (b *Builder) orderFileContents() (string, error) {
 	buf := &bytes.Buffer{}
-	lifecycleVersion := b.GetLifecycleVersion()
-	var (
-		tomlData interface{}
-	)
-	if lifecycleVersion != nil && lifecycleVersion.LessThan(semver.MustParse("0.4.0")) {
+	var tomlData interface{}
+	switch b.Platform().Layout().OrderFormat {
+	case lifecycle.OrderGroups:
 		tomlData = v1OrderTOML{Groups: b.metadata.Groups}
-	} else {
+	default:
 		tomlData = orderTOML{Order: b.order}
 	}
 	if err := toml.NewEncoder(buf).Encode(tomlData); err != nil {
@@ -562,6 +551,7 @@ func (b *Builder) buildpackLayer(dest string, bp buildpack.Buildpack) (string, e
 			b.UID,
 			b.GID,
 			-1,
+			nil,
 		)
 	}
 
@@ -691,35 +681,6 @@ func (b *Builder) embedLifecycleTar(tw *tar.Writer, srcTar string) error {
 	return nil
 }
 
-func (b *Builder) envLayer(dest string, env map[string]string) (string, error) {
-	fh, err := os.Create(filepath.Join(dest, "env.tar"))
-	if err != nil {
-		return "", err
-	}
-	defer fh.Close()
-
-	tw := tar.NewWriter(fh)
-	defer tw.Close()
-
-	now := time.Now()
-
-	for k, v := range env {
-		if err := tw.WriteHeader(&tar.Header{
-			Name:    path.Join(platformDir, "env", k),
-			Size:    int64(len(v)),
-			Mode:    0644,
-			ModTime: now,
-		}); err != nil {
-			return "", err
-		}
-		if _, err := tw.Write([]byte(v)); err != nil {
-			return "", err
-		}
-	}
-
-	return fh.Name(), nil
-}
-
 func (b *Builder) lifecycleLayer(dest string) (string, error) {
 	fh, err := os.Create(filepath.Join(dest, "lifecycle.tar"))
 	if err != nil {
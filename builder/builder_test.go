@@ -916,23 +916,5 @@ func testBuilder(t *testing.T, when spec.G, it spec.S) {
 				h.AssertEq(t, metadata.Stack.RunImage.Mirrors[1], "other/mirror")
 			})
 		})
-
-		when("#SetEnv", func() {
-			it.Before(func() {
-				subject.SetEnv(map[string]string{
-					"SOME_KEY":  "some-val",
-					"OTHER_KEY": "other-val",
-				})
-				h.AssertNil(t, subject.Save())
-				h.AssertEq(t, baseImage.IsSaved(), true)
-			})
-
-			it("adds the env vars as files to the image", func() {
-				layerTar, err := baseImage.FindLayerWithPath("/platform/env/SOME_KEY")
-				h.AssertNil(t, err)
-				h.AssertOnTarEntry(t, layerTar, "/platform/env/SOME_KEY", h.ContentEquals(`some-val`))
-				h.AssertOnTarEntry(t, layerTar, "/platform/env/OTHER_KEY", h.ContentEquals(`other-val`))
-			})
-		})
 	})
 }
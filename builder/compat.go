@@ -9,7 +9,6 @@ import (
 	"time"
 
 	"github.com/BurntSushi/toml"
-	"github.com/Masterminds/semver"
 	"github.com/pkg/errors"
 
 	"github.com/buildpack/pack/buildpack"
@@ -119,7 +118,7 @@ func (b *Builder) compatBuildpacks(tw *tar.Writer) error {
 			return err
 		}
 
-		if lifecycleVersion := b.GetLifecycleVersion(); lifecycleVersion != nil && lifecycleVersion.LessThan(semver.MustParse("0.4.0")) {
+		if b.Platform().Layout().LatestSymlinks {
 			if err := symlinkLatest(tw, bpDir, bp, b.metadata); err != nil {
 				return err
 			}
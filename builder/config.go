@@ -3,7 +3,6 @@ package builder
 import (
 	"fmt"
 	"io"
-	"net/url"
 	"os"
 	"path/filepath"
 
@@ -114,7 +113,7 @@ func parseConfig(reader io.Reader, relativeToDir string) (Config, error) {
 	}
 
 	for i, bp := range builderConfig.Buildpacks {
-		uri, err := transformRelativePath(bp.URI, relativeToDir)
+		uri, err := paths.ResolveRelativeUri(bp.URI, relativeToDir)
 		if err != nil {
 			return Config{}, errors.Wrap(err, "transforming buildpack URI")
 		}
@@ -122,7 +121,7 @@ func parseConfig(reader io.Reader, relativeToDir string) (Config, error) {
 	}
 
 	if builderConfig.Lifecycle.URI != "" {
-		uri, err := transformRelativePath(builderConfig.Lifecycle.URI, relativeToDir)
+		uri, err := paths.ResolveRelativeUri(builderConfig.Lifecycle.URI, relativeToDir)
 		if err != nil {
 			return Config{}, errors.Wrap(err, "transforming lifecycle URI")
 		}
@@ -131,19 +130,3 @@ func parseConfig(reader io.Reader, relativeToDir string) (Config, error) {
 
 	return builderConfig, nil
 }
-
-func transformRelativePath(uri, relativeTo string) (string, error) {
-	parsed, err := url.Parse(uri)
-	if err != nil {
-		return "", err
-	}
-
-	if parsed.Scheme == "" {
-		if !filepath.IsAbs(parsed.Path) {
-			absPath := filepath.Join(relativeTo, parsed.Path)
-			return paths.FilePathToUri(absPath)
-		}
-	}
-
-	return uri, nil
-}
@@ -0,0 +1,112 @@
+package builder
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/buildpack/pack/style"
+)
+
+// EnvOp is how a build-time env var is combined with the value buildpacks give it
+type EnvOp string
+
+const (
+	EnvOverride EnvOp = "="  // replaces the value
+	EnvAppend   EnvOp = "+=" // added after the value, separated by the delimiter
+	EnvPrepend  EnvOp = "^=" // added before the value, separated by the delimiter
+	EnvDefault  EnvOp = "?=" // used when the variable has no value
+)
+
+// DefaultEnvDelim separates appended and prepended values from the value buildpacks give the variable, unless a
+// NAME.delim file says otherwise. It suits PATH-like variables.
+const DefaultEnvDelim = ":"
+
+// envDelimSuffix is the suffix of the platform env file a variable's delimiter is kept in
+const envDelimSuffix = ".delim"
+
+// envOpSuffixes are the suffixes of the platform env files buildpacks read each operator from
+var envOpSuffixes = map[EnvOp]string{
+	EnvOverride: ".override",
+	EnvAppend:   ".append",
+	EnvPrepend:  ".prepend",
+	EnvDefault:  ".default",
+}
+
+// ParseEnvAssignment splits an assignment of the form NAME<op>VALUE, such as 'PATH^=/opt/bin', into the name of the
+// platform env file it is kept in and the value. ok is false when item has no value, as in the 'NAME' form, which
+// overrides the variable with a value the caller looks up by item.
+func ParseEnvAssignment(item string) (file, value string, ok bool, err error) {
+	i := strings.Index(item, "=")
+	if i < 0 {
+		if strings.ContainsAny(item, "+^?") {
+			// most likely an operator missing its '=' or value, which would otherwise be looked up as a name
+			return "", "", false, fmt.Errorf("invalid env var name %s, operators need a value as in 'NAME+=VALUE'", style.Symbol(item))
+		}
+		file, err := EnvFile(item, EnvOverride)
+		return file, "", false, err
+	}
+
+	name, op := item[:i], EnvOverride
+	for _, candidate := range []EnvOp{EnvAppend, EnvPrepend, EnvDefault} {
+		if strings.HasSuffix(item[:i+1], string(candidate)) {
+			name, op = item[:i+1-len(candidate)], candidate
+			break
+		}
+	}
+	file, err = EnvFile(name, op)
+	if err != nil {
+		return "", "", false, err
+	}
+	return file, item[i+1:], true, nil
+}
+
+// EnvFile returns the name of the platform env file the value of the variable name is kept in for op. A delimiter,
+// given as NAME.delim, is kept in that file.
+func EnvFile(name string, op EnvOp) (string, error) {
+	if err := validateEnvName(name); err != nil {
+		return "", err
+	}
+	if op == EnvOverride && strings.HasSuffix(name, envDelimSuffix) {
+		return name, nil
+	}
+	return name + envOpSuffixes[op], nil
+}
+
+// ValidateEnvFiles checks that each platform env file in env, which is keyed on file names as returned by
+// ParseEnvAssignment, is directly in the platform env dir
+func ValidateEnvFiles(env map[string]string) error {
+	for file := range env {
+		if err := validateEnvName(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateEnvName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid env var name %s", style.Symbol(name))
+	}
+	return nil
+}
+
+// EnvFiles returns the contents of the platform env files for env, which is keyed on file names as returned by
+// ParseEnvAssignment. The default delimiter is added for each variable appended or prepended to without one.
+func EnvFiles(env map[string]string) map[string]string {
+	result := map[string]string{}
+	for k, v := range env {
+		result[k] = v
+	}
+	for k := range env {
+		for _, suffix := range []string{envOpSuffixes[EnvAppend], envOpSuffixes[EnvPrepend]} {
+			if !strings.HasSuffix(k, suffix) {
+				continue
+			}
+			delimFile := strings.TrimSuffix(k, suffix) + envDelimSuffix
+			if _, ok := env[delimFile]; !ok {
+				result[delimFile] = DefaultEnvDelim
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,99 @@
+package builder_test
+
+import (
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpack/pack/builder"
+	h "github.com/buildpack/pack/testhelpers"
+)
+
+func TestEnv(t *testing.T) {
+	spec.Run(t, "Env", testEnv, spec.Parallel(), spec.Report(report.Terminal{}))
+}
+
+func testEnv(t *testing.T, when spec.G, it spec.S) {
+	when("#ParseEnvAssignment", func() {
+		for _, tc := range []struct {
+			item, file, value string
+		}{
+			{"SOME_KEY=some-val", "SOME_KEY.override", "some-val"},
+			{"SOME_KEY=a=b", "SOME_KEY.override", "a=b"},
+			{"SOME_KEY=", "SOME_KEY.override", ""},
+			{"PATH+=/opt/bin", "PATH.append", "/opt/bin"},
+			{"PATH^=/opt/bin", "PATH.prepend", "/opt/bin"},
+			{"SOME_KEY?=some-default", "SOME_KEY.default", "some-default"},
+			{"SOME_KEY.delim= ", "SOME_KEY.delim", " "},
+		} {
+			tc := tc
+			it("parses "+tc.item, func() {
+				file, value, ok, err := builder.ParseEnvAssignment(tc.item)
+				h.AssertNil(t, err)
+				h.AssertEq(t, ok, true)
+				h.AssertEq(t, file, tc.file)
+				h.AssertEq(t, value, tc.value)
+			})
+		}
+
+		it("returns the override file when there is no value", func() {
+			file, _, ok, err := builder.ParseEnvAssignment("SOME_KEY")
+			h.AssertNil(t, err)
+			h.AssertEq(t, ok, false)
+			h.AssertEq(t, file, "SOME_KEY.override")
+		})
+
+		for _, item := range []string{"PATH+", "PATH^", "SOME_KEY?"} {
+			item := item
+			it("errors for the operator without a value in "+item, func() {
+				_, _, _, err := builder.ParseEnvAssignment(item)
+				h.AssertError(t, err, "invalid env var name '"+item+"', operators need a value")
+			})
+		}
+
+		it("errors when the name is missing", func() {
+			_, _, _, err := builder.ParseEnvAssignment("+=value")
+			h.AssertError(t, err, "invalid env var name ''")
+		})
+
+		it("errors when the name is a path", func() {
+			_, _, _, err := builder.ParseEnvAssignment("../SOME_KEY=value")
+			h.AssertError(t, err, "invalid env var name '../SOME_KEY'")
+		})
+
+		it("errors when the name is the parent dir", func() {
+			_, _, _, err := builder.ParseEnvAssignment("..=value")
+			h.AssertError(t, err, "invalid env var name '..'")
+		})
+	})
+
+	when("#ValidateEnvFiles", func() {
+		it("accepts files in the platform env dir", func() {
+			h.AssertNil(t, builder.ValidateEnvFiles(map[string]string{"SOME_KEY.override": "value", "PATH.append": "/some/bin"}))
+		})
+
+		it("errors for a file outside it", func() {
+			err := builder.ValidateEnvFiles(map[string]string{"../../etc/some-file": "value"})
+			h.AssertError(t, err, "invalid env var name '../../etc/some-file'")
+		})
+	})
+
+	when("#EnvFiles", func() {
+		it("adds the default delimiter for variables appended or prepended to without one", func() {
+			files := builder.EnvFiles(map[string]string{
+				"SOME_KEY.override": "some-val",
+				"PATH.prepend":      "/opt/bin",
+				"JAVA_OPTS.append":  "-Xmx1g",
+				"JAVA_OPTS.delim":   " ",
+			})
+			h.AssertEq(t, files, map[string]string{
+				"SOME_KEY.override": "some-val",
+				"PATH.prepend":      "/opt/bin",
+				"PATH.delim":        ":",
+				"JAVA_OPTS.append":  "-Xmx1g",
+				"JAVA_OPTS.delim":   " ",
+			})
+		})
+	})
+}
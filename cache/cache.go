@@ -0,0 +1,47 @@
+package cache
+
+import "strings"
+
+const (
+	// NamePrefix starts the names of all cache volumes and images pack creates
+	NamePrefix = "pack-cache-"
+	// ImageLabel is set on cache volumes and images to the name of the app image they cache layers for
+	ImageLabel = "io.buildpacks.pack.cache.image"
+	// NameLabel is set on cache volumes and images to the name they were given to share them between app images
+	NameLabel = "io.buildpacks.pack.cache.name"
+)
+
+// Type is where a build cache is kept
+type Type int
+
+const (
+	Image  Type = iota // an image in the daemon
+	Volume             // a volume in the daemon
+)
+
+func (t Type) String() string {
+	switch t {
+	case Image:
+		return "image"
+	case Volume:
+		return "volume"
+	}
+	return "unknown"
+}
+
+// namedCacheKeyPrefix starts the keys of named caches
+const namedCacheKeyPrefix = "name:"
+
+// NamedCacheKey keeps the names of named caches, and the records of their use, distinct from those of caches keyed
+// on an image
+func NamedCacheKey(cacheName string) string {
+	return namedCacheKeyPrefix + cacheName
+}
+
+// ParseNamedCacheKey returns the name of the cache key is for, and whether key was returned by NamedCacheKey
+func ParseNamedCacheKey(key string) (string, bool) {
+	if !strings.HasPrefix(key, namedCacheKeyPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(key, namedCacheKeyPrefix), true
+}
@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/buildpack/imgutil"
+	"github.com/docker/docker/api/types"
+	dcontainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/pkg/errors"
+
+	"github.com/buildpack/pack/container"
+	"github.com/buildpack/pack/internal/resource"
+	"github.com/buildpack/pack/style"
+)
+
+const (
+	// helperImage is an empty image for creating containers that give access to a cache volume. They are never
+	// started, so the image needs no contents.
+	helperImage = "pack.local/cache-helper:latest"
+	// helperMountPath is where helper containers mount the cache volume, and so the directory that exported volume
+	// contents are under
+	helperMountPath = "/cache"
+)
+
+// withVolume calls f with the ID of a container that has volume mounted at helperMountPath, and removes the
+// container afterwards
+func withVolume(ctx context.Context, runtime container.Runtime, volume string, f func(ctrID string) error) error {
+	if err := ensureHelperImage(ctx, runtime); err != nil {
+		return errors.Wrap(err, "creating cache helper image")
+	}
+
+	ctr, err := runtime.ContainerCreate(ctx,
+		&dcontainer.Config{Image: helperImage, Cmd: []string{"none"}, Labels: resource.Labels(resource.PurposeCacheHelper)},
+		&dcontainer.HostConfig{Binds: []string{volume + ":" + helperMountPath}},
+		nil, "",
+	)
+	if err != nil {
+		return errors.Wrap(err, "creating cache helper container")
+	}
+	defer runtime.ContainerRemove(context.Background(), ctr.ID, types.ContainerRemoveOptions{Force: true})
+
+	return f(ctr.ID)
+}
+
+// ensureHelperImage creates the helper image when the runtime does not have it. Only the Docker runtime can create
+// it, others must provide it.
+func ensureHelperImage(ctx context.Context, runtime container.Runtime) error {
+	if _, _, err := runtime.ImageInspectWithRaw(ctx, helperImage); err == nil {
+		return nil
+	}
+	docker, ok := runtime.(*client.Client)
+	if !ok {
+		return fmt.Errorf("image %s not found", style.Symbol(helperImage))
+	}
+	_, err := imgutil.EmptyLocalImage(helperImage, docker).Save()
+	return err
+}
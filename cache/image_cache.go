@@ -1,24 +1,45 @@
 package cache
 
 import (
+	"archive/tar"
 	"context"
 	"crypto/sha256"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 
 	"github.com/docker/docker/api/types"
+	dcontainer "github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
 	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/pkg/errors"
+
+	"github.com/buildpack/pack/style"
 )
 
 type ImageCache struct {
 	docker *client.Client
 	image  string
+	labels map[string]string
 }
 
 func NewImageCache(imageRef name.Reference, dockerClient *client.Client) *ImageCache {
 	sum := sha256.Sum256([]byte(imageRef.String()))
 	return &ImageCache{
-		image:  fmt.Sprintf("pack-cache-%x", sum[:6]),
+		image:  fmt.Sprintf("%s%x", NamePrefix, sum[:6]),
+		labels: map[string]string{ImageLabel: imageRef.Name()},
+		docker: dockerClient,
+	}
+}
+
+// NewNamedImageCache returns the image cache called cacheName, which can be shared between images
+func NewNamedImageCache(cacheName string, dockerClient *client.Client) *ImageCache {
+	sum := sha256.Sum256([]byte(NamedCacheKey(cacheName)))
+	return &ImageCache{
+		image:  fmt.Sprintf("%s%x", NamePrefix, sum[:6]),
+		labels: map[string]string{NameLabel: cacheName},
 		docker: dockerClient,
 	}
 }
@@ -27,6 +48,139 @@ func (c *ImageCache) Name() string {
 	return c.image
 }
 
+// Label labels the cache image with the image or name it is for. The lifecycle writes a new cache image without the
+// labels on every build, so this must be called after it has run. Rather than saving the image again, the labels are
+// added by committing a container of it, which reuses its layers, and only when they are missing or have changed.
+func (c *ImageCache) Label(ctx context.Context) error {
+	inspect, _, err := c.docker.ImageInspectWithRaw(ctx, c.Name())
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	var current map[string]string
+	if inspect.Config != nil {
+		current = inspect.Config.Labels
+	}
+	changed := false
+	for k, v := range c.labels {
+		if current[k] != v {
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	// the container is never started, and is not labelled as the image committed from it would get its labels
+	ctr, err := c.docker.ContainerCreate(ctx, &dcontainer.Config{Image: c.Name(), Cmd: []string{"none"}}, nil, nil, "")
+	if err != nil {
+		return errors.Wrap(err, "creating container to label cache image")
+	}
+	defer c.docker.ContainerRemove(context.Background(), ctr.ID, types.ContainerRemoveOptions{Force: true})
+
+	_, err = c.docker.ContainerCommit(ctx, ctr.ID, types.ContainerCommitOptions{
+		Reference: c.Name(),
+		Config:    &dcontainer.Config{Labels: c.labels},
+	})
+	return err
+}
+
+// Empty reports whether the image is missing
+func (c *ImageCache) Empty(ctx context.Context) (bool, error) {
+	_, _, err := c.docker.ImageInspectWithRaw(ctx, c.Name())
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	return false, nil
+}
+
+// Export writes the image to w in the format of docker save
+func (c *ImageCache) Export(ctx context.Context, w io.Writer) error {
+	rc, err := c.docker.ImageSave(ctx, []string{c.Name()})
+	if err != nil {
+		return errors.Wrapf(err, "saving cache image %s", style.Symbol(c.Name()))
+	}
+	defer rc.Close()
+	_, err = io.Copy(w, rc)
+	return err
+}
+
+// Import loads an image written by Export and tags it with the name of this cache. The image is tagged with the
+// name it was exported with in the tar, which may be the cache of another app, so the tar is rewritten as it is loaded
+// to tag it with the name of this cache instead, and no other image is tagged or untagged.
+func (c *ImageCache) Import(ctx context.Context, r io.Reader) error {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(retagSavedImage(r, pw, c.Name()+":latest"))
+	}()
+	defer pr.Close()
+
+	res, err := c.docker.ImageLoad(ctx, pr, true)
+	if err != nil {
+		return errors.Wrap(err, "loading cache image")
+	}
+	defer res.Body.Close()
+	if err := jsonmessage.DisplayJSONMessagesStream(res.Body, ioutil.Discard, 0, false, nil); err != nil {
+		return errors.Wrap(err, "loading cache image")
+	}
+	return c.Label(ctx)
+}
+
+// retagSavedImage copies a tar written by docker save of a single image from r to w, with the tags in its manifest
+// replaced by tag. The legacy repositories file, which also names the tags, is left out.
+func retagSavedImage(r io.Reader, w io.Writer, tag string) error {
+	tr := tar.NewReader(r)
+	tw := tar.NewWriter(w)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		switch header.Name {
+		case "repositories":
+			continue
+		case "manifest.json":
+			var manifest []map[string]interface{}
+			if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+				return errors.Wrap(err, "parsing manifest")
+			}
+			if len(manifest) != 1 {
+				return fmt.Errorf("expected a single image in manifest, found %d", len(manifest))
+			}
+			manifest[0]["RepoTags"] = []string{tag}
+			contents, err := json.Marshal(manifest)
+			if err != nil {
+				return err
+			}
+			header.Size = int64(len(contents))
+			if err := tw.WriteHeader(header); err != nil {
+				return err
+			}
+			if _, err := tw.Write(contents); err != nil {
+				return err
+			}
+		default:
+			if err := tw.WriteHeader(header); err != nil {
+				return err
+			}
+			if _, err := io.Copy(tw, tr); err != nil {
+				return err
+			}
+		}
+	}
+	return tw.Close()
+}
+
 func (c *ImageCache) Clear(ctx context.Context) error {
 	_, err := c.docker.ImageRemove(ctx, c.Name(), types.ImageRemoveOptions{
 		Force: true,
@@ -36,3 +190,7 @@ func (c *ImageCache) Clear(ctx context.Context) error {
 	}
 	return nil
 }
+
+func (c *ImageCache) Type() Type {
+	return Image
+}
@@ -1,6 +1,7 @@
 package cache_test
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"math/rand"
@@ -96,6 +97,100 @@ func testImageCache(t *testing.T, when spec.G, it spec.S) {
 		})
 	})
 
+	when("#Label", func() {
+		var (
+			dockerClient *client.Client
+			subject      *cache.ImageCache
+			ref          name.Reference
+			ctx          context.Context
+		)
+
+		it.Before(func() {
+			var err error
+			dockerClient, err = client.NewClientWithOpts(client.FromEnv, client.WithVersion("1.38"))
+			h.AssertNil(t, err)
+			ctx = context.TODO()
+
+			ref, err = name.ParseReference(h.RandString(10), name.WeakValidation)
+			h.AssertNil(t, err)
+			subject = cache.NewImageCache(ref, dockerClient)
+		})
+
+		it.After(func() {
+			h.AssertNil(t, h.DockerRmi(dockerClient, subject.Name()))
+		})
+
+		it("adds the labels keeping the labels and layers written by the lifecycle", func() {
+			h.CreateImageOnLocal(t, dockerClient, subject.Name(), "FROM busybox\nLABEL io.buildpacks.lifecycle.cache.metadata={}")
+			before, _, err := dockerClient.ImageInspectWithRaw(ctx, subject.Name())
+			h.AssertNil(t, err)
+
+			h.AssertNil(t, subject.Label(ctx))
+
+			after, _, err := dockerClient.ImageInspectWithRaw(ctx, subject.Name())
+			h.AssertNil(t, err)
+			h.AssertEq(t, after.Config.Labels[cache.ImageLabel], ref.Name())
+			h.AssertEq(t, after.Config.Labels["io.buildpacks.lifecycle.cache.metadata"], "{}")
+			h.AssertEq(t, after.RootFS.Layers[:len(before.RootFS.Layers)], before.RootFS.Layers)
+		})
+
+		it("leaves an image that is labelled already alone", func() {
+			h.CreateImageOnLocal(t, dockerClient, subject.Name(), fmt.Sprintf("FROM busybox\nLABEL %s=%s", cache.ImageLabel, ref.Name()))
+			before, _, err := dockerClient.ImageInspectWithRaw(ctx, subject.Name())
+			h.AssertNil(t, err)
+
+			h.AssertNil(t, subject.Label(ctx))
+
+			after, _, err := dockerClient.ImageInspectWithRaw(ctx, subject.Name())
+			h.AssertNil(t, err)
+			h.AssertEq(t, after.ID, before.ID)
+		})
+	})
+
+	when("#Import", func() {
+		var (
+			dockerClient *client.Client
+			subject      *cache.ImageCache
+			otherCache   *cache.ImageCache
+			ctx          context.Context
+		)
+
+		it.Before(func() {
+			var err error
+			dockerClient, err = client.NewClientWithOpts(client.FromEnv, client.WithVersion("1.38"))
+			h.AssertNil(t, err)
+			ctx = context.TODO()
+
+			ref, err := name.ParseReference(h.RandString(10), name.WeakValidation)
+			h.AssertNil(t, err)
+			subject = cache.NewImageCache(ref, dockerClient)
+			otherRef, err := name.ParseReference(h.RandString(10), name.WeakValidation)
+			h.AssertNil(t, err)
+			otherCache = cache.NewImageCache(otherRef, dockerClient)
+		})
+
+		it.After(func() {
+			h.AssertNil(t, h.DockerRmi(dockerClient, subject.Name(), otherCache.Name()))
+		})
+
+		it("tags the image with the name of the cache and leaves the cache it was exported from alone", func() {
+			h.CreateImageOnLocal(t, dockerClient, otherCache.Name(), "FROM busybox\nLABEL some=label")
+			before, _, err := dockerClient.ImageInspectWithRaw(ctx, otherCache.Name())
+			h.AssertNil(t, err)
+
+			buf := &bytes.Buffer{}
+			h.AssertNil(t, otherCache.Export(ctx, buf))
+			h.AssertNil(t, subject.Import(ctx, buf))
+
+			after, _, err := dockerClient.ImageInspectWithRaw(ctx, otherCache.Name())
+			h.AssertNil(t, err)
+			h.AssertEq(t, after.ID, before.ID)
+			imported, _, err := dockerClient.ImageInspectWithRaw(ctx, subject.Name())
+			h.AssertNil(t, err)
+			h.AssertEq(t, imported.Config.Labels["some"], "label")
+		})
+	})
+
 	when("#Clear", func() {
 		var (
 			imageName    string
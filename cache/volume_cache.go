@@ -1,24 +1,45 @@
 package cache
 
 import (
+	"archive/tar"
 	"context"
 	"crypto/sha256"
 	"fmt"
+	"io"
 
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/volume"
 	"github.com/docker/docker/client"
 	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/pkg/errors"
+
+	"github.com/buildpack/pack/container"
+	"github.com/buildpack/pack/internal/resource"
+	"github.com/buildpack/pack/style"
 )
 
 type VolumeCache struct {
-	docker *client.Client
-	volume string
+	runtime container.Runtime
+	volume  string
+	labels  map[string]string
 }
 
-func NewVolumeCache(imageRef name.Reference, suffix string, dockerClient *client.Client) *VolumeCache {
+func NewVolumeCache(imageRef name.Reference, suffix string, runtime container.Runtime) *VolumeCache {
 	sum := sha256.Sum256([]byte(imageRef.String()))
 	return &VolumeCache{
-		volume: fmt.Sprintf("pack-cache-%x.%s", sum[:6], suffix),
-		docker: dockerClient,
+		volume:  fmt.Sprintf("%s%x.%s", NamePrefix, sum[:6], suffix),
+		labels:  map[string]string{ImageLabel: imageRef.Name()},
+		runtime: runtime,
+	}
+}
+
+// NewNamedVolumeCache returns the volume cache called cacheName, which can be shared between images
+func NewNamedVolumeCache(cacheName, suffix string, runtime container.Runtime) *VolumeCache {
+	sum := sha256.Sum256([]byte(NamedCacheKey(cacheName)))
+	return &VolumeCache{
+		volume:  fmt.Sprintf("%s%x.%s", NamePrefix, sum[:6], suffix),
+		labels:  map[string]string{NameLabel: cacheName},
+		runtime: runtime,
 	}
 }
 
@@ -26,10 +47,104 @@ func (c *VolumeCache) Name() string {
 	return c.volume
 }
 
+// Create creates the volume labelled with the image or name it is for, and as created by pack, unless it already
+// exists. Volumes created by earlier versions of pack are left unlabelled.
+func (c *VolumeCache) Create(ctx context.Context) error {
+	_, err := c.runtime.VolumeInspect(ctx, c.Name())
+	if err == nil || !client.IsErrNotFound(err) {
+		return err
+	}
+
+	labels := resource.Labels(resource.PurposeCache)
+	for k, v := range c.labels {
+		labels[k] = v
+	}
+	_, err = c.runtime.VolumeCreate(ctx, volume.VolumeCreateBody{
+		Name:   c.Name(),
+		Labels: labels,
+	})
+	return err
+}
+
+// Empty reports whether the volume is missing or has nothing in it
+func (c *VolumeCache) Empty(ctx context.Context) (bool, error) {
+	if _, err := c.runtime.VolumeInspect(ctx, c.Name()); err != nil {
+		if client.IsErrNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+
+	empty := true
+	err := withVolume(ctx, c.runtime, c.Name(), func(ctrID string) error {
+		rc, _, err := c.runtime.CopyFromContainer(ctx, ctrID, helperMountPath)
+		if err != nil {
+			return errors.Wrapf(err, "reading cache volume %s", style.Symbol(c.Name()))
+		}
+		defer rc.Close()
+
+		// the first entry is the cache directory itself, anything after it is contents
+		tr := tar.NewReader(rc)
+		for i := 0; i < 2; i++ {
+			if _, err := tr.Next(); err != nil {
+				if err == io.EOF {
+					return nil
+				}
+				return err
+			}
+		}
+		empty = false
+		return nil
+	})
+	return empty, err
+}
+
+// Export writes the contents of the volume to w as a tar, with all entries under the cache directory
+func (c *VolumeCache) Export(ctx context.Context, w io.Writer) error {
+	if _, err := c.runtime.VolumeInspect(ctx, c.Name()); err != nil {
+		if client.IsErrNotFound(err) {
+			return fmt.Errorf("cache volume %s does not exist", style.Symbol(c.Name()))
+		}
+		return err
+	}
+
+	return withVolume(ctx, c.runtime, c.Name(), func(ctrID string) error {
+		rc, _, err := c.runtime.CopyFromContainer(ctx, ctrID, helperMountPath)
+		if err != nil {
+			return errors.Wrapf(err, "reading cache volume %s", style.Symbol(c.Name()))
+		}
+		defer rc.Close()
+		_, err = io.Copy(w, rc)
+		return err
+	})
+}
+
+// Import replaces the contents of the volume with those of a tar written by Export. File ownership is kept, so
+// the lifecycle can update the cache.
+func (c *VolumeCache) Import(ctx context.Context, r io.Reader) error {
+	if err := c.Clear(ctx); err != nil {
+		return err
+	}
+	if err := c.Create(ctx); err != nil {
+		return err
+	}
+
+	return withVolume(ctx, c.runtime, c.Name(), func(ctrID string) error {
+		if err := c.runtime.CopyToContainer(ctx, ctrID, "/", r, types.CopyToContainerOptions{}); err != nil {
+			return errors.Wrapf(err, "writing cache volume %s", style.Symbol(c.Name()))
+		}
+		return nil
+	})
+}
+
 func (c *VolumeCache) Clear(ctx context.Context) error {
-	err := c.docker.VolumeRemove(ctx, c.Name(), true)
+	err := c.runtime.VolumeRemove(ctx, c.Name(), true)
 	if err != nil && !client.IsErrNotFound(err) {
 		return err
 	}
 	return nil
 }
+
+func (c *VolumeCache) Type() Type {
+	return Volume
+}
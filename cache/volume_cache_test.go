@@ -1,7 +1,10 @@
 package cache_test
 
 import (
+	"archive/tar"
+	"bytes"
 	"context"
+	"io/ioutil"
 	"math/rand"
 	"strings"
 	"testing"
@@ -16,6 +19,8 @@ import (
 	"github.com/sclevine/spec/report"
 
 	"github.com/buildpack/pack/cache"
+	"github.com/buildpack/pack/internal/archive"
+	"github.com/buildpack/pack/internal/resource"
 	h "github.com/buildpack/pack/testhelpers"
 )
 
@@ -92,6 +97,17 @@ func testCache(t *testing.T, when spec.G, it spec.S) {
 			}
 		})
 
+		it("supplies different volumes for named caches", func() {
+			ref, err := name.ParseReference("my/repo", name.WeakValidation)
+			h.AssertNil(t, err)
+			subject := cache.NewNamedVolumeCache("my-cache", "some-suffix", dockerClient)
+			notExpected := cache.NewVolumeCache(ref, "some-suffix", dockerClient)
+			if subject.Name() == notExpected.Name() {
+				t.Fatalf("Named caches should not share volumes with image caches")
+			}
+			h.AssertEq(t, subject.Name(), cache.NewNamedVolumeCache("my-cache", "some-suffix", dockerClient).Name())
+		})
+
 		it("resolves implied registry", func() {
 			ref, err := name.ParseReference("index.docker.io/my/repo", name.WeakValidation)
 			h.AssertNil(t, err)
@@ -105,6 +121,112 @@ func testCache(t *testing.T, when spec.G, it spec.S) {
 		})
 	})
 
+	when("#Create", func() {
+		var (
+			dockerClient *client.Client
+			subject      *cache.VolumeCache
+			ref          name.Reference
+		)
+
+		it.Before(func() {
+			var err error
+			dockerClient, err = client.NewClientWithOpts(client.FromEnv, client.WithVersion("1.38"))
+			h.AssertNil(t, err)
+
+			ref, err = name.ParseReference(h.RandString(10), name.WeakValidation)
+			h.AssertNil(t, err)
+			subject = cache.NewVolumeCache(ref, "some-suffix", dockerClient)
+		})
+
+		it.After(func() {
+			h.AssertNil(t, subject.Clear(context.TODO()))
+		})
+
+		it("creates the volume labelled with its image", func() {
+			h.AssertNil(t, subject.Create(context.TODO()))
+
+			vol, err := dockerClient.VolumeInspect(context.TODO(), subject.Name())
+			h.AssertNil(t, err)
+			h.AssertEq(t, vol.Labels[cache.ImageLabel], ref.Name())
+			h.AssertEq(t, resource.OwnerOf(vol.Labels).Purpose, resource.PurposeCache)
+		})
+
+		it("leaves an existing volume alone", func() {
+			_, err := dockerClient.VolumeCreate(context.TODO(), volume.VolumeCreateBody{Name: subject.Name()})
+			h.AssertNil(t, err)
+
+			h.AssertNil(t, subject.Create(context.TODO()))
+
+			vol, err := dockerClient.VolumeInspect(context.TODO(), subject.Name())
+			h.AssertNil(t, err)
+			h.AssertEq(t, vol.Labels[cache.ImageLabel], "")
+		})
+	})
+
+	when("#Export and #Import", func() {
+		var (
+			dockerClient *client.Client
+			subject      *cache.VolumeCache
+		)
+
+		it.Before(func() {
+			var err error
+			dockerClient, err = client.NewClientWithOpts(client.FromEnv, client.WithVersion("1.38"))
+			h.AssertNil(t, err)
+
+			ref, err := name.ParseReference(h.RandString(10), name.WeakValidation)
+			h.AssertNil(t, err)
+			subject = cache.NewVolumeCache(ref, "some-suffix", dockerClient)
+		})
+
+		it.After(func() {
+			h.AssertNil(t, subject.Clear(context.TODO()))
+		})
+
+		it("round trips the volume contents", func() {
+			tarReader, err := archive.CreateSingleFileTarReader("cache/some-layer/some-file", "some-contents")
+			h.AssertNil(t, err)
+			h.AssertNil(t, subject.Import(context.TODO(), tarReader))
+
+			var buf bytes.Buffer
+			h.AssertNil(t, subject.Export(context.TODO(), &buf))
+
+			tr := tar.NewReader(&buf)
+			for {
+				header, err := tr.Next()
+				h.AssertNil(t, err)
+				if header.Name == "cache/some-layer/some-file" {
+					contents, err := ioutil.ReadAll(tr)
+					h.AssertNil(t, err)
+					h.AssertEq(t, string(contents), "some-contents")
+					break
+				}
+			}
+		})
+
+		it("is empty until something is imported", func() {
+			empty, err := subject.Empty(context.TODO())
+			h.AssertNil(t, err)
+			h.AssertEq(t, empty, true)
+
+			h.AssertNil(t, subject.Create(context.TODO()))
+			empty, err = subject.Empty(context.TODO())
+			h.AssertNil(t, err)
+			h.AssertEq(t, empty, true)
+
+			tarReader, err := archive.CreateSingleFileTarReader("cache/some-file", "some-contents")
+			h.AssertNil(t, err)
+			h.AssertNil(t, subject.Import(context.TODO(), tarReader))
+			empty, err = subject.Empty(context.TODO())
+			h.AssertNil(t, err)
+			h.AssertEq(t, empty, false)
+		})
+
+		it("fails to export a missing volume", func() {
+			h.AssertError(t, subject.Export(context.TODO(), ioutil.Discard), "does not exist")
+		})
+	})
+
 	when("#Clear", func() {
 		var (
 			volumeName   string
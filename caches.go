@@ -0,0 +1,395 @@
+package pack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/pkg/errors"
+
+	"github.com/buildpack/pack/cache"
+	"github.com/buildpack/pack/internal/archive"
+	"github.com/buildpack/pack/internal/lockfile"
+	"github.com/buildpack/pack/style"
+)
+
+// CacheInfo describes a cache volume or image pack created to build an app image
+type CacheInfo struct {
+	Name      string
+	Type      cache.Type
+	Image     string    // app image the cache is for, empty when it is named or was created before pack recorded it
+	CacheName string    // name the cache was given to share it between images, empty when it is for Image
+	Size      int64     // in bytes, -1 when unknown
+	LastUsed  time.Time // when a build of Image last started, or when the cache was created if that is unknown
+}
+
+// ListCaches returns all cache volumes and images in the daemon, ordered by cache name, image and name
+func (c *Client) ListCaches(ctx context.Context) ([]CacheInfo, error) {
+	usage, err := c.docker.DiskUsage(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting docker disk usage")
+	}
+	lastUsed, err := c.cacheUsage.read()
+	if err != nil {
+		c.logger.Debugf("Unable to read cache usage: %s", err)
+	}
+
+	var caches []CacheInfo
+	for _, v := range usage.Volumes {
+		if !strings.HasPrefix(v.Name, cache.NamePrefix) {
+			continue
+		}
+		info := CacheInfo{
+			Name:      v.Name,
+			Type:      cache.Volume,
+			Image:     v.Labels[cache.ImageLabel],
+			CacheName: v.Labels[cache.NameLabel],
+			Size:      -1,
+		}
+		if v.UsageData != nil {
+			info.Size = v.UsageData.Size
+		}
+		info.LastUsed, _ = time.Parse(time.RFC3339, v.CreatedAt)
+		caches = append(caches, info)
+	}
+	for _, img := range usage.Images {
+		for _, tag := range img.RepoTags {
+			if !strings.HasPrefix(tag, cache.NamePrefix) {
+				continue
+			}
+			caches = append(caches, CacheInfo{
+				Name:      strings.TrimSuffix(tag, ":latest"),
+				Type:      cache.Image,
+				Image:     img.Labels[cache.ImageLabel],
+				CacheName: img.Labels[cache.NameLabel],
+				Size:      img.Size,
+				LastUsed:  time.Unix(img.Created, 0),
+			})
+		}
+	}
+
+	for i := range caches {
+		if t, ok := lastUsed[caches[i].usageKey()]; ok && caches[i].usageKey() != "" {
+			caches[i].LastUsed = t
+		}
+	}
+	sort.Slice(caches, func(i, j int) bool {
+		if caches[i].CacheName != caches[j].CacheName {
+			return caches[i].CacheName < caches[j].CacheName
+		}
+		if caches[i].Image != caches[j].Image {
+			return caches[i].Image < caches[j].Image
+		}
+		return caches[i].Name < caches[j].Name
+	})
+	return caches, nil
+}
+
+// InspectCache returns the caches used to build imageName, or the caches called cacheName when imageName is given
+// as it is listed, name:<cacheName>
+func (c *Client) InspectCache(ctx context.Context, imageName string) ([]CacheInfo, error) {
+	target, err := c.resolveCaches(imageName)
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[string]bool{
+		target.buildVolume.Name(): true,
+		target.buildImage.Name():  true,
+	}
+	if target.launchVolume != nil {
+		names[target.launchVolume.Name()] = true
+	}
+
+	all, err := c.ListCaches(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lastUsed, _ := c.cacheUsage.read()
+
+	var caches []CacheInfo
+	for _, info := range all {
+		if !names[info.Name] {
+			continue
+		}
+		if info.Image == "" && info.CacheName == "" {
+			info.Image, info.CacheName = target.image, target.cacheName
+			if t, ok := lastUsed[info.usageKey()]; ok {
+				info.LastUsed = t
+			}
+		}
+		caches = append(caches, info)
+	}
+	if len(caches) == 0 {
+		return nil, fmt.Errorf("no caches found for %s", target)
+	}
+	return caches, nil
+}
+
+// RemoveCache removes the caches used to build imageName and returns them
+func (c *Client) RemoveCache(ctx context.Context, imageName string) ([]CacheInfo, error) {
+	caches, err := c.InspectCache(ctx, imageName)
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range caches {
+		if err := c.removeCache(ctx, info); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.cacheUsage.forget(caches[0].usageKey()); err != nil {
+		c.logger.Debugf("Unable to update cache usage: %s", err)
+	}
+	return caches, nil
+}
+
+// PruneCaches removes the caches not used in the last olderThan and returns them. Caches that cannot be removed,
+// such as those in use by a running build, are skipped with a warning.
+func (c *Client) PruneCaches(ctx context.Context, olderThan time.Duration) ([]CacheInfo, error) {
+	all, err := c.ListCaches(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	var pruned []CacheInfo
+	for _, info := range all {
+		if !info.LastUsed.Before(cutoff) {
+			continue
+		}
+		if err := c.removeCache(ctx, info); err != nil {
+			c.logger.Warnf("Skipping cache %s: %s", style.Symbol(info.Name), err)
+			continue
+		}
+		pruned = append(pruned, info)
+	}
+	return pruned, nil
+}
+
+// ExportCache writes the build cache for imageName, or the one called cacheName when imageName is given as
+// name:<cacheName>, to w as a tar, so it can be kept outside the daemon and imported elsewhere
+func (c *Client) ExportCache(ctx context.Context, imageName string, w io.Writer) error {
+	target, err := c.resolveCaches(imageName)
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.docker.VolumeInspect(ctx, target.buildVolume.Name()); err == nil {
+		c.logger.Debugf("Exporting build cache volume %s", style.Symbol(target.buildVolume.Name()))
+		return target.buildVolume.Export(ctx, w)
+	}
+
+	if _, _, err := c.docker.ImageInspectWithRaw(ctx, target.buildImage.Name()); err == nil {
+		c.logger.Debugf("Exporting build cache image %s", style.Symbol(target.buildImage.Name()))
+		return target.buildImage.Export(ctx, w)
+	}
+
+	return fmt.Errorf("no build cache found for %s", target)
+}
+
+// ImportCache replaces the build cache for imageName, or the one called cacheName when imageName is given as
+// name:<cacheName>, with the contents of a tar written by ExportCache, possibly for another image or name
+func (c *Client) ImportCache(ctx context.Context, imageName, path string) error {
+	target, err := c.resolveCaches(imageName)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap(err, "opening cache tar")
+	}
+	defer f.Close()
+
+	if err := c.cacheUsage.touch(target.usageKey()); err != nil {
+		c.logger.Debugf("Unable to record use of caches for %s: %s", target, err)
+	}
+
+	// exported images have a docker save manifest, exported volumes only the cache directory
+	_, contents, err := archive.ReadTarEntry(path, "manifest.json")
+	if archive.IsEntryNotExist(err) {
+		c.logger.Debugf("Importing build cache volume %s", style.Symbol(target.buildVolume.Name()))
+		return target.buildVolume.Import(ctx, f)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "reading %s", style.Symbol(path))
+	}
+
+	var manifest []struct {
+		RepoTags []string
+	}
+	if err := json.Unmarshal(contents, &manifest); err != nil {
+		return errors.Wrapf(err, "parsing manifest of %s", style.Symbol(path))
+	}
+	if len(manifest) != 1 || len(manifest[0].RepoTags) != 1 {
+		return fmt.Errorf("%s does not contain an exported build cache", style.Symbol(path))
+	}
+
+	c.logger.Debugf("Importing build cache image %s", style.Symbol(target.buildImage.Name()))
+	return target.buildImage.Import(ctx, f)
+}
+
+// cacheTarget is the caches a cache command is given: those of an app image, or those called cacheName, which are
+// shared between images
+type cacheTarget struct {
+	image        string
+	cacheName    string
+	buildVolume  *cache.VolumeCache
+	buildImage   *cache.ImageCache
+	launchVolume *cache.VolumeCache // nil for a named cache, launch caches are always keyed on the image
+}
+
+// resolveCaches returns the caches for imageName, or for the cache name in it when it is a named cache key as
+// build records its use under, so the caches are the same build uses
+func (c *Client) resolveCaches(imageName string) (cacheTarget, error) {
+	if cacheName, ok := cache.ParseNamedCacheKey(imageName); ok {
+		if cacheName == "" {
+			return cacheTarget{}, fmt.Errorf("invalid cache name '%s'", imageName)
+		}
+		return cacheTarget{
+			cacheName:   cacheName,
+			buildVolume: cache.NewNamedVolumeCache(cacheName, "build", c.docker),
+			buildImage:  cache.NewNamedImageCache(cacheName, c.docker),
+		}, nil
+	}
+
+	imageRef, err := c.parseTagReference(imageName)
+	if err != nil {
+		return cacheTarget{}, errors.Wrapf(err, "invalid image name '%s'", imageName)
+	}
+	return cacheTarget{
+		image:        imageRef.Name(),
+		buildVolume:  cache.NewVolumeCache(imageRef, "build", c.docker),
+		buildImage:   cache.NewImageCache(imageRef, c.docker),
+		launchVolume: cache.NewVolumeCache(imageRef, "launch", c.docker),
+	}, nil
+}
+
+// usageKey is what the last use of the caches is recorded under
+func (t cacheTarget) usageKey() string {
+	return CacheInfo{Image: t.image, CacheName: t.cacheName}.usageKey()
+}
+
+func (t cacheTarget) String() string {
+	if t.cacheName != "" {
+		return "cache " + style.Symbol(t.cacheName)
+	}
+	return "image " + style.Symbol(t.image)
+}
+
+func (c *Client) removeCache(ctx context.Context, info CacheInfo) error {
+	var err error
+	switch info.Type {
+	case cache.Volume:
+		err = c.docker.VolumeRemove(ctx, info.Name, false)
+	case cache.Image:
+		_, err = c.docker.ImageRemove(ctx, info.Name, types.ImageRemoveOptions{})
+	}
+	if err != nil && !client.IsErrNotFound(err) {
+		return errors.Wrapf(err, "removing cache %s %s", info.Type, style.Symbol(info.Name))
+	}
+	return nil
+}
+
+// usageKey is what the last use of the cache is recorded under
+func (i CacheInfo) usageKey() string {
+	if i.CacheName != "" {
+		return cache.NamedCacheKey(i.CacheName)
+	}
+	return i.Image
+}
+
+// cacheUsage records when builds of each app image, or using each named cache, last started, in a file so it is
+// shared between invocations of pack. The daemon does not track when volumes and images are used.
+type cacheUsage struct {
+	path string
+	mu   sync.Mutex
+}
+
+func newCacheUsage(path string) *cacheUsage {
+	return &cacheUsage{path: path}
+}
+
+// touch records that a build of image started now. A nil cacheUsage records nothing.
+func (u *cacheUsage) touch(image string) error {
+	return u.update(func(lastUsed map[string]time.Time) {
+		lastUsed[image] = time.Now()
+	})
+}
+
+// forget removes the record of image being built
+func (u *cacheUsage) forget(image string) error {
+	return u.update(func(lastUsed map[string]time.Time) {
+		delete(lastUsed, image)
+	})
+}
+
+func (u *cacheUsage) read() (map[string]time.Time, error) {
+	if u == nil {
+		return nil, nil
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	unlock, err := lockfile.Lock(u.path)
+	if os.IsNotExist(err) {
+		// the directory of the file is only created once a use is recorded
+		return map[string]time.Time{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return u.readFile()
+}
+
+func (u *cacheUsage) update(f func(lastUsed map[string]time.Time)) error {
+	if u == nil {
+		return nil
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(u.path), 0755); err != nil {
+		return err
+	}
+	unlock, err := lockfile.Lock(u.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	lastUsed, err := u.readFile()
+	if err != nil {
+		return err
+	}
+	f(lastUsed)
+
+	contents, err := json.Marshal(lastUsed)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(u.path, contents, 0644)
+}
+
+func (u *cacheUsage) readFile() (map[string]time.Time, error) {
+	lastUsed := map[string]time.Time{}
+	contents, err := ioutil.ReadFile(u.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(contents) > 0 {
+		if err := json.Unmarshal(contents, &lastUsed); err != nil {
+			return nil, errors.Wrapf(err, "parsing %s", style.Symbol(u.path))
+		}
+	}
+	return lastUsed, nil
+}
@@ -0,0 +1,191 @@
+package pack
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpack/pack/cache"
+	"github.com/buildpack/pack/internal/lockfile"
+	"github.com/buildpack/pack/internal/mocks"
+	h "github.com/buildpack/pack/testhelpers"
+)
+
+func TestCacheUsage(t *testing.T) {
+	color.NoColor = true
+	spec.Run(t, "CacheUsage", testCacheUsage, spec.Report(report.Terminal{}))
+}
+
+func testCacheUsage(t *testing.T, when spec.G, it spec.S) {
+	var (
+		subject *cacheUsage
+		tmpDir  string
+	)
+
+	it.Before(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "cache-usage-test")
+		h.AssertNil(t, err)
+		subject = newCacheUsage(filepath.Join(tmpDir, "some-dir", "cache-usage.json"))
+	})
+
+	it.After(func() {
+		h.AssertNil(t, os.RemoveAll(tmpDir))
+	})
+
+	when("#touch", func() {
+		it("records when the image was used", func() {
+			before := time.Now()
+			h.AssertNil(t, subject.touch("some/app"))
+
+			lastUsed, err := subject.read()
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(lastUsed), 1)
+			if lastUsed["some/app"].Before(before) {
+				t.Fatalf("expected last use after %s, got %s", before, lastUsed["some/app"])
+			}
+		})
+
+		it("keeps other images", func() {
+			h.AssertNil(t, subject.touch("some/app"))
+			h.AssertNil(t, subject.touch("other/app"))
+
+			lastUsed, err := subject.read()
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(lastUsed), 2)
+		})
+
+		it("waits for another pack recording a use", func() {
+			h.AssertNil(t, os.MkdirAll(filepath.Dir(subject.path), 0755))
+			unlock, err := lockfile.Lock(subject.path)
+			h.AssertNil(t, err)
+
+			done := make(chan error)
+			go func() { done <- subject.touch("some/app") }()
+
+			select {
+			case <-done:
+				t.Fatal("expected touch to wait for the lock")
+			case <-time.After(100 * time.Millisecond):
+			}
+			unlock()
+			h.AssertNil(t, <-done)
+
+			lastUsed, err := subject.read()
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(lastUsed), 1)
+		})
+	})
+
+	when("#forget", func() {
+		it("removes the image", func() {
+			h.AssertNil(t, subject.touch("some/app"))
+			h.AssertNil(t, subject.touch("other/app"))
+			h.AssertNil(t, subject.forget("some/app"))
+
+			lastUsed, err := subject.read()
+			h.AssertNil(t, err)
+			_, ok := lastUsed["some/app"]
+			h.AssertEq(t, ok, false)
+			_, ok = lastUsed["other/app"]
+			h.AssertEq(t, ok, true)
+		})
+	})
+
+	when("the cacheUsage is nil", func() {
+		it("records nothing", func() {
+			var nilUsage *cacheUsage
+			h.AssertNil(t, nilUsage.touch("some/app"))
+			lastUsed, err := nilUsage.read()
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(lastUsed), 0)
+		})
+	})
+}
+
+func TestImportCache(t *testing.T) {
+	color.NoColor = true
+	spec.Run(t, "ImportCache", testImportCache, spec.Report(report.Terminal{}))
+}
+
+func testImportCache(t *testing.T, when spec.G, it spec.S) {
+	var (
+		subject *Client
+		tmpDir  string
+		outBuf  bytes.Buffer
+	)
+
+	it.Before(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "import-cache-test")
+		h.AssertNil(t, err)
+		subject = &Client{logger: mocks.NewMockLogger(&outBuf)}
+	})
+
+	it.After(func() {
+		h.AssertNil(t, os.RemoveAll(tmpDir))
+	})
+
+	when("the tar cannot be read", func() {
+		it("fails rather than importing it as a volume", func() {
+			path := filepath.Join(tmpDir, "cache.tar")
+			h.AssertNil(t, ioutil.WriteFile(path, bytes.Repeat([]byte("not a tar"), 100), 0644))
+
+			err := subject.ImportCache(context.TODO(), "some/app", path)
+			h.AssertError(t, err, "invalid tar header")
+			h.AssertNotContains(t, outBuf.String(), "Importing build cache volume")
+		})
+	})
+}
+
+func TestResolveCaches(t *testing.T) {
+	spec.Run(t, "resolveCaches", testResolveCaches, spec.Report(report.Terminal{}))
+}
+
+func testResolveCaches(t *testing.T, when spec.G, it spec.S) {
+	var subject *Client
+
+	it.Before(func() {
+		subject = &Client{logger: mocks.NewMockLogger(&bytes.Buffer{})}
+	})
+
+	when("given an image", func() {
+		it("returns the caches keyed on the image", func() {
+			target, err := subject.resolveCaches("some/app")
+			h.AssertNil(t, err)
+
+			imageRef, err := name.ParseReference("some/app", name.WeakValidation)
+			h.AssertNil(t, err)
+			h.AssertEq(t, target.buildVolume.Name(), cache.NewVolumeCache(imageRef, "build", nil).Name())
+			h.AssertEq(t, target.buildImage.Name(), cache.NewImageCache(imageRef, nil).Name())
+			h.AssertEq(t, target.launchVolume.Name(), cache.NewVolumeCache(imageRef, "launch", nil).Name())
+			h.AssertEq(t, target.usageKey(), "index.docker.io/some/app:latest")
+		})
+	})
+
+	when("given a named cache key", func() {
+		it("returns the caches build uses for the name", func() {
+			target, err := subject.resolveCaches(cache.NamedCacheKey("shared"))
+			h.AssertNil(t, err)
+
+			h.AssertEq(t, target.buildVolume.Name(), cache.NewNamedVolumeCache("shared", "build", nil).Name())
+			h.AssertEq(t, target.buildImage.Name(), cache.NewNamedImageCache("shared", nil).Name())
+			h.AssertEq(t, target.launchVolume == nil, true)
+			h.AssertEq(t, target.usageKey(), cache.NamedCacheKey("shared"))
+		})
+
+		it("fails without a name", func() {
+			_, err := subject.resolveCaches(cache.NamedCacheKey(""))
+			h.AssertError(t, err, "invalid cache name 'name:'")
+		})
+	})
+}
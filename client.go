@@ -16,12 +16,15 @@ import (
 )
 
 type Client struct {
-	logger           logging.Logger
-	imageFetcher     ImageFetcher
-	buildpackFetcher BuildpackFetcher
-	lifecycleFetcher LifecycleFetcher
-	lifecycle        Lifecycle
-	docker           *dockerClient.Client
+	logger            logging.Logger
+	imageFetcher      ImageFetcher
+	buildpackFetcher  BuildpackFetcher
+	lifecycleFetcher  LifecycleFetcher
+	lifecycle         Lifecycle
+	newLifecycle      func(logger logging.Logger) Lifecycle // creates a lifecycle for each concurrent build
+	ephemeralBuilders *builderLRU                           // ephemeral builders kept for reuse, may be nil
+	cacheUsage        *cacheUsage                           // when caches were last used, may be nil
+	docker            *dockerClient.Client
 }
 
 type ClientOption func(c *Client)
@@ -67,7 +70,12 @@ func NewClient(opts ...ClientOption) (*Client, error) {
 	client.imageFetcher = image.NewFetcher(client.logger, client.docker)
 	client.buildpackFetcher = buildpack.NewFetcher(downloader)
 	client.lifecycleFetcher = lifecycle.NewFetcher(downloader)
+	client.ephemeralBuilders = newBuilderLRU(filepath.Join(packHome, "ephemeral-builders.json"), ephemeralBuilderCacheSize)
+	client.cacheUsage = newCacheUsage(filepath.Join(packHome, "cache-usage.json"))
 	client.lifecycle = build.NewLifecycle(client.docker, client.logger)
+	client.newLifecycle = func(logger logging.Logger) Lifecycle {
+		return build.NewLifecycle(client.docker, logger)
+	}
 
 	return &client, nil
 }
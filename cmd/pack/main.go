@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"os"
 
 	"github.com/fatih/color"
@@ -12,6 +13,7 @@ import (
 	"github.com/buildpack/pack/config"
 	clilogger "github.com/buildpack/pack/internal/logging"
 	"github.com/buildpack/pack/logging"
+	"github.com/buildpack/pack/style"
 )
 
 var (
@@ -46,6 +48,9 @@ func main() {
 			}
 
 			packClient = initClient(logger)
+			if cfg.GCOnStartup && commands.CreatesResources(cmd) {
+				collectGarbage(logger, &packClient)
+			}
 		},
 	}
 	rootCmd.PersistentFlags().Bool("no-color", false, "Disable color output")
@@ -54,8 +59,14 @@ func main() {
 	commands.AddHelpFlag(rootCmd, "pack")
 
 	rootCmd.AddCommand(commands.Build(logger, cfg, &packClient))
+	rootCmd.AddCommand(commands.BuildBatch(logger, cfg, &packClient))
+	rootCmd.AddCommand(commands.Detect(logger, cfg, &packClient))
 	rootCmd.AddCommand(commands.Run(logger, cfg, &packClient))
+	rootCmd.AddCommand(commands.Shell(logger, cfg, &packClient))
 	rootCmd.AddCommand(commands.Rebase(logger, cfg, &packClient))
+	rootCmd.AddCommand(commands.InspectImage(logger, cfg, &packClient))
+	rootCmd.AddCommand(commands.Cache(logger, &packClient))
+	rootCmd.AddCommand(commands.GC(logger, &packClient))
 
 	rootCmd.AddCommand(commands.CreateBuilder(logger, &packClient))
 	rootCmd.AddCommand(commands.SetRunImagesMirrors(logger, cfg))
@@ -97,6 +108,18 @@ func initClient(logger logging.Logger) pack.Client {
 	return *client
 }
 
+// collectGarbage removes resources left behind by killed pack processes before running a command, without failing it
+func collectGarbage(logger logging.Logger, client *pack.Client) {
+	removed, err := client.CollectGarbage(context.Background(), pack.GCOptions{OlderThan: pack.DefaultGCAge})
+	if err != nil {
+		logger.Debugf("Unable to remove resources left behind by pack: %s", err)
+		return
+	}
+	for _, r := range removed {
+		logger.Debugf("Removed %s %s left behind by pack", r.Kind, style.Symbol(r.Name))
+	}
+}
+
 func exitError(logger logging.Logger, err error) {
 	logger.Error(err.Error())
 	os.Exit(1)
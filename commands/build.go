@@ -1,29 +1,48 @@
 package commands
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 
+	"github.com/BurntSushi/toml"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	"github.com/buildpack/pack"
+	"github.com/buildpack/pack/builder"
 	"github.com/buildpack/pack/config"
 	"github.com/buildpack/pack/logging"
+	"github.com/buildpack/pack/project"
 	"github.com/buildpack/pack/style"
 )
 
 type BuildFlags struct {
-	AppPath    string
-	Builder    string
-	RunImage   string
-	Env        []string
-	EnvFile    string
-	Publish    bool
-	NoPull     bool
-	ClearCache bool
-	Buildpacks []string
+	AppPath        string
+	Tags           []string
+	Builder        string
+	RunImage       string
+	Env            []string
+	EnvFile        string
+	Secrets        []string
+	Bindings       []string
+	CACerts        []string
+	Publish        bool
+	NoPull         bool
+	ClearCache     bool
+	Buildpacks     []string
+	Exclude        []string
+	Include        []string
+	Report         string
+	DetectOnly     bool
+	CacheName      string
+	CacheFallbacks []string
+	KeepOnFailure  bool
+	ResumeFrom     string
 }
 
 func Build(logger logging.Logger, cfg config.Config, packClient *pack.Client) *cobra.Command {
@@ -36,6 +55,14 @@ func Build(logger logging.Logger, cfg config.Config, packClient *pack.Client) *c
 		Short: "Generate app image from source code",
 		RunE: logError(logger, func(cmd *cobra.Command, args []string) error {
 			imageName := args[0]
+			if flags.DetectOnly {
+				if err := checkDetectOnlyFlags(cmd); err != nil {
+					return err
+				}
+			}
+			if err := applyProjectBuilder(cmd, &flags); err != nil {
+				return err
+			}
 			if flags.Builder == "" {
 				suggestSettingBuilder(logger, packClient)
 				return MakeSoftError()
@@ -44,19 +71,55 @@ func Build(logger logging.Logger, cfg config.Config, packClient *pack.Client) *c
 			if err != nil {
 				return err
 			}
-			if err := packClient.Build(ctx, pack.BuildOptions{
+			secrets, bindings, err := parseBindings(flags.Secrets, flags.Bindings)
+			if err != nil {
+				return err
+			}
+			flags.CACerts = caCertFiles(cfg, flags.CACerts)
+			var appReader io.Reader
+			if flags.AppPath == "-" {
+				appReader = os.Stdin
+			}
+			if flags.DetectOnly {
+				return detect(ctx, logger, packClient, flags, env, secrets, bindings, appReader)
+			}
+			var report *pack.BuildReport
+			if flags.Report != "" {
+				report = &pack.BuildReport{}
+			}
+			buildErr := packClient.Build(ctx, pack.BuildOptions{
 				AppPath:           flags.AppPath,
+				AppReader:         appReader,
 				Builder:           flags.Builder,
 				AdditionalMirrors: getMirrors(cfg),
 				RunImage:          flags.RunImage,
 				Env:               env,
+				Secrets:           secrets,
+				Bindings:          bindings,
+				CACerts:           flags.CACerts,
 				Image:             imageName,
+				AdditionalTags:    flags.Tags,
 				Publish:           flags.Publish,
 				NoPull:            flags.NoPull,
 				ClearCache:        flags.ClearCache,
 				Buildpacks:        flags.Buildpacks,
-			}); err != nil {
-				return err
+				Exclude:           flags.Exclude,
+				Include:           flags.Include,
+				Report:            report,
+				CacheName:         flags.CacheName,
+				CacheFallbacks:    flags.CacheFallbacks,
+				KeepOnFailure:     flags.KeepOnFailure,
+				ResumeFrom:        flags.ResumeFrom,
+			})
+			if report != nil {
+				if err := writeReport(flags.Report, report); err != nil {
+					logger.Warnf("failed to write build report: %s", err)
+				} else {
+					logger.Debugf("Build report written to %s", style.Symbol(flags.Report))
+				}
+			}
+			if buildErr != nil {
+				return buildErr
 			}
 			logger.Infof("Successfully built image %s", style.Symbol(imageName))
 			return nil
@@ -64,19 +127,91 @@ func Build(logger logging.Logger, cfg config.Config, packClient *pack.Client) *c
 	}
 	buildCommandFlags(cmd, &flags, cfg)
 	cmd.Flags().BoolVar(&flags.Publish, "publish", false, "Publish to registry")
+	cmd.Flags().StringSliceVarP(&flags.Tags, "tag", "t", nil, "Additional tag to export the image with, on the same registry as the image when publishing"+multiValueHelp("tag"))
+	cmd.Flags().BoolVar(&flags.DetectOnly, "detect-only", false, "Only run detection and print the selected buildpacks and build plan, no image is built")
+	cmd.Flags().StringVar(&flags.CacheName, "cache-name", "", "Name of the build cache to use instead of one for the image, so builds of different images can share it")
+	cmd.Flags().StringSliceVar(&flags.CacheFallbacks, "cache-fallback", nil, "Name of a build cache to restore from when the build cache is empty, e.g. that of the main branch"+multiValueHelp("cache name"))
+	cmd.Flags().BoolVar(&flags.KeepOnFailure, "keep-on-failure", false, "Keep the volumes and failed container of a failed build for inspection\nA failed detector or builder is not kept when there is build-time env, as it may hold secrets")
+	cmd.Flags().StringVar(&flags.ResumeFrom, "resume-from", "", "Resume a build kept with --keep-on-failure from this phase, reusing its volumes\nOne of detect, restore, analyze, build, export or cache")
+	cmd.Flags().StringVar(&flags.Report, "report", "", "Write a build report to this file\nFormat is TOML if the file has a .toml extension, otherwise JSON")
 	AddHelpFlag(cmd, "build")
+	markCreatesResources(cmd)
 	return cmd
 }
 
+// buildOnlyFlags are the flags of build that detection has no use for
+var buildOnlyFlags = []string{"run-image", "clear-cache", "publish", "tag", "cache-name", "cache-fallback", "keep-on-failure", "resume-from", "report"}
+
+// checkDetectOnlyFlags rejects build-only flags given with --detect-only, rather than ignoring them
+func checkDetectOnlyFlags(cmd *cobra.Command) error {
+	for _, name := range buildOnlyFlags {
+		if cmd.Flags().Changed(name) {
+			return fmt.Errorf("%s cannot be used with %s, no image is built", style.Symbol("--"+name), style.Symbol("--detect-only"))
+		}
+	}
+	return nil
+}
+
 func buildCommandFlags(cmd *cobra.Command, buildFlags *BuildFlags, cfg config.Config) {
-	cmd.Flags().StringVarP(&buildFlags.AppPath, "path", "p", "", "Path to app dir or zip-formatted file (defaults to current working directory)")
-	cmd.Flags().StringVar(&buildFlags.Builder, "builder", cfg.DefaultBuilder, "Builder image")
+	appCommandFlags(cmd, buildFlags, cfg)
 	cmd.Flags().StringVar(&buildFlags.RunImage, "run-image", "", "Run image (defaults to default stack's run image)")
-	cmd.Flags().StringArrayVarP(&buildFlags.Env, "env", "e", []string{}, "Build-time environment variable, in the form 'VAR=VALUE' or 'VAR'.\nWhen using latter value-less form, value will be taken from current\n  environment at the time this command is executed.\nThis flag may be specified multiple times and will override\n  individual values defined by --env-file.")
-	cmd.Flags().StringVar(&buildFlags.EnvFile, "env-file", "", "Build-time environment variables file\nOne variable per line, of the form 'VAR=VALUE' or 'VAR'\nWhen using latter value-less form, value will be taken from current\n  environment at the time this command is executed")
-	cmd.Flags().BoolVar(&buildFlags.NoPull, "no-pull", false, "Skip pulling builder and run images before use")
 	cmd.Flags().BoolVar(&buildFlags.ClearCache, "clear-cache", false, "Clear image's associated cache before building")
+}
+
+// appCommandFlags adds the flags shared by every command that runs buildpacks against an app
+func appCommandFlags(cmd *cobra.Command, buildFlags *BuildFlags, cfg config.Config) {
+	cmd.Flags().StringVarP(&buildFlags.AppPath, "path", "p", "", "Path to app dir, zip or tar file, or git repository URL (defaults to current working directory)\nGit URLs may end with #<ref>[:<subdir>]\nUse '-' to read a tar from stdin")
+	cmd.Flags().StringVar(&buildFlags.Builder, "builder", cfg.DefaultBuilder, "Builder image (defaults to the builder in project.toml, then the default builder)")
+	cmd.Flags().StringArrayVarP(&buildFlags.Env, "env", "e", []string{}, "Build-time environment variable, in the form 'VAR=VALUE' or 'VAR'.\nWhen using latter value-less form, value will be taken from current\n  environment at the time this command is executed.\nUse 'VAR+=VALUE' to append, 'VAR^=VALUE' to prepend, or 'VAR?=VALUE'\n  to set a default, separated by ':' unless 'VAR.delim' is set.\nThis flag may be specified multiple times and will override\n  individual values defined by --env-file.")
+	cmd.Flags().StringVar(&buildFlags.EnvFile, "env-file", "", "Build-time environment variables file\nOne variable per line, of the form 'VAR=VALUE' or 'VAR', or with the\n  operators accepted by --env\nWhen using latter value-less form, value will be taken from current\n  environment at the time this command is executed")
+	cmd.Flags().StringArrayVar(&buildFlags.Secrets, "secret", nil, "Secret file for buildpacks, in the form 'id=NAME,src=FILE'\nMounted read-only at /platform/bindings/NAME/<file name> for detection and build only,\n  it never reaches the image or the cache\nOnly for a local daemon, as it is mounted from this machine\nRepeat for each secret")
+	cmd.Flags().StringArrayVar(&buildFlags.Bindings, "binding", nil, "Service binding for buildpacks, in the form 'NAME=DIR'\nMounted read-only at /platform/bindings/NAME for detection and build only,\n  it never reaches the image or the cache\nOnly for a local daemon, as it is mounted from this machine\nRepeat for each binding")
+	cmd.Flags().StringArrayVar(&buildFlags.CACerts, "ca-cert", nil, "PEM file of CA certs for every build phase to trust, such as that of a TLS-intercepting proxy\nAdded to those in the config file\nRepeat for each file")
+	cmd.Flags().BoolVar(&buildFlags.NoPull, "no-pull", false, "Skip pulling builder and run images before use")
 	cmd.Flags().StringSliceVar(&buildFlags.Buildpacks, "buildpack", nil, "Buildpack ID, path to a Buildpack directory, or path/URL to a Buildpack .tgz file"+multiValueHelp("buildpack"))
+	cmd.Flags().StringSliceVar(&buildFlags.Exclude, "exclude", nil, "Gitignore-style pattern for app files to leave out, in addition to those in .packignore"+multiValueHelp("pattern"))
+	cmd.Flags().StringSliceVar(&buildFlags.Include, "include", nil, "Gitignore-style pattern for app files to use, all other files are left out"+multiValueHelp("pattern"))
+}
+
+// applyProjectBuilder uses the builder named in the app's project descriptor unless one was given with
+// --builder, so that the descriptor takes precedence over the configured default builder
+func applyProjectBuilder(cmd *cobra.Command, flags *BuildFlags) error {
+	if cmd.Flags().Changed("builder") {
+		return nil
+	}
+
+	appDir := flags.AppPath
+	if appDir == "" {
+		appDir = "."
+	}
+	if fi, err := os.Stat(appDir); err != nil || !fi.IsDir() {
+		return nil
+	}
+
+	descriptor, err := project.ReadDescriptor(appDir)
+	if err != nil {
+		return err
+	}
+	if descriptor.Build.Builder != "" {
+		flags.Builder = descriptor.Build.Builder
+	}
+	return nil
+}
+
+func writeReport(path string, report *pack.BuildReport) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if filepath.Ext(path) == ".toml" {
+		return toml.NewEncoder(f).Encode(report)
+	}
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
 }
 
 func parseEnv(envFile string, envVars []string) (map[string]string, error) {
@@ -89,7 +224,9 @@ func parseEnv(envFile string, envVars []string) (map[string]string, error) {
 		}
 	}
 	for _, envVar := range envVars {
-		env = addEnvVar(env, envVar)
+		if err := addEnvVar(env, envVar); err != nil {
+			return nil, err
+		}
 	}
 	return env, nil
 }
@@ -105,17 +242,71 @@ func parseEnvFile(filename string) (map[string]string, error) {
 		if line == "" {
 			continue
 		}
-		out = addEnvVar(out, line)
+		if err := addEnvVar(out, line); err != nil {
+			return nil, err
+		}
 	}
 	return out, nil
 }
 
-func addEnvVar(env map[string]string, item string) map[string]string {
-	arr := strings.SplitN(item, "=", 2)
-	if len(arr) > 1 {
-		env[arr[0]] = arr[1]
-	} else {
-		env[arr[0]] = os.Getenv(arr[0])
+// addEnvVar adds an assignment such as 'PATH^=/opt/bin' to env, keyed by the platform env file it is kept in. A
+// name alone takes its value from the current environment.
+func addEnvVar(env map[string]string, item string) error {
+	file, value, ok, err := builder.ParseEnvAssignment(item)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		value = os.Getenv(item)
 	}
-	return env
+	env[file] = value
+	return nil
+}
+
+// parseBindings parses --secret values of the form 'id=NAME,src=FILE' and --binding values of the form 'NAME=DIR'
+// into the files and dirs to mount, by name
+func parseBindings(secretItems, bindingItems []string) (secrets, bindings map[string]string, err error) {
+	secrets = map[string]string{}
+	for _, item := range secretItems {
+		var id, src string
+		for _, field := range strings.Split(item, ",") {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				return nil, nil, fmt.Errorf("invalid secret %s, must be of the form %s", style.Symbol(item), style.Symbol("id=NAME,src=FILE"))
+			}
+			switch kv[0] {
+			case "id":
+				id = kv[1]
+			case "src", "source":
+				src = kv[1]
+			default:
+				return nil, nil, fmt.Errorf("invalid secret %s, unknown field %s", style.Symbol(item), style.Symbol(kv[0]))
+			}
+		}
+		if id == "" || src == "" {
+			return nil, nil, fmt.Errorf("invalid secret %s, must be of the form %s", style.Symbol(item), style.Symbol("id=NAME,src=FILE"))
+		}
+		if _, ok := secrets[id]; ok {
+			return nil, nil, fmt.Errorf("secret id %s is given more than once", style.Symbol(id))
+		}
+		secrets[id] = src
+	}
+
+	bindings = map[string]string{}
+	for _, item := range bindingItems {
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return nil, nil, fmt.Errorf("invalid binding %s, must be of the form %s", style.Symbol(item), style.Symbol("NAME=DIR"))
+		}
+		if _, ok := bindings[kv[0]]; ok {
+			return nil, nil, fmt.Errorf("binding %s is given more than once", style.Symbol(kv[0]))
+		}
+		bindings[kv[0]] = kv[1]
+	}
+	return secrets, bindings, nil
+}
+
+// caCertFiles returns the CA cert files in the config followed by those given with --ca-cert
+func caCertFiles(cfg config.Config, flagFiles []string) []string {
+	return append(append([]string{}, cfg.CACerts...), flagFiles...)
 }
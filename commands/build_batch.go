@@ -0,0 +1,184 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/buildpack/pack"
+	"github.com/buildpack/pack/config"
+	"github.com/buildpack/pack/internal/git"
+	"github.com/buildpack/pack/logging"
+	"github.com/buildpack/pack/style"
+)
+
+type BuildBatchFlags struct {
+	Concurrency int
+	Publish     bool
+	NoPull      bool
+	ClearCache  bool
+}
+
+type batchManifest struct {
+	Builder  string     `toml:"builder"`
+	RunImage string     `toml:"run-image"`
+	Apps     []batchApp `toml:"apps"`
+}
+
+type batchApp struct {
+	Image      string            `toml:"image"`
+	Path       string            `toml:"path"`
+	Builder    string            `toml:"builder"`
+	RunImage   string            `toml:"run-image"`
+	Buildpacks []string          `toml:"buildpacks"`
+	Env        map[string]string `toml:"env"`
+	Tags       []string          `toml:"tags"`
+}
+
+func BuildBatch(logger logging.Logger, cfg config.Config, client PackClient) *cobra.Command {
+	var flags BuildBatchFlags
+	ctx := createCancellableContext()
+
+	cmd := &cobra.Command{
+		Use:   "build-batch <manifest>",
+		Args:  cobra.ExactArgs(1),
+		Short: "Generate app images for all apps in a manifest",
+		RunE: logError(logger, func(cmd *cobra.Command, args []string) error {
+			apps, err := readBatchManifest(args[0], flags, cfg)
+			if err != nil {
+				return err
+			}
+
+			results := client.BuildMany(ctx, pack.BuildManyOptions{
+				Apps:        apps,
+				Concurrency: flags.Concurrency,
+			})
+
+			failed := logBatchSummary(logger, results)
+			if failed > 0 {
+				return fmt.Errorf("%d of %d builds failed", failed, len(results))
+			}
+			logger.Infof("Successfully built %d images", len(results))
+			return nil
+		}),
+	}
+	cmd.Flags().IntVarP(&flags.Concurrency, "concurrency", "c", 4, "Maximum number of apps to build at once")
+	cmd.Flags().BoolVar(&flags.Publish, "publish", false, "Publish to registry")
+	cmd.Flags().BoolVar(&flags.NoPull, "no-pull", false, "Skip pulling builder and run images before use")
+	cmd.Flags().BoolVar(&flags.ClearCache, "clear-cache", false, "Clear each image's associated cache before building")
+	AddHelpFlag(cmd, "build-batch")
+	markCreatesResources(cmd)
+	return cmd
+}
+
+func readBatchManifest(path string, flags BuildBatchFlags, cfg config.Config) ([]pack.BuildOptions, error) {
+	var manifest batchManifest
+	if _, err := toml.DecodeFile(path, &manifest); err != nil {
+		return nil, errors.Wrapf(err, "reading manifest %s", style.Symbol(path))
+	}
+
+	if len(manifest.Apps) == 0 {
+		return nil, fmt.Errorf("manifest %s has no apps", style.Symbol(path))
+	}
+
+	manifestDir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+
+	var apps []pack.BuildOptions
+	for i, app := range manifest.Apps {
+		if app.Image == "" {
+			return nil, fmt.Errorf("app %d in manifest is missing an %s", i+1, style.Symbol("image"))
+		}
+
+		appPath := app.Path
+		if _, ok := git.ParseSource(appPath); !ok && !filepath.IsAbs(appPath) {
+			appPath = filepath.Join(manifestDir, appPath)
+		}
+
+		builder := firstNonEmpty(app.Builder, manifest.Builder, cfg.DefaultBuilder)
+		if builder == "" {
+			return nil, fmt.Errorf("no builder set for app %s, set one in the manifest or with %s", style.Symbol(app.Image), style.Symbol("pack set-default-builder"))
+		}
+
+		env, err := parseBatchEnv(app.Env)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid env for app %s", style.Symbol(app.Image))
+		}
+
+		apps = append(apps, pack.BuildOptions{
+			Image:             app.Image,
+			AdditionalTags:    app.Tags,
+			AppPath:           appPath,
+			Builder:           builder,
+			RunImage:          firstNonEmpty(app.RunImage, manifest.RunImage),
+			AdditionalMirrors: getMirrors(cfg),
+			Env:               env,
+			CACerts:           cfg.CACerts,
+			Buildpacks:        app.Buildpacks,
+			Publish:           flags.Publish,
+			NoPull:            flags.NoPull,
+			ClearCache:        flags.ClearCache,
+		})
+	}
+	return apps, nil
+}
+
+// parseBatchEnv parses the env of an app in the manifest as --env does, so names may end in an operator, as in
+// 'PATH+', and the result is keyed by the platform env file each value is kept in
+func parseBatchEnv(appEnv map[string]string) (map[string]string, error) {
+	if len(appEnv) == 0 {
+		return nil, nil
+	}
+	env := map[string]string{}
+	for name, value := range appEnv {
+		if strings.Contains(name, "=") {
+			return nil, fmt.Errorf("invalid env var name %s", style.Symbol(name))
+		}
+		if err := addEnvVar(env, name+"="+value); err != nil {
+			return nil, err
+		}
+	}
+	return env, nil
+}
+
+// logBatchSummary logs the outcome of each build and returns the number of failures
+func logBatchSummary(logger logging.Logger, results []pack.BuildResult) int {
+	failed := 0
+	buf := &bytes.Buffer{}
+	tabWriter := new(tabwriter.Writer).Init(buf, 0, 0, 4, ' ', 0)
+	for _, result := range results {
+		status := fmt.Sprintf("succeeded in %s", result.Duration.Round(100*time.Millisecond))
+		if result.Err != nil {
+			failed++
+			status = "failed: " + result.Err.Error()
+		}
+		if _, err := fmt.Fprintf(tabWriter, "\n  %s\t%s", result.Image, status); err != nil {
+			logger.Error(err.Error())
+		}
+	}
+
+	if err := tabWriter.Flush(); err != nil {
+		logger.Error(err.Error())
+	}
+
+	logger.Info("\nSummary:" + buf.String())
+	return failed
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,166 @@
+package commands_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/golang/mock/gomock"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"github.com/spf13/cobra"
+
+	"github.com/buildpack/pack"
+	"github.com/buildpack/pack/commands"
+	cmdmocks "github.com/buildpack/pack/commands/mocks"
+	"github.com/buildpack/pack/config"
+	"github.com/buildpack/pack/internal/mocks"
+	"github.com/buildpack/pack/logging"
+	h "github.com/buildpack/pack/testhelpers"
+)
+
+func TestBuildBatchCommand(t *testing.T) {
+	color.NoColor = true
+	spec.Run(t, "Commands", testBuildBatchCommand, spec.Parallel(), spec.Report(report.Terminal{}))
+}
+
+func testBuildBatchCommand(t *testing.T, when spec.G, it spec.S) {
+	var (
+		command        *cobra.Command
+		logger         logging.Logger
+		outBuf         bytes.Buffer
+		mockController *gomock.Controller
+		mockClient     *cmdmocks.MockPackClient
+		tmpDir         string
+		manifestPath   string
+	)
+
+	it.Before(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "build-batch-test")
+		h.AssertNil(t, err)
+		manifestPath = filepath.Join(tmpDir, "manifest.toml")
+
+		mockController = gomock.NewController(t)
+		mockClient = cmdmocks.NewMockPackClient(mockController)
+		logger = mocks.NewMockLogger(&outBuf)
+		command = commands.BuildBatch(logger, config.Config{DefaultBuilder: "default/builder"}, mockClient)
+	})
+
+	it.After(func() {
+		mockController.Finish()
+		os.RemoveAll(tmpDir)
+	})
+
+	when("#BuildBatch", func() {
+		when("the manifest is valid", func() {
+			it.Before(func() {
+				h.AssertNil(t, ioutil.WriteFile(manifestPath, []byte(`
+builder = "some/builder"
+
+[[apps]]
+image = "some/app-a"
+path = "apps/a"
+tags = ["some/app-a:v1"]
+
+[apps.env]
+SOME_KEY = "some-value"
+"PATH+" = "/some/bin"
+
+[[apps]]
+image = "some/app-b"
+path = "/abs/app-b"
+builder = "other/builder"
+run-image = "other/run"
+`), 0644))
+			})
+
+			it("builds every app", func() {
+				mockClient.EXPECT().BuildMany(gomock.Any(), pack.BuildManyOptions{
+					Concurrency: 2,
+					Apps: []pack.BuildOptions{
+						{
+							Image:             "some/app-a",
+							AdditionalTags:    []string{"some/app-a:v1"},
+							AppPath:           filepath.Join(tmpDir, "apps", "a"),
+							Builder:           "some/builder",
+							AdditionalMirrors: map[string][]string{},
+							Env:               map[string]string{"SOME_KEY.override": "some-value", "PATH.append": "/some/bin"},
+							Publish:           true,
+						},
+						{
+							Image:             "some/app-b",
+							AppPath:           "/abs/app-b",
+							Builder:           "other/builder",
+							RunImage:          "other/run",
+							AdditionalMirrors: map[string][]string{},
+							Publish:           true,
+						},
+					},
+				}).Return([]pack.BuildResult{
+					{Image: "some/app-a", Duration: 2 * time.Second},
+					{Image: "some/app-b", Duration: 3 * time.Second},
+				})
+
+				command.SetArgs([]string{manifestPath, "--concurrency", "2", "--publish"})
+				h.AssertNil(t, command.Execute())
+				h.AssertContains(t, outBuf.String(), "some/app-a    succeeded in 2s")
+				h.AssertContains(t, outBuf.String(), "some/app-b    succeeded in 3s")
+				h.AssertContains(t, outBuf.String(), "Successfully built 2 images")
+			})
+
+			it("fails when any build fails", func() {
+				mockClient.EXPECT().BuildMany(gomock.Any(), gomock.Any()).Return([]pack.BuildResult{
+					{Image: "some/app-a", Duration: 2 * time.Second},
+					{Image: "some/app-b", Err: errors.New("some error")},
+				})
+
+				command.SetArgs([]string{manifestPath})
+				h.AssertError(t, command.Execute(), "1 of 2 builds failed")
+				h.AssertContains(t, outBuf.String(), "some/app-b    failed: some error")
+			})
+		})
+
+		when("an app has no image", func() {
+			it("returns an error", func() {
+				h.AssertNil(t, ioutil.WriteFile(manifestPath, []byte(`
+[[apps]]
+path = "apps/a"
+`), 0644))
+
+				command.SetArgs([]string{manifestPath})
+				h.AssertError(t, command.Execute(), "app 1 in manifest is missing an 'image'")
+			})
+		})
+
+		when("an app has an invalid env var name", func() {
+			it("returns an error", func() {
+				h.AssertNil(t, ioutil.WriteFile(manifestPath, []byte(`
+[[apps]]
+image = "some/app-a"
+path = "apps/a"
+
+[apps.env]
+"../some-file" = "some-value"
+`), 0644))
+
+				command.SetArgs([]string{manifestPath})
+				h.AssertError(t, command.Execute(), "invalid env for app 'some/app-a': invalid env var name '../some-file'")
+			})
+		})
+
+		when("the manifest has no apps", func() {
+			it("returns an error", func() {
+				h.AssertNil(t, ioutil.WriteFile(manifestPath, []byte(`builder = "some/builder"`), 0644))
+
+				command.SetArgs([]string{manifestPath})
+				h.AssertError(t, command.Execute(), "has no apps")
+			})
+		})
+	})
+}
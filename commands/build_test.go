@@ -0,0 +1,49 @@
+package commands_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"github.com/spf13/cobra"
+
+	"github.com/buildpack/pack/commands"
+	"github.com/buildpack/pack/config"
+	"github.com/buildpack/pack/internal/mocks"
+	h "github.com/buildpack/pack/testhelpers"
+)
+
+func TestBuildCommand(t *testing.T) {
+	color.NoColor = true
+	spec.Run(t, "Commands", testBuildCommand, spec.Parallel(), spec.Report(report.Terminal{}))
+}
+
+func testBuildCommand(t *testing.T, when spec.G, it spec.S) {
+	var (
+		command *cobra.Command
+		outBuf  bytes.Buffer
+	)
+
+	it.Before(func() {
+		command = commands.Build(mocks.NewMockLogger(&outBuf), config.Config{DefaultBuilder: "default/builder"}, nil)
+	})
+
+	when("--detect-only", func() {
+		for _, args := range [][]string{
+			{"--publish"},
+			{"--tag", "some/app:v1"},
+			{"--run-image", "some/run"},
+			{"--clear-cache"},
+			{"--cache-name", "shared"},
+			{"--report", "report.json"},
+		} {
+			args := args
+			it("rejects "+args[0]+", as no image is built", func() {
+				command.SetArgs(append([]string{"some/app", "--detect-only"}, args...))
+				h.AssertError(t, command.Execute(), "'"+args[0]+"' cannot be used with '--detect-only'")
+			})
+		}
+	})
+}
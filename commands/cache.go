@@ -0,0 +1,212 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/buildpack/pack"
+	"github.com/buildpack/pack/cache"
+	"github.com/buildpack/pack/logging"
+	"github.com/buildpack/pack/style"
+)
+
+func Cache(logger logging.Logger, client PackClient) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the build and launch caches pack keeps for app images",
+	}
+	cmd.AddCommand(listCaches(logger, client))
+	cmd.AddCommand(inspectCache(logger, client))
+	cmd.AddCommand(removeCache(logger, client))
+	cmd.AddCommand(pruneCaches(logger, client))
+	cmd.AddCommand(exportCache(logger, client))
+	cmd.AddCommand(importCache(logger, client))
+	AddHelpFlag(cmd, "cache")
+	return cmd
+}
+
+func listCaches(logger logging.Logger, client PackClient) *cobra.Command {
+	ctx := createCancellableContext()
+	cmd := &cobra.Command{
+		Use:   "ls",
+		Args:  cobra.NoArgs,
+		Short: "List caches with the app image or cache name they are for, their size and when they were last used",
+		RunE: logError(logger, func(cmd *cobra.Command, args []string) error {
+			caches, err := client.ListCaches(ctx)
+			if err != nil {
+				return err
+			}
+			if len(caches) == 0 {
+				logger.Info("No caches found")
+				return nil
+			}
+
+			tw := tabwriter.NewWriter(logger.Writer(), 0, 0, 3, ' ', 0)
+			_, _ = fmt.Fprintln(tw, "IMAGE\tTYPE\tNAME\tSIZE\tLAST USED")
+			for _, info := range caches {
+				image := info.Image
+				switch {
+				case info.CacheName != "":
+					image = cache.NamedCacheKey(info.CacheName)
+				case image == "":
+					image = "(unknown)"
+				}
+				_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", image, info.Type, info.Name, cacheSize(info), cacheLastUsed(info))
+			}
+			return tw.Flush()
+		}),
+	}
+	AddHelpFlag(cmd, "ls")
+	return cmd
+}
+
+func inspectCache(logger logging.Logger, client PackClient) *cobra.Command {
+	ctx := createCancellableContext()
+	cmd := &cobra.Command{
+		Use:   "inspect <image-name> | name:<cache-name>",
+		Args:  cobra.ExactArgs(1),
+		Short: "Show the caches used to build an app image, or the named cache",
+		RunE: logError(logger, func(cmd *cobra.Command, args []string) error {
+			caches, err := client.InspectCache(ctx, args[0])
+			if err != nil {
+				return err
+			}
+
+			if caches[0].CacheName != "" {
+				logger.Infof("Caches named: %s", style.Symbol(caches[0].CacheName))
+			} else {
+				logger.Infof("Caches for image: %s", style.Symbol(caches[0].Image))
+			}
+			logger.Info("")
+			tw := tabwriter.NewWriter(logger.Writer(), 0, 0, 3, ' ', 0)
+			_, _ = fmt.Fprintln(tw, "  TYPE\tNAME\tSIZE\tLAST USED")
+			for _, info := range caches {
+				_, _ = fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", info.Type, info.Name, cacheSize(info), cacheLastUsed(info))
+			}
+			return tw.Flush()
+		}),
+	}
+	AddHelpFlag(cmd, "inspect")
+	return cmd
+}
+
+func removeCache(logger logging.Logger, client PackClient) *cobra.Command {
+	ctx := createCancellableContext()
+	cmd := &cobra.Command{
+		Use:   "rm <image-name> | name:<cache-name>",
+		Args:  cobra.ExactArgs(1),
+		Short: "Remove the caches used to build an app image, or the named cache",
+		RunE: logError(logger, func(cmd *cobra.Command, args []string) error {
+			caches, err := client.RemoveCache(ctx, args[0])
+			if err != nil {
+				return err
+			}
+			for _, info := range caches {
+				logger.Infof("Removed cache %s %s", info.Type, style.Symbol(info.Name))
+			}
+			return nil
+		}),
+	}
+	AddHelpFlag(cmd, "rm")
+	return cmd
+}
+
+func pruneCaches(logger logging.Logger, client PackClient) *cobra.Command {
+	var olderThan time.Duration
+	ctx := createCancellableContext()
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Args:  cobra.NoArgs,
+		Short: "Remove caches that have not been used recently",
+		RunE: logError(logger, func(cmd *cobra.Command, args []string) error {
+			caches, err := client.PruneCaches(ctx, olderThan)
+			if err != nil {
+				return err
+			}
+			for _, info := range caches {
+				logger.Infof("Removed cache %s %s", info.Type, style.Symbol(info.Name))
+			}
+			logger.Infof("Pruned %d caches", len(caches))
+			return nil
+		}),
+	}
+	cmd.Flags().DurationVar(&olderThan, "older-than", 0, "Only remove caches not used for at least this long, e.g. 72h\nBy default all caches not in use are removed")
+	AddHelpFlag(cmd, "prune")
+	return cmd
+}
+
+func exportCache(logger logging.Logger, client PackClient) *cobra.Command {
+	var output string
+	ctx := createCancellableContext()
+	cmd := &cobra.Command{
+		Use:   "export <image-name> | name:<cache-name> -o <file>",
+		Args:  cobra.ExactArgs(1),
+		Short: "Write the build cache for an app image, or the named build cache, to a tar file",
+		RunE: logError(logger, func(cmd *cobra.Command, args []string) error {
+			f, err := os.Create(output)
+			if err != nil {
+				return errors.Wrap(err, "creating output file")
+			}
+			err = client.ExportCache(ctx, args[0], f)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				_ = os.Remove(output)
+				return err
+			}
+			logger.Infof("Exported build cache for %s to %s", style.Symbol(args[0]), style.Symbol(output))
+			return nil
+		}),
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "File to write the build cache to")
+	_ = cmd.MarkFlagRequired("output")
+	AddHelpFlag(cmd, "export")
+	markCreatesResources(cmd)
+	return cmd
+}
+
+func importCache(logger logging.Logger, client PackClient) *cobra.Command {
+	ctx := createCancellableContext()
+	cmd := &cobra.Command{
+		Use:   "import <image-name> | name:<cache-name> <file>",
+		Args:  cobra.ExactArgs(2),
+		Short: "Replace the build cache for an app image, or the named build cache, with one written by 'pack cache export'",
+		RunE: logError(logger, func(cmd *cobra.Command, args []string) error {
+			if err := client.ImportCache(ctx, args[0], args[1]); err != nil {
+				return err
+			}
+			logger.Infof("Imported build cache for %s from %s", style.Symbol(args[0]), style.Symbol(args[1]))
+			return nil
+		}),
+	}
+	AddHelpFlag(cmd, "import")
+	markCreatesResources(cmd)
+	return cmd
+}
+
+func cacheSize(info pack.CacheInfo) string {
+	if info.Size < 0 {
+		return "unknown"
+	}
+	size := float64(info.Size)
+	for _, unit := range []string{"B", "kB", "MB", "GB"} {
+		if size < 1000 {
+			return fmt.Sprintf("%.4g%s", size, unit)
+		}
+		size /= 1000
+	}
+	return fmt.Sprintf("%.4gTB", size)
+}
+
+func cacheLastUsed(info pack.CacheInfo) string {
+	if info.LastUsed.IsZero() {
+		return "unknown"
+	}
+	return info.LastUsed.Local().Format("2006-01-02 15:04")
+}
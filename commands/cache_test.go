@@ -0,0 +1,236 @@
+package commands_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/golang/mock/gomock"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"github.com/spf13/cobra"
+
+	"github.com/buildpack/pack"
+	"github.com/buildpack/pack/cache"
+	"github.com/buildpack/pack/commands"
+	cmdmocks "github.com/buildpack/pack/commands/mocks"
+	"github.com/buildpack/pack/internal/mocks"
+	"github.com/buildpack/pack/logging"
+	h "github.com/buildpack/pack/testhelpers"
+)
+
+func TestCacheCommand(t *testing.T) {
+	color.NoColor = true
+	spec.Run(t, "Commands", testCacheCommand, spec.Parallel(), spec.Report(report.Terminal{}))
+}
+
+func testCacheCommand(t *testing.T, when spec.G, it spec.S) {
+	var (
+		command        *cobra.Command
+		logger         logging.Logger
+		outBuf         bytes.Buffer
+		mockController *gomock.Controller
+		mockClient     *cmdmocks.MockPackClient
+		caches         []pack.CacheInfo
+	)
+
+	it.Before(func() {
+		mockController = gomock.NewController(t)
+		mockClient = cmdmocks.NewMockPackClient(mockController)
+		logger = mocks.NewMockLogger(&outBuf)
+		command = commands.Cache(logger, mockClient)
+
+		caches = []pack.CacheInfo{
+			{
+				Name:     "pack-cache-123456789abc.build",
+				Type:     cache.Volume,
+				Image:    "index.docker.io/some/app:latest",
+				Size:     1500000,
+				LastUsed: time.Date(2019, 8, 1, 12, 30, 0, 0, time.Local),
+			},
+			{
+				Name:  "pack-cache-cba987654321",
+				Type:  cache.Image,
+				Size:  -1,
+				Image: "",
+			},
+			{
+				Name:      "pack-cache-abcabcabcabc.build",
+				Type:      cache.Volume,
+				CacheName: "some-shared-cache",
+				Size:      2000,
+			},
+		}
+	})
+
+	it.After(func() {
+		mockController.Finish()
+	})
+
+	when("ls", func() {
+		it("lists caches with their image, size and last use", func() {
+			mockClient.EXPECT().ListCaches(gomock.Any()).Return(caches, nil)
+
+			command.SetArgs([]string{"ls"})
+			h.AssertNil(t, command.Execute())
+
+			h.AssertContains(t, outBuf.String(), "IMAGE")
+			h.AssertContainsMatch(t, outBuf.String(), `index.docker.io/some/app:latest\s+volume\s+pack-cache-123456789abc.build\s+1.5MB\s+2019-08-01 12:30`)
+			h.AssertContainsMatch(t, outBuf.String(), `\(unknown\)\s+image\s+pack-cache-cba987654321\s+unknown\s+unknown`)
+			h.AssertContainsMatch(t, outBuf.String(), `name:some-shared-cache\s+volume\s+pack-cache-abcabcabcabc.build\s+2kB`)
+		})
+
+		it("reports when there are no caches", func() {
+			mockClient.EXPECT().ListCaches(gomock.Any()).Return(nil, nil)
+
+			command.SetArgs([]string{"ls"})
+			h.AssertNil(t, command.Execute())
+
+			h.AssertContains(t, outBuf.String(), "No caches found")
+		})
+	})
+
+	when("inspect", func() {
+		it("shows the caches for the image", func() {
+			mockClient.EXPECT().InspectCache(gomock.Any(), "some/app").Return(caches[:1], nil)
+
+			command.SetArgs([]string{"inspect", "some/app"})
+			h.AssertNil(t, command.Execute())
+
+			h.AssertContains(t, outBuf.String(), "Caches for image: 'index.docker.io/some/app:latest'")
+			h.AssertContainsMatch(t, outBuf.String(), `volume\s+pack-cache-123456789abc.build\s+1.5MB`)
+		})
+
+		it("shows the caches with the name", func() {
+			named := pack.CacheInfo{Name: "pack-cache-cba987654321.build", Type: cache.Volume, CacheName: "shared", Size: 1500}
+			mockClient.EXPECT().InspectCache(gomock.Any(), "name:shared").Return([]pack.CacheInfo{named}, nil)
+
+			command.SetArgs([]string{"inspect", "name:shared"})
+			h.AssertNil(t, command.Execute())
+
+			h.AssertContains(t, outBuf.String(), "Caches named: 'shared'")
+			h.AssertContainsMatch(t, outBuf.String(), `volume\s+pack-cache-cba987654321.build\s+1.5kB`)
+		})
+
+		it("returns the error when there are none", func() {
+			mockClient.EXPECT().InspectCache(gomock.Any(), "some/app").Return(nil, errors.New("no caches found"))
+
+			command.SetArgs([]string{"inspect", "some/app"})
+			h.AssertError(t, command.Execute(), "no caches found")
+		})
+	})
+
+	when("rm", func() {
+		it("logs the removed caches", func() {
+			mockClient.EXPECT().RemoveCache(gomock.Any(), "some/app").Return(caches[:1], nil)
+
+			command.SetArgs([]string{"rm", "some/app"})
+			h.AssertNil(t, command.Execute())
+
+			h.AssertContains(t, outBuf.String(), "Removed cache volume 'pack-cache-123456789abc.build'")
+		})
+	})
+
+	when("prune", func() {
+		it("passes --older-than and logs the removed caches", func() {
+			mockClient.EXPECT().PruneCaches(gomock.Any(), 72*time.Hour).Return(caches, nil)
+
+			command.SetArgs([]string{"prune", "--older-than", "72h"})
+			h.AssertNil(t, command.Execute())
+
+			h.AssertContains(t, outBuf.String(), "Removed cache image 'pack-cache-cba987654321'")
+			h.AssertContains(t, outBuf.String(), "Pruned 3 caches")
+		})
+
+		it("removes all caches by default", func() {
+			mockClient.EXPECT().PruneCaches(gomock.Any(), time.Duration(0)).Return(nil, nil)
+
+			command.SetArgs([]string{"prune"})
+			h.AssertNil(t, command.Execute())
+
+			h.AssertContains(t, outBuf.String(), "Pruned 0 caches")
+		})
+	})
+	when("export", func() {
+		var tmpDir string
+
+		it.Before(func() {
+			var err error
+			tmpDir, err = ioutil.TempDir("", "cache-command-test")
+			h.AssertNil(t, err)
+		})
+
+		it.After(func() {
+			h.AssertNil(t, os.RemoveAll(tmpDir))
+		})
+
+		it("writes the cache to the output file", func() {
+			output := filepath.Join(tmpDir, "cache.tar")
+			mockClient.EXPECT().ExportCache(gomock.Any(), "some/app", gomock.Any()).
+				DoAndReturn(func(_ context.Context, _ string, w io.Writer) error {
+					_, err := w.Write([]byte("some-tar"))
+					return err
+				})
+
+			command.SetArgs([]string{"export", "some/app", "-o", output})
+			h.AssertNil(t, command.Execute())
+
+			contents, err := ioutil.ReadFile(output)
+			h.AssertNil(t, err)
+			h.AssertEq(t, string(contents), "some-tar")
+			h.AssertContains(t, outBuf.String(), "Exported build cache for 'some/app'")
+		})
+
+		it("removes the output file on failure", func() {
+			output := filepath.Join(tmpDir, "cache.tar")
+			mockClient.EXPECT().ExportCache(gomock.Any(), "some/app", gomock.Any()).Return(errors.New("no build cache found"))
+
+			command.SetArgs([]string{"export", "some/app", "-o", output})
+			h.AssertError(t, command.Execute(), "no build cache found")
+
+			_, err := os.Stat(output)
+			h.AssertEq(t, os.IsNotExist(err), true)
+		})
+
+		it("requires an output file", func() {
+			command.SetArgs([]string{"export", "some/app"})
+			h.AssertError(t, command.Execute(), `required flag(s) "output" not set`)
+		})
+	})
+
+	when("import", func() {
+		it("imports the cache from the file", func() {
+			mockClient.EXPECT().ImportCache(gomock.Any(), "some/app", "cache.tar").Return(nil)
+
+			command.SetArgs([]string{"import", "some/app", "cache.tar"})
+			h.AssertNil(t, command.Execute())
+
+			h.AssertContains(t, outBuf.String(), "Imported build cache for 'some/app' from 'cache.tar'")
+		})
+	})
+
+	when("resources left behind by pack are collected on startup", func() {
+		it("collects them before the subcommands that create containers", func() {
+			for _, args := range [][]string{{"export"}, {"import"}} {
+				sub, _, err := command.Find(args)
+				h.AssertNil(t, err)
+				h.AssertEq(t, commands.CreatesResources(sub), true)
+			}
+		})
+
+		it("does not collect them before the other subcommands", func() {
+			for _, args := range [][]string{{"ls"}, {"inspect"}, {"rm"}, {"prune"}} {
+				sub, _, err := command.Find(args)
+				h.AssertNil(t, err)
+				h.AssertEq(t, commands.CreatesResources(sub), false)
+			}
+		})
+	})
+}
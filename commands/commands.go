@@ -3,11 +3,13 @@ package commands
 import (
 	"context"
 	"fmt"
+	"io"
 	"math/rand"
 	"os"
 	"os/signal"
 	"syscall"
 	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -19,9 +21,18 @@ import (
 
 //go:generate mockgen -package mocks -destination mocks/pack_client.go github.com/buildpack/pack/commands PackClient
 type PackClient interface {
+	BuildMany(context.Context, pack.BuildManyOptions) []pack.BuildResult
 	InspectBuilder(string, bool) (*pack.BuilderInfo, error)
+	InspectImage(string, bool) (*pack.ImageInfo, error)
 	Rebase(context.Context, pack.RebaseOptions) error
 	CreateBuilder(context.Context, pack.CreateBuilderOptions) error
+	ListCaches(context.Context) ([]pack.CacheInfo, error)
+	InspectCache(context.Context, string) ([]pack.CacheInfo, error)
+	RemoveCache(context.Context, string) ([]pack.CacheInfo, error)
+	PruneCaches(context.Context, time.Duration) ([]pack.CacheInfo, error)
+	ExportCache(context.Context, string, io.Writer) error
+	ImportCache(context.Context, string, string) error
+	CollectGarbage(context.Context, pack.GCOptions) ([]pack.Resource, error)
 }
 
 type suggestedBuilder struct {
@@ -43,6 +54,22 @@ func AddHelpFlag(cmd *cobra.Command, commandName string) {
 	cmd.Flags().BoolP("help", "h", false, fmt.Sprintf("Help for '%s'", commandName))
 }
 
+// createsResourcesAnnotation is set on commands that create containers or volumes in the daemon
+const createsResourcesAnnotation = "pack.creates-resources"
+
+func markCreatesResources(cmd *cobra.Command) {
+	if cmd.Annotations == nil {
+		cmd.Annotations = map[string]string{}
+	}
+	cmd.Annotations[createsResourcesAnnotation] = "true"
+}
+
+// CreatesResources reports whether cmd creates containers or volumes in the daemon, so resources left behind by killed
+// pack processes are worth collecting before it runs
+func CreatesResources(cmd *cobra.Command) bool {
+	return cmd.Annotations[createsResourcesAnnotation] == "true"
+}
+
 func logError(logger logging.Logger, f func(cmd *cobra.Command, args []string) error) func(*cobra.Command, []string) error {
 	return func(cmd *cobra.Command, args []string) error {
 		cmd.SilenceErrors = true
@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/buildpack/pack"
+	"github.com/buildpack/pack/build"
+	"github.com/buildpack/pack/config"
+	"github.com/buildpack/pack/logging"
+)
+
+func Detect(logger logging.Logger, cfg config.Config, packClient *pack.Client) *cobra.Command {
+	var flags BuildFlags
+	ctx := createCancellableContext()
+
+	cmd := &cobra.Command{
+		Use:   "detect",
+		Args:  cobra.NoArgs,
+		Short: "Show which buildpacks would build the app, without building it",
+		RunE: logError(logger, func(cmd *cobra.Command, args []string) error {
+			if err := applyProjectBuilder(cmd, &flags); err != nil {
+				return err
+			}
+			if flags.Builder == "" {
+				suggestSettingBuilder(logger, packClient)
+				return MakeSoftError()
+			}
+			env, err := parseEnv(flags.EnvFile, flags.Env)
+			if err != nil {
+				return err
+			}
+			secrets, bindings, err := parseBindings(flags.Secrets, flags.Bindings)
+			if err != nil {
+				return err
+			}
+			flags.CACerts = caCertFiles(cfg, flags.CACerts)
+			var appReader io.Reader
+			if flags.AppPath == "-" {
+				appReader = os.Stdin
+			}
+			return detect(ctx, logger, packClient, flags, env, secrets, bindings, appReader)
+		}),
+	}
+	appCommandFlags(cmd, &flags, cfg)
+	AddHelpFlag(cmd, "detect")
+	markCreatesResources(cmd)
+	return cmd
+}
+
+func detect(ctx context.Context, logger logging.Logger, packClient *pack.Client, flags BuildFlags, env, secrets, bindings map[string]string, appReader io.Reader) error {
+	result, err := packClient.Detect(ctx, pack.DetectOptions{
+		Builder:    flags.Builder,
+		AppPath:    flags.AppPath,
+		AppReader:  appReader,
+		Env:        env,
+		Secrets:    secrets,
+		Bindings:   bindings,
+		CACerts:    flags.CACerts,
+		NoPull:     flags.NoPull,
+		Buildpacks: flags.Buildpacks,
+		Exclude:    flags.Exclude,
+		Include:    flags.Include,
+	})
+	if err != nil {
+		return err
+	}
+	logDetectResult(logger, result)
+	return nil
+}
+
+func logDetectResult(logger logging.Logger, result *build.DetectResult) {
+	logger.Info("Detected buildpacks:")
+	tw := tabwriter.NewWriter(logger.Writer(), 0, 0, 4, ' ', 0)
+	for _, bp := range result.Group {
+		_, _ = fmt.Fprintf(tw, "  %s\t%s\n", bp.ID, bp.Version)
+	}
+	_ = tw.Flush()
+
+	plan := strings.TrimSpace(result.Plan)
+	if plan == "" {
+		plan = "(empty)"
+	}
+	logger.Info("")
+	logger.Info("Build plan:")
+	logger.Info(plan)
+}
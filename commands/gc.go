@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/buildpack/pack"
+	"github.com/buildpack/pack/logging"
+	"github.com/buildpack/pack/style"
+)
+
+func GC(logger logging.Logger, client PackClient) *cobra.Command {
+	var opts pack.GCOptions
+	ctx := createCancellableContext()
+	cmd := &cobra.Command{
+		Use:   "gc",
+		Args:  cobra.NoArgs,
+		Short: "Remove containers, volumes and images left behind by pack processes that were killed",
+		RunE: logError(logger, func(cmd *cobra.Command, args []string) error {
+			resources, err := client.CollectGarbage(ctx, opts)
+			if err != nil {
+				return err
+			}
+			action := "Removed"
+			if opts.DryRun {
+				action = "Would remove"
+			}
+			for _, r := range resources {
+				logger.Infof("%s %s %s%s", action, r.Kind, style.Symbol(r.Name), resourceDetails(r))
+			}
+			if !opts.DryRun {
+				logger.Infof("Removed %d resources", len(resources))
+			}
+			return nil
+		}),
+	}
+	cmd.Flags().DurationVar(&opts.OlderThan, "older-than", pack.DefaultGCAge, "Only remove resources created at least this long ago")
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "List the resources that would be removed without removing them")
+	cmd.Flags().BoolVar(&opts.Kept, "kept", false, "Also remove the volumes and failed containers of builds kept with --keep-on-failure\nThey are left alone otherwise, even once the build that kept them has exited")
+	AddHelpFlag(cmd, "gc")
+	return cmd
+}
+
+func resourceDetails(r pack.Resource) string {
+	var details []string
+	if r.Purpose != "" {
+		details = append(details, r.Purpose)
+	}
+	if !r.Created.IsZero() {
+		details = append(details, "created "+r.Created.Local().Format("2006-01-02 15:04"))
+	}
+	if len(details) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(details, ", ") + ")"
+}
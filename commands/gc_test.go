@@ -0,0 +1,96 @@
+package commands_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/golang/mock/gomock"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"github.com/spf13/cobra"
+
+	"github.com/buildpack/pack"
+	"github.com/buildpack/pack/commands"
+	cmdmocks "github.com/buildpack/pack/commands/mocks"
+	"github.com/buildpack/pack/internal/mocks"
+	"github.com/buildpack/pack/logging"
+	h "github.com/buildpack/pack/testhelpers"
+)
+
+func TestGCCommand(t *testing.T) {
+	color.NoColor = true
+	spec.Run(t, "Commands", testGCCommand, spec.Parallel(), spec.Report(report.Terminal{}))
+}
+
+func testGCCommand(t *testing.T, when spec.G, it spec.S) {
+	var (
+		command        *cobra.Command
+		logger         logging.Logger
+		outBuf         bytes.Buffer
+		mockController *gomock.Controller
+		mockClient     *cmdmocks.MockPackClient
+		resources      []pack.Resource
+	)
+
+	it.Before(func() {
+		mockController = gomock.NewController(t)
+		mockClient = cmdmocks.NewMockPackClient(mockController)
+		logger = mocks.NewMockLogger(&outBuf)
+		command = commands.GC(logger, mockClient)
+
+		resources = []pack.Resource{
+			{
+				Kind:    pack.ContainerResource,
+				Name:    "some-container",
+				Purpose: "phase",
+				Created: time.Date(2019, 8, 1, 12, 30, 0, 0, time.Local),
+			},
+			{
+				Kind: pack.VolumeResource,
+				Name: "pack-layers-abcdefghij",
+			},
+		}
+	})
+
+	it.After(func() {
+		mockController.Finish()
+	})
+
+	it("removes resources older than an hour by default", func() {
+		mockClient.EXPECT().CollectGarbage(gomock.Any(), pack.GCOptions{OlderThan: time.Hour}).Return(resources, nil)
+
+		command.SetArgs([]string{})
+		h.AssertNil(t, command.Execute())
+		h.AssertContains(t, outBuf.String(), "Removed container 'some-container' (phase, created 2019-08-01 12:30)")
+		h.AssertContains(t, outBuf.String(), "Removed volume 'pack-layers-abcdefghij'\n")
+		h.AssertContains(t, outBuf.String(), "Removed 2 resources")
+	})
+
+	it("lists the resources that would be removed when --dry-run is set", func() {
+		mockClient.EXPECT().CollectGarbage(gomock.Any(), pack.GCOptions{OlderThan: 30 * time.Minute, DryRun: true}).Return(resources, nil)
+
+		command.SetArgs([]string{"--older-than", "30m", "--dry-run"})
+		h.AssertNil(t, command.Execute())
+		h.AssertContains(t, outBuf.String(), "Would remove container 'some-container'")
+		h.AssertContains(t, outBuf.String(), "Would remove volume 'pack-layers-abcdefghij'")
+		h.AssertNotContains(t, outBuf.String(), "Removed")
+	})
+
+	it("also removes kept resources when --kept is set", func() {
+		mockClient.EXPECT().CollectGarbage(gomock.Any(), pack.GCOptions{OlderThan: time.Hour, Kept: true}).Return(resources, nil)
+
+		command.SetArgs([]string{"--kept"})
+		h.AssertNil(t, command.Execute())
+		h.AssertContains(t, outBuf.String(), "Removed 2 resources")
+	})
+
+	it("returns an error when garbage cannot be collected", func() {
+		mockClient.EXPECT().CollectGarbage(gomock.Any(), gomock.Any()).Return(nil, errors.New("some-error"))
+
+		command.SetArgs([]string{})
+		h.AssertError(t, command.Execute(), "some-error")
+	})
+}
@@ -0,0 +1,205 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/buildpack/pack"
+	"github.com/buildpack/pack/config"
+	"github.com/buildpack/pack/logging"
+	"github.com/buildpack/pack/style"
+)
+
+type InspectImageFlags struct {
+	Output string
+}
+
+func InspectImage(logger logging.Logger, cfg config.Config, client PackClient) *cobra.Command {
+	var flags InspectImageFlags
+	cmd := &cobra.Command{
+		Use:   "inspect-image <image-name>",
+		Short: "Show information about a built image",
+		Args:  cobra.ExactArgs(1),
+		RunE: logError(logger, func(cmd *cobra.Command, args []string) error {
+			imageName := args[0]
+			switch flags.Output {
+			case "json":
+				return inspectImageJSON(logger, client, imageName)
+			case "text", "":
+			default:
+				return fmt.Errorf("invalid output format %s, must be one of %s or %s", style.Symbol(flags.Output), style.Symbol("text"), style.Symbol("json"))
+			}
+
+			logger.Infof("Inspecting image: %s", style.Symbol(imageName))
+			logger.Info("")
+
+			logger.Info("Remote")
+			logger.Info("------")
+			inspectImageOutput(logger, client, imageName, false, cfg)
+
+			logger.Info("")
+			logger.Info("Local")
+			logger.Info("-----")
+			inspectImageOutput(logger, client, imageName, true, cfg)
+
+			return nil
+		}),
+	}
+	cmd.Flags().StringVarP(&flags.Output, "output", "o", "text", "Output format, one of 'text' or 'json'")
+	AddHelpFlag(cmd, "inspect-image")
+	return cmd
+}
+
+// imageSection is the remote or local part of the json output, carrying the error instead of the image info when
+// inspecting that image failed
+type imageSection struct {
+	*pack.ImageInfo
+	Error string `json:"error,omitempty"`
+}
+
+func inspectImageJSON(logger logging.Logger, client PackClient, imageName string) error {
+	out, err := json.MarshalIndent(struct {
+		Remote *imageSection `json:"remote"`
+		Local  *imageSection `json:"local"`
+	}{
+		inspectImageSection(client, imageName, false),
+		inspectImageSection(client, imageName, true),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	logger.Info(string(out))
+	return nil
+}
+
+func inspectImageSection(client PackClient, imageName string, local bool) *imageSection {
+	info, err := client.InspectImage(imageName, local)
+	if err != nil {
+		return &imageSection{Error: err.Error()}
+	}
+	if info == nil {
+		return nil
+	}
+	return &imageSection{ImageInfo: info}
+}
+
+func inspectImageOutput(logger logging.Logger, client PackClient, imageName string, local bool, cfg config.Config) {
+	info, err := client.InspectImage(imageName, local)
+	if err != nil {
+		logger.Info("")
+		logger.Error(err.Error())
+		return
+	}
+
+	if info == nil {
+		logger.Info("")
+		logger.Info("Not present")
+		return
+	}
+
+	logger.Info("")
+	logger.Infof("Stack: %s", info.StackID)
+	logger.Info("")
+
+	logger.Info("Base Image:")
+	if info.RunImage.SHA != "" {
+		logger.Infof("  Reference: %s", info.RunImage.SHA)
+	}
+	logger.Infof("  Top Layer: %s", info.RunImage.TopLayer)
+
+	logger.Info("")
+	logger.Info("Run Images:")
+	if info.Stack.RunImage.Image == "" {
+		logger.Info("  (none)")
+	} else {
+		for _, r := range getLocalMirrors(info.Stack.RunImage.Image, cfg) {
+			logger.Infof("  %s (user-configured)", r)
+		}
+		logger.Infof("  %s", info.Stack.RunImage.Image)
+		for _, r := range info.Stack.RunImage.Mirrors {
+			logger.Infof("  %s", r)
+		}
+	}
+
+	if len(info.Buildpacks) == 0 {
+		logger.Info("")
+		logger.Warnf("%s has no buildpacks", style.Symbol(imageName))
+	} else {
+		logImageBuildpacksInfo(logger, info)
+	}
+
+	if len(info.Processes) > 0 {
+		logProcessesInfo(logger, info)
+	}
+}
+
+func logImageBuildpacksInfo(logger logging.Logger, info *pack.ImageInfo) {
+	buf := &bytes.Buffer{}
+	tabWriter := new(tabwriter.Writer).Init(buf, 0, 0, 8, ' ', 0)
+	if _, err := fmt.Fprint(tabWriter, "\n  ID\tVERSION"); err != nil {
+		logger.Error(err.Error())
+	}
+
+	for _, bp := range info.Buildpacks {
+		if _, err := fmt.Fprintf(tabWriter, "\n  %s\t%s", bp.ID, bp.Version); err != nil {
+			logger.Error(err.Error())
+		}
+
+		var layerNames []string
+		for name := range bp.Layers {
+			layerNames = append(layerNames, name)
+		}
+		sort.Strings(layerNames)
+		for _, name := range layerNames {
+			if _, err := fmt.Fprintf(tabWriter, "\n    %s\t%s", name, layerFlags(bp.Layers[name].Build, bp.Layers[name].Launch, bp.Layers[name].Cache)); err != nil {
+				logger.Error(err.Error())
+			}
+		}
+	}
+
+	if err := tabWriter.Flush(); err != nil {
+		logger.Error(err.Error())
+	}
+
+	logger.Info("\nBuildpacks:" + buf.String())
+}
+
+func layerFlags(build, launch, cache bool) string {
+	var flags []string
+	if build {
+		flags = append(flags, "build")
+	}
+	if launch {
+		flags = append(flags, "launch")
+	}
+	if cache {
+		flags = append(flags, "cache")
+	}
+	return strings.Join(flags, ", ")
+}
+
+func logProcessesInfo(logger logging.Logger, info *pack.ImageInfo) {
+	buf := &bytes.Buffer{}
+	tabWriter := new(tabwriter.Writer).Init(buf, 0, 0, 4, ' ', 0)
+	if _, err := fmt.Fprint(tabWriter, "\n  TYPE\tCOMMAND\tARGS"); err != nil {
+		logger.Error(err.Error())
+	}
+
+	for _, p := range info.Processes {
+		if _, err := fmt.Fprintf(tabWriter, "\n  %s\t%s\t%s", p.Type, p.Command, strings.Join(p.Args, " ")); err != nil {
+			logger.Error(err.Error())
+		}
+	}
+
+	if err := tabWriter.Flush(); err != nil {
+		logger.Error(err.Error())
+	}
+
+	logger.Info("\nProcesses:" + buf.String())
+}
@@ -0,0 +1,173 @@
+package commands_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/buildpack/lifecycle/metadata"
+	"github.com/golang/mock/gomock"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"github.com/spf13/cobra"
+
+	"github.com/buildpack/pack"
+	"github.com/buildpack/pack/commands"
+	cmdmocks "github.com/buildpack/pack/commands/mocks"
+	"github.com/buildpack/pack/config"
+	"github.com/buildpack/pack/internal/mocks"
+	"github.com/buildpack/pack/logging"
+	h "github.com/buildpack/pack/testhelpers"
+)
+
+func TestInspectImageCommand(t *testing.T) {
+	spec.Run(t, "Commands", testInspectImageCommand, spec.Parallel(), spec.Report(report.Terminal{}))
+}
+
+func testInspectImageCommand(t *testing.T, when spec.G, it spec.S) {
+	var (
+		command        *cobra.Command
+		logger         logging.Logger
+		outBuf         bytes.Buffer
+		mockController *gomock.Controller
+		mockClient     *cmdmocks.MockPackClient
+		cfg            config.Config
+		info           *pack.ImageInfo
+	)
+
+	it.Before(func() {
+		cfg = config.Config{
+			RunImages: []config.RunImage{
+				{Image: "some/run-image", Mirrors: []string{"first/local"}},
+			},
+		}
+		mockController = gomock.NewController(t)
+		mockClient = cmdmocks.NewMockPackClient(mockController)
+		logger = mocks.NewMockLogger(&outBuf)
+		command = commands.InspectImage(logger, cfg, mockClient)
+
+		info = &pack.ImageInfo{
+			StackID: "test.stack.id",
+			Stack: metadata.StackMetadata{
+				RunImage: metadata.StackRunImageMetadata{
+					Image:   "some/run-image",
+					Mirrors: []string{"some/mirror"},
+				},
+			},
+			RunImage: metadata.RunImageMetadata{TopLayer: "some-top-layer", SHA: "some-run-image-sha"},
+			Buildpacks: []metadata.BuildpackMetadata{
+				{
+					ID:      "some.buildpack",
+					Version: "1.2.3",
+					Layers: map[string]metadata.LayerMetadata{
+						"some-layer": {Launch: true, Cache: true},
+					},
+				},
+			},
+			Processes: []pack.ProcessInfo{
+				{Type: "web", Command: "some-command", Args: []string{"some", "args"}},
+			},
+		}
+	})
+
+	it.After(func() {
+		mockController.Finish()
+	})
+
+	when("#InspectImage", func() {
+		when("image cannot be found", func() {
+			it("logs 'Not present'", func() {
+				mockClient.EXPECT().InspectImage("some/image", false).Return(nil, nil)
+				mockClient.EXPECT().InspectImage("some/image", true).Return(nil, nil)
+
+				command.SetArgs([]string{"some/image"})
+				h.AssertNil(t, command.Execute())
+
+				h.AssertContains(t, outBuf.String(), "Remote\n------\n\nNot present\n\nLocal\n-----\n\nNot present\n")
+			})
+		})
+
+		when("inspector returns an error", func() {
+			it("logs the error message", func() {
+				mockClient.EXPECT().InspectImage("some/image", false).Return(nil, errors.New("some remote error"))
+				mockClient.EXPECT().InspectImage("some/image", true).Return(nil, errors.New("some local error"))
+
+				command.SetArgs([]string{"some/image"})
+				h.AssertNil(t, command.Execute())
+
+				h.AssertContains(t, outBuf.String(), "ERROR: some remote error")
+				h.AssertContains(t, outBuf.String(), "ERROR: some local error")
+			})
+		})
+
+		when("the image is present", func() {
+			it("displays image information", func() {
+				mockClient.EXPECT().InspectImage("some/image", false).Return(info, nil)
+				mockClient.EXPECT().InspectImage("some/image", true).Return(nil, nil)
+
+				command.SetArgs([]string{"some/image"})
+				h.AssertNil(t, command.Execute())
+
+				h.AssertContains(t, outBuf.String(), "Inspecting image: 'some/image'")
+				h.AssertContains(t, outBuf.String(), "Stack: test.stack.id")
+				h.AssertContains(t, outBuf.String(), "Reference: some-run-image-sha")
+				h.AssertContains(t, outBuf.String(), "Top Layer: some-top-layer")
+				h.AssertContains(t, outBuf.String(), `Run Images:
+  first/local (user-configured)
+  some/run-image
+  some/mirror
+`)
+				h.AssertContains(t, outBuf.String(), "some.buildpack        1.2.3")
+				h.AssertContains(t, outBuf.String(), "some-layer          launch, cache")
+				h.AssertContains(t, outBuf.String(), "web     some-command    some args")
+			})
+		})
+
+		when("--output json", func() {
+			it("prints remote and local info as json", func() {
+				mockClient.EXPECT().InspectImage("some/image", false).Return(info, nil)
+				mockClient.EXPECT().InspectImage("some/image", true).Return(nil, nil)
+
+				command.SetArgs([]string{"some/image", "--output", "json"})
+				h.AssertNil(t, command.Execute())
+
+				var out struct {
+					Remote *pack.ImageInfo `json:"remote"`
+					Local  *pack.ImageInfo `json:"local"`
+				}
+				h.AssertNil(t, json.Unmarshal(outBuf.Bytes(), &out))
+				h.AssertEq(t, out.Remote.StackID, "test.stack.id")
+				h.AssertEq(t, out.Remote.Processes[0].Command, "some-command")
+				h.AssertNil(t, out.Local)
+			})
+
+			it("records a remote error and still prints the local info", func() {
+				mockClient.EXPECT().InspectImage("some/image", false).Return(nil, errors.New("some remote error"))
+				mockClient.EXPECT().InspectImage("some/image", true).Return(info, nil)
+
+				command.SetArgs([]string{"some/image", "--output", "json"})
+				h.AssertNil(t, command.Execute())
+
+				var out struct {
+					Remote *struct {
+						StackID string `json:"stack"`
+						Error   string `json:"error"`
+					} `json:"remote"`
+					Local *pack.ImageInfo `json:"local"`
+				}
+				h.AssertNil(t, json.Unmarshal(outBuf.Bytes(), &out))
+				h.AssertEq(t, out.Remote.Error, "some remote error")
+				h.AssertEq(t, out.Remote.StackID, "")
+				h.AssertEq(t, out.Local.StackID, "test.stack.id")
+			})
+		})
+
+		when("the output format is invalid", func() {
+			it("returns an error", func() {
+				command.SetArgs([]string{"some/image", "--output", "yaml"})
+				h.AssertError(t, command.Execute(), "invalid output format 'yaml'")
+			})
+		})
+	})
+}
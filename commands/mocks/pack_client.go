@@ -6,7 +6,9 @@ package mocks
 
 import (
 	context "context"
+	io "io"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 
@@ -36,6 +38,31 @@ func (m *MockPackClient) EXPECT() *MockPackClientMockRecorder {
 	return m.recorder
 }
 
+// BuildMany mocks base method
+func (m *MockPackClient) BuildMany(arg0 context.Context, arg1 pack.BuildManyOptions) []pack.BuildResult {
+	ret := m.ctrl.Call(m, "BuildMany", arg0, arg1)
+	ret0, _ := ret[0].([]pack.BuildResult)
+	return ret0
+}
+
+// BuildMany indicates an expected call of BuildMany
+func (mr *MockPackClientMockRecorder) BuildMany(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BuildMany", reflect.TypeOf((*MockPackClient)(nil).BuildMany), arg0, arg1)
+}
+
+// CollectGarbage mocks base method
+func (m *MockPackClient) CollectGarbage(arg0 context.Context, arg1 pack.GCOptions) ([]pack.Resource, error) {
+	ret := m.ctrl.Call(m, "CollectGarbage", arg0, arg1)
+	ret0, _ := ret[0].([]pack.Resource)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CollectGarbage indicates an expected call of CollectGarbage
+func (mr *MockPackClientMockRecorder) CollectGarbage(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CollectGarbage", reflect.TypeOf((*MockPackClient)(nil).CollectGarbage), arg0, arg1)
+}
+
 // CreateBuilder mocks base method
 func (m *MockPackClient) CreateBuilder(arg0 context.Context, arg1 pack.CreateBuilderOptions) error {
 	ret := m.ctrl.Call(m, "CreateBuilder", arg0, arg1)
@@ -48,6 +75,30 @@ func (mr *MockPackClientMockRecorder) CreateBuilder(arg0, arg1 interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBuilder", reflect.TypeOf((*MockPackClient)(nil).CreateBuilder), arg0, arg1)
 }
 
+// ExportCache mocks base method
+func (m *MockPackClient) ExportCache(arg0 context.Context, arg1 string, arg2 io.Writer) error {
+	ret := m.ctrl.Call(m, "ExportCache", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExportCache indicates an expected call of ExportCache
+func (mr *MockPackClientMockRecorder) ExportCache(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportCache", reflect.TypeOf((*MockPackClient)(nil).ExportCache), arg0, arg1, arg2)
+}
+
+// ImportCache mocks base method
+func (m *MockPackClient) ImportCache(arg0 context.Context, arg1 string, arg2 string) error {
+	ret := m.ctrl.Call(m, "ImportCache", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ImportCache indicates an expected call of ImportCache
+func (mr *MockPackClientMockRecorder) ImportCache(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportCache", reflect.TypeOf((*MockPackClient)(nil).ImportCache), arg0, arg1, arg2)
+}
+
 // InspectBuilder mocks base method
 func (m *MockPackClient) InspectBuilder(arg0 string, arg1 bool) (*pack.BuilderInfo, error) {
 	ret := m.ctrl.Call(m, "InspectBuilder", arg0, arg1)
@@ -61,6 +112,58 @@ func (mr *MockPackClientMockRecorder) InspectBuilder(arg0, arg1 interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InspectBuilder", reflect.TypeOf((*MockPackClient)(nil).InspectBuilder), arg0, arg1)
 }
 
+// InspectCache mocks base method
+func (m *MockPackClient) InspectCache(arg0 context.Context, arg1 string) ([]pack.CacheInfo, error) {
+	ret := m.ctrl.Call(m, "InspectCache", arg0, arg1)
+	ret0, _ := ret[0].([]pack.CacheInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InspectCache indicates an expected call of InspectCache
+func (mr *MockPackClientMockRecorder) InspectCache(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InspectCache", reflect.TypeOf((*MockPackClient)(nil).InspectCache), arg0, arg1)
+}
+
+// InspectImage mocks base method
+func (m *MockPackClient) InspectImage(arg0 string, arg1 bool) (*pack.ImageInfo, error) {
+	ret := m.ctrl.Call(m, "InspectImage", arg0, arg1)
+	ret0, _ := ret[0].(*pack.ImageInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InspectImage indicates an expected call of InspectImage
+func (mr *MockPackClientMockRecorder) InspectImage(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InspectImage", reflect.TypeOf((*MockPackClient)(nil).InspectImage), arg0, arg1)
+}
+
+// ListCaches mocks base method
+func (m *MockPackClient) ListCaches(arg0 context.Context) ([]pack.CacheInfo, error) {
+	ret := m.ctrl.Call(m, "ListCaches", arg0)
+	ret0, _ := ret[0].([]pack.CacheInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListCaches indicates an expected call of ListCaches
+func (mr *MockPackClientMockRecorder) ListCaches(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListCaches", reflect.TypeOf((*MockPackClient)(nil).ListCaches), arg0)
+}
+
+// PruneCaches mocks base method
+func (m *MockPackClient) PruneCaches(arg0 context.Context, arg1 time.Duration) ([]pack.CacheInfo, error) {
+	ret := m.ctrl.Call(m, "PruneCaches", arg0, arg1)
+	ret0, _ := ret[0].([]pack.CacheInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PruneCaches indicates an expected call of PruneCaches
+func (mr *MockPackClientMockRecorder) PruneCaches(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PruneCaches", reflect.TypeOf((*MockPackClient)(nil).PruneCaches), arg0, arg1)
+}
+
 // Rebase mocks base method
 func (m *MockPackClient) Rebase(arg0 context.Context, arg1 pack.RebaseOptions) error {
 	ret := m.ctrl.Call(m, "Rebase", arg0, arg1)
@@ -72,3 +175,16 @@ func (m *MockPackClient) Rebase(arg0 context.Context, arg1 pack.RebaseOptions) e
 func (mr *MockPackClientMockRecorder) Rebase(arg0, arg1 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rebase", reflect.TypeOf((*MockPackClient)(nil).Rebase), arg0, arg1)
 }
+
+// RemoveCache mocks base method
+func (m *MockPackClient) RemoveCache(arg0 context.Context, arg1 string) ([]pack.CacheInfo, error) {
+	ret := m.ctrl.Call(m, "RemoveCache", arg0, arg1)
+	ret0, _ := ret[0].([]pack.CacheInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemoveCache indicates an expected call of RemoveCache
+func (mr *MockPackClientMockRecorder) RemoveCache(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveCache", reflect.TypeOf((*MockPackClient)(nil).RemoveCache), arg0, arg1)
+}
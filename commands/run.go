@@ -18,6 +18,9 @@ func Run(logger logging.Logger, cfg config.Config, packClient *pack.Client) *cob
 		Args:  cobra.NoArgs,
 		Short: "Build and run app image (recommended for development only)",
 		RunE: logError(logger, func(cmd *cobra.Command, args []string) error {
+			if err := applyProjectBuilder(cmd, &flags); err != nil {
+				return err
+			}
 			if flags.Builder == "" {
 				suggestSettingBuilder(logger, packClient)
 				return MakeSoftError()
@@ -26,14 +29,24 @@ func Run(logger logging.Logger, cfg config.Config, packClient *pack.Client) *cob
 			if err != nil {
 				return err
 			}
+			secrets, bindings, err := parseBindings(flags.Secrets, flags.Bindings)
+			if err != nil {
+				return err
+			}
+			flags.CACerts = caCertFiles(cfg, flags.CACerts)
 			return packClient.Run(ctx, pack.RunOptions{
 				AppPath:    flags.AppPath,
 				Builder:    flags.Builder,
 				RunImage:   flags.RunImage,
 				Env:        env,
+				Secrets:    secrets,
+				Bindings:   bindings,
+				CACerts:    flags.CACerts,
 				NoPull:     flags.NoPull,
 				ClearCache: flags.ClearCache,
 				Buildpacks: flags.Buildpacks,
+				Exclude:    flags.Exclude,
+				Include:    flags.Include,
 				Ports:      ports,
 			})
 		}),
@@ -41,5 +54,6 @@ func Run(logger logging.Logger, cfg config.Config, packClient *pack.Client) *cob
 	buildCommandFlags(cmd, &flags, cfg)
 	cmd.Flags().StringSliceVar(&ports, "port", nil, "Port to publish (defaults to port(s) exposed by container)"+multiValueHelp("port"))
 	AddHelpFlag(cmd, "run")
+	markCreatesResources(cmd)
 	return cmd
 }
@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/spf13/cobra"
+
+	"github.com/buildpack/pack"
+	"github.com/buildpack/pack/config"
+	"github.com/buildpack/pack/logging"
+)
+
+func Shell(logger logging.Logger, cfg config.Config, packClient *pack.Client) *cobra.Command {
+	var flags BuildFlags
+	var detect bool
+	ctx := createCancellableContext()
+
+	cmd := &cobra.Command{
+		Use:   "shell",
+		Args:  cobra.NoArgs,
+		Short: "Open a shell in the build environment of an app (recommended for development only)",
+		RunE: logError(logger, func(cmd *cobra.Command, args []string) error {
+			if flags.AppPath == "-" {
+				return errors.New("cannot read the app from stdin when opening a shell")
+			}
+			if err := applyProjectBuilder(cmd, &flags); err != nil {
+				return err
+			}
+			if flags.Builder == "" {
+				suggestSettingBuilder(logger, packClient)
+				return MakeSoftError()
+			}
+			env, err := parseEnv(flags.EnvFile, flags.Env)
+			if err != nil {
+				return err
+			}
+			secrets, bindings, err := parseBindings(flags.Secrets, flags.Bindings)
+			if err != nil {
+				return err
+			}
+			flags.CACerts = caCertFiles(cfg, flags.CACerts)
+			return packClient.Shell(ctx, pack.ShellOptions{
+				DetectOptions: pack.DetectOptions{
+					Builder:    flags.Builder,
+					AppPath:    flags.AppPath,
+					Env:        env,
+					Secrets:    secrets,
+					Bindings:   bindings,
+					CACerts:    flags.CACerts,
+					NoPull:     flags.NoPull,
+					Buildpacks: flags.Buildpacks,
+					Exclude:    flags.Exclude,
+					Include:    flags.Include,
+				},
+				Detect: detect,
+				Stdin:  os.Stdin,
+				Stdout: os.Stdout,
+			})
+		}),
+	}
+	appCommandFlags(cmd, &flags, cfg)
+	cmd.Flags().BoolVar(&detect, "detect", false, "Run detection before opening the shell so /layers/group.toml is in place")
+	AddHelpFlag(cmd, "shell")
+	markCreatesResources(cmd)
+	return cmd
+}
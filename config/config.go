@@ -11,6 +11,8 @@ import (
 type Config struct {
 	RunImages      []RunImage `toml:"run-images"`
 	DefaultBuilder string     `toml:"default-builder-image,omitempty"`
+	GCOnStartup    bool       `toml:"gc-on-startup,omitempty"` // remove resources left behind by killed pack processes before commands that create them
+	CACerts        []string   `toml:"ca-certs,omitempty"`      // PEM files of CA certs trusted by every build phase, along with those given with --ca-cert
 }
 
 type RunImage struct {
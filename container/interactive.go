@@ -0,0 +1,65 @@
+package container
+
+import (
+	"context"
+	"io"
+
+	"github.com/docker/docker/api/types"
+	dcontainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/term"
+	"github.com/pkg/errors"
+)
+
+// RunInteractive starts a container created with a TTY and open stdin, and connects in and out to it until it exits.
+// When in is a terminal it is put in raw mode for the duration and the container TTY is sized to match it.
+func RunInteractive(ctx context.Context, runtime Runtime, ctrID string, in io.Reader, out io.Writer) error {
+	resp, err := runtime.ContainerAttach(ctx, ctrID, types.ContainerAttachOptions{
+		Stream: true,
+		Stdin:  true,
+		Stdout: true,
+		Stderr: true,
+	})
+	if err != nil {
+		return errors.Wrap(err, "container attach")
+	}
+	defer resp.Close()
+
+	bodyChan, errChan := runtime.ContainerWait(ctx, ctrID, dcontainer.WaitConditionNextExit)
+
+	if err := runtime.ContainerStart(ctx, ctrID, types.ContainerStartOptions{}); err != nil {
+		return errors.Wrap(err, "container start")
+	}
+
+	if fd, isTerminal := term.GetFdInfo(in); isTerminal {
+		state, err := term.SetRawTerminal(fd)
+		if err != nil {
+			return errors.Wrap(err, "set raw terminal")
+		}
+		defer term.RestoreTerminal(fd, state)
+
+		if size, err := term.GetWinsize(fd); err == nil {
+			_ = runtime.ContainerResize(ctx, ctrID, types.ResizeOptions{Height: uint(size.Height), Width: uint(size.Width)})
+		}
+	}
+
+	go func() {
+		_, _ = io.Copy(resp.Conn, in)
+		_ = resp.CloseWrite()
+	}()
+	outputDone := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(out, resp.Reader)
+		close(outputDone)
+	}()
+
+	select {
+	case body := <-bodyChan:
+		<-outputDone
+		if body.StatusCode != 0 {
+			return &ExitError{StatusCode: body.StatusCode}
+		}
+	case err := <-errChan:
+		return err
+	}
+	return nil
+}
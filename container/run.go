@@ -7,18 +7,27 @@ import (
 
 	"github.com/docker/docker/api/types"
 	dcontainer "github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/pkg/errors"
 )
 
-func Run(ctx context.Context, docker *client.Client, ctrID string, out, errOut io.Writer) error {
-	bodyChan, errChan := docker.ContainerWait(ctx, ctrID, dcontainer.WaitConditionNextExit)
+// ExitError is returned by Run when the container exits with a non-zero status code
+type ExitError struct {
+	StatusCode int64
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("failed with status code: %d", e.StatusCode)
+}
+
+// Run starts a created container and copies its output to out and errOut until it exits
+func Run(ctx context.Context, runtime Runtime, ctrID string, out, errOut io.Writer) error {
+	bodyChan, errChan := runtime.ContainerWait(ctx, ctrID, dcontainer.WaitConditionNextExit)
 
-	if err := docker.ContainerStart(ctx, ctrID, types.ContainerStartOptions{}); err != nil {
+	if err := runtime.ContainerStart(ctx, ctrID, types.ContainerStartOptions{}); err != nil {
 		return errors.Wrap(err, "container start")
 	}
-	logs, err := docker.ContainerLogs(ctx, ctrID, types.ContainerLogsOptions{
+	logs, err := runtime.ContainerLogs(ctx, ctrID, types.ContainerLogsOptions{
 		ShowStdout: true,
 		ShowStderr: true,
 		Follow:     true,
@@ -36,7 +45,7 @@ func Run(ctx context.Context, docker *client.Client, ctrID string, out, errOut i
 	select {
 	case body := <-bodyChan:
 		if body.StatusCode != 0 {
-			return fmt.Errorf("failed with status code: %d", body.StatusCode)
+			return &ExitError{StatusCode: body.StatusCode}
 		}
 	case err := <-errChan:
 		return err
@@ -0,0 +1,41 @@
+package container
+
+import (
+	"context"
+	"io"
+
+	"github.com/docker/docker/api/types"
+	dcontainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+)
+
+// Runtime creates and runs the containers pack needs, and the volumes they mount. It has the method set of the Docker
+// client, which is the runtime pack uses, so any engine with a Docker compatible API can be plugged in.
+type Runtime interface {
+	ContainerCreate(ctx context.Context, config *dcontainer.Config, hostConfig *dcontainer.HostConfig, networkingConfig *network.NetworkingConfig, containerName string) (dcontainer.ContainerCreateCreatedBody, error)
+	CopyToContainer(ctx context.Context, containerID, dstPath string, content io.Reader, options types.CopyToContainerOptions) error
+	CopyFromContainer(ctx context.Context, containerID, srcPath string) (io.ReadCloser, types.ContainerPathStat, error)
+	ContainerStart(ctx context.Context, containerID string, options types.ContainerStartOptions) error
+	ContainerWait(ctx context.Context, containerID string, condition dcontainer.WaitCondition) (<-chan dcontainer.ContainerWaitOKBody, <-chan error)
+	ContainerLogs(ctx context.Context, containerID string, options types.ContainerLogsOptions) (io.ReadCloser, error)
+	ContainerAttach(ctx context.Context, containerID string, options types.ContainerAttachOptions) (types.HijackedResponse, error)
+	ContainerResize(ctx context.Context, containerID string, options types.ResizeOptions) error
+	ContainerRemove(ctx context.Context, containerID string, options types.ContainerRemoveOptions) error
+
+	VolumeCreate(ctx context.Context, options volume.VolumeCreateBody) (types.Volume, error)
+	VolumeInspect(ctx context.Context, volumeID string) (types.Volume, error)
+	VolumeRemove(ctx context.Context, volumeID string, force bool) error
+
+	// ImageInspectWithRaw returns the config of an image containers are created from
+	ImageInspectWithRaw(ctx context.Context, imageID string) (types.ImageInspect, []byte, error)
+	// ContainerCommit labels an exported image by committing a container of it
+	ContainerCommit(ctx context.Context, containerID string, options types.ContainerCommitOptions) (types.IDResponse, error)
+	// ImageTag gives an exported image an additional tag
+	ImageTag(ctx context.Context, source, target string) error
+	// DaemonHost returns the address containers reach the runtime at, for lifecycle phases that export to it
+	DaemonHost() string
+}
+
+var _ Runtime = (*client.Client)(nil)
@@ -0,0 +1,115 @@
+package pack
+
+import (
+	"context"
+	"io"
+
+	"github.com/pkg/errors"
+
+	"github.com/buildpack/pack/build"
+	"github.com/buildpack/pack/builder"
+)
+
+type DetectOptions struct {
+	Builder     string            // required unless set in the project descriptor
+	AppPath     string            // defaults to current working directory, may contain a project descriptor
+	AppReader   io.Reader         // tar stream of the app, used instead of AppPath when provided
+	Env         map[string]string // build-time environment visible to detection
+	Secrets     map[string]string // files, by ID, mounted read-only at /platform/bindings/<ID>/<file name>
+	Bindings    map[string]string // dirs, by name, mounted read-only at /platform/bindings/<name>
+	CACerts     []string          // PEM files of CA certs trusted by every phase, in addition to those of the builder
+	NoPull      bool
+	Buildpacks  []string
+	Exclude     []string     // gitignore-style patterns for app files to leave out, added to those in .packignore
+	Include     []string     // when set, only app files matching these patterns are used
+	ProxyConfig *ProxyConfig // defaults to  environment proxy vars
+}
+
+// Detect runs only the detect phase against the app and returns the buildpack group that would build it along with
+// the resulting build plan. No image is created and all containers and volumes used are removed afterwards.
+func (c *Client) Detect(ctx context.Context, opts DetectOptions) (*build.DetectResult, error) {
+	lifecycleOpts, cleanup, err := c.prepareLifecycle(ctx, opts)
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+	return c.lifecycle.ExecuteDetect(ctx, lifecycleOpts)
+}
+
+// prepareLifecycle resolves the app and creates an ephemeral builder for running individual phases against it,
+// without anything needed to export an image. The returned cleanup func must be called even on error.
+func (c *Client) prepareLifecycle(ctx context.Context, opts DetectOptions) (build.LifecycleOptions, func(), error) {
+	if err := builder.ValidateEnvFiles(opts.Env); err != nil {
+		return build.LifecycleOptions{}, func() {}, err
+	}
+
+	bindings, err := processBindings(opts.Secrets, opts.Bindings)
+	if err != nil {
+		return build.LifecycleOptions{}, func() {}, err
+	}
+
+	caCerts, err := processCACerts(opts.CACerts)
+	if err != nil {
+		return build.LifecycleOptions{}, func() {}, err
+	}
+
+	appPath, _, cleanup, err := c.resolveAppSource(ctx, opts.AppPath, opts.AppReader)
+	if err != nil {
+		return build.LifecycleOptions{}, cleanup, err
+	}
+
+	buildOpts, err := c.applyProjectDescriptor(appPath, BuildOptions{
+		Builder:    opts.Builder,
+		Env:        opts.Env,
+		Buildpacks: opts.Buildpacks,
+		Exclude:    opts.Exclude,
+		Include:    opts.Include,
+	})
+	if err != nil {
+		return build.LifecycleOptions{}, cleanup, err
+	}
+
+	appExclude, err := c.processAppExclude(appPath, buildOpts.Exclude)
+	if err != nil {
+		return build.LifecycleOptions{}, cleanup, err
+	}
+
+	proxyConfig := c.processProxyConfig(opts.ProxyConfig)
+
+	builderRef, err := c.processBuilderName(buildOpts.Builder)
+	if err != nil {
+		return build.LifecycleOptions{}, cleanup, errors.Wrapf(err, "invalid builder '%s'", buildOpts.Builder)
+	}
+
+	rawBuilderImage, err := c.imageFetcher.Fetch(ctx, builderRef.Name(), true, !opts.NoPull)
+	if err != nil {
+		return build.LifecycleOptions{}, cleanup, errors.Wrapf(err, "failed to fetch builder image '%s'", builderRef.Name())
+	}
+
+	if _, err := c.processBuilderImage(rawBuilderImage); err != nil {
+		return build.LifecycleOptions{}, cleanup, errors.Wrapf(err, "invalid builder '%s'", buildOpts.Builder)
+	}
+
+	extraBuildpacks, group, err := c.processBuildpacks(buildOpts.Buildpacks)
+	if err != nil {
+		return build.LifecycleOptions{}, cleanup, errors.Wrap(err, "invalid buildpack")
+	}
+
+	ephemeralBuilder, err := c.createEphemeralBuilder(ctx, rawBuilderImage, group, extraBuildpacks)
+	if err != nil {
+		return build.LifecycleOptions{}, cleanup, err
+	}
+
+	return build.LifecycleOptions{
+		AppPath:    appPath,
+		AppExclude: appExclude,
+		AppInclude: buildOpts.Include,
+		Builder:    ephemeralBuilder,
+		Env:        buildOpts.Env,
+		Bindings:   bindings,
+		CACerts:    caCerts,
+		HTTPProxy:  proxyConfig.HTTPProxy,
+		HTTPSProxy: proxyConfig.HTTPSProxy,
+		NoProxy:    proxyConfig.NoProxy,
+	}, cleanup, nil
+}
@@ -0,0 +1,150 @@
+package pack
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/buildpack/imgutil/fakes"
+	"github.com/fatih/color"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpack/pack/build"
+	"github.com/buildpack/pack/builder"
+	"github.com/buildpack/pack/buildpack"
+	"github.com/buildpack/pack/internal/mocks"
+	h "github.com/buildpack/pack/testhelpers"
+)
+
+func TestDetect(t *testing.T) {
+	color.NoColor = true
+	spec.Run(t, "Detect and Shell", testDetect, spec.Report(report.Terminal{}))
+}
+
+func testDetect(t *testing.T, when spec.G, it spec.S) {
+	var (
+		subject          *Client
+		fakeImageFetcher *mocks.FakeImageFetcher
+		fakeLifecycle    *mocks.FakeLifecycle
+		builderImage     *fakes.Image
+		tmpDir           string
+		outBuf           bytes.Buffer
+	)
+
+	it.Before(func() {
+		fakeImageFetcher = mocks.NewFakeImageFetcher()
+		fakeLifecycle = &mocks.FakeLifecycle{
+			DetectResult: &build.DetectResult{
+				Group: []buildpack.BuildpackInfo{{ID: "buildpack.id", Version: "buildpack.version"}},
+				Plan:  "[some-dep]\n",
+			},
+		}
+
+		builderImage = mocks.NewFakeBuilderImage(t,
+			"example.com/some/builder:tag",
+			[]builder.BuildpackMetadata{
+				{BuildpackInfo: buildpack.BuildpackInfo{ID: "buildpack.id", Version: "buildpack.version"}, Latest: true},
+			},
+			builder.Config{
+				Stack: builder.StackConfig{ID: "some.stack", RunImage: "some/run"},
+			},
+		)
+		fakeImageFetcher.LocalImages[builderImage.Name()] = builderImage
+
+		var err error
+		tmpDir, err = ioutil.TempDir("", "detect-test")
+		h.AssertNil(t, err)
+
+		logger := mocks.NewMockLogger(&outBuf)
+		subject = &Client{
+			logger:           logger,
+			imageFetcher:     fakeImageFetcher,
+			buildpackFetcher: buildpack.NewFetcher(NewDownloader(logger, tmpDir)),
+			lifecycle:        fakeLifecycle,
+		}
+	})
+
+	it.After(func() {
+		builderImage.Cleanup()
+		os.RemoveAll(tmpDir)
+	})
+
+	when("#Detect", func() {
+		it("runs detection with the env and returns the result", func() {
+			result, err := subject.Detect(context.TODO(), DetectOptions{
+				Builder: "example.com/some/builder:tag",
+				AppPath: filepath.Join("testdata", "some-app"),
+				Env:     map[string]string{"SOME_KEY.override": "some-value"},
+				NoPull:  true,
+			})
+			h.AssertNil(t, err)
+			h.AssertEq(t, result.Group[0].ID, "buildpack.id")
+			h.AssertEq(t, result.Plan, "[some-dep]\n")
+
+			h.AssertEq(t, fakeLifecycle.Opts.Builder.Name(), "example.com/some/builder:tag")
+			h.AssertEq(t, fakeLifecycle.Opts.Env, map[string]string{"SOME_KEY.override": "some-value"})
+			h.AssertEq(t, fakeLifecycle.Opts.Image == nil, true)
+			absAppPath, err := filepath.Abs(filepath.Join("testdata", "some-app"))
+			h.AssertNil(t, err)
+			h.AssertEq(t, fakeLifecycle.Opts.AppPath, absAppPath)
+		})
+
+		it("does not fetch a run image", func() {
+			_, err := subject.Detect(context.TODO(), DetectOptions{
+				Builder: "example.com/some/builder:tag",
+				AppPath: filepath.Join("testdata", "some-app"),
+				NoPull:  true,
+			})
+			h.AssertNil(t, err)
+			_, fetched := fakeImageFetcher.FetchCalls["some/run"]
+			h.AssertEq(t, fetched, false)
+		})
+
+		it("requires a builder", func() {
+			_, err := subject.Detect(context.TODO(), DetectOptions{
+				AppPath: filepath.Join("testdata", "some-app"),
+			})
+			h.AssertError(t, err, "builder is a required parameter")
+		})
+
+		it("fails for an env name that is not a platform env file", func() {
+			_, err := subject.Detect(context.TODO(), DetectOptions{
+				Builder: "example.com/some/builder:tag",
+				AppPath: filepath.Join("testdata", "some-app"),
+				Env:     map[string]string{"../some-file": "some-value"},
+				NoPull:  true,
+			})
+			h.AssertError(t, err, "invalid env var name '../some-file'")
+		})
+	})
+
+	when("#Shell", func() {
+		it("opens a shell with the env", func() {
+			h.AssertNil(t, subject.Shell(context.TODO(), ShellOptions{
+				DetectOptions: DetectOptions{
+					Builder: "example.com/some/builder:tag",
+					AppPath: filepath.Join("testdata", "some-app"),
+					Env:     map[string]string{"SOME_KEY.override": "some-value"},
+					NoPull:  true,
+				},
+				Detect: true,
+				Stdin:  strings.NewReader(""),
+				Stdout: &bytes.Buffer{},
+			}))
+
+			h.AssertEq(t, fakeLifecycle.Opts.Env, map[string]string{"SOME_KEY.override": "some-value"})
+			h.AssertEq(t, fakeLifecycle.ShellDetect, true)
+		})
+
+		it("requires stdin and stdout", func() {
+			h.AssertError(t, subject.Shell(context.TODO(), ShellOptions{
+				DetectOptions: DetectOptions{Builder: "example.com/some/builder:tag"},
+			}), "shell requires stdin and stdout")
+		})
+	})
+}
@@ -0,0 +1,248 @@
+package pack
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/buildpack/imgutil"
+	"github.com/docker/docker/api/types"
+	"github.com/pkg/errors"
+
+	"github.com/buildpack/pack/builder"
+	"github.com/buildpack/pack/buildpack"
+	"github.com/buildpack/pack/internal/lockfile"
+	"github.com/buildpack/pack/internal/resource"
+	"github.com/buildpack/pack/style"
+)
+
+const (
+	// ephemeralBuilderLabel marks builder images pack creates to apply build options to a builder
+	ephemeralBuilderLabel = "io.buildpacks.pack.ephemeral-builder"
+	// ephemeralBuilderCacheSize is the number of ephemeral builders kept for reuse by later builds
+	ephemeralBuilderCacheSize = 5
+)
+
+// createEphemeralBuilder returns a builder with group and buildpacks applied to the builder in rawBuilderImage. When
+// there is nothing to apply the builder is used as is. Otherwise the ephemeral builder is named after its contents,
+// so one created by an earlier build is reused, and only the most recently used ones are kept.
+func (c *Client) createEphemeralBuilder(ctx context.Context, rawBuilderImage imgutil.Image, group builder.OrderEntry, buildpacks []buildpack.Buildpack) (*builder.Builder, error) {
+	origBuilderName := rawBuilderImage.Name()
+	if len(group.Group) == 0 && len(buildpacks) == 0 {
+		c.logger.Debugf("Using builder %s as is", style.Symbol(origBuilderName))
+		return builder.GetBuilder(rawBuilderImage)
+	}
+
+	key, err := ephemeralBuilderKey(rawBuilderImage, group, buildpacks)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid builder %s", style.Symbol(origBuilderName))
+	}
+	name := fmt.Sprintf("pack.local/builder/%s:latest", key)
+
+	if img, err := c.imageFetcher.Fetch(ctx, name, true, false); err == nil {
+		if bldr, err := builder.GetBuilder(img); err == nil {
+			c.logger.Debugf("Reusing ephemeral builder %s", style.Symbol(name))
+			c.touchEphemeralBuilder(ctx, name)
+			return bldr, nil
+		}
+	}
+
+	bldr, err := builder.New(rawBuilderImage, name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid builder %s", style.Symbol(origBuilderName))
+	}
+	for _, bp := range buildpacks {
+		c.logger.Debugf("adding buildpack %s version %s to builder", style.Symbol(bp.ID), style.Symbol(bp.Version))
+		bldr.AddBuildpack(bp)
+	}
+	if len(group.Group) > 0 {
+		c.logger.Debug("setting custom order")
+		bldr.SetOrder([]builder.OrderEntry{group})
+	}
+	labels := resource.Labels(resource.PurposeEphemeralBuilder)
+	labels[ephemeralBuilderLabel] = "true"
+	for k, v := range labels {
+		if err := rawBuilderImage.SetLabel(k, v); err != nil {
+			return nil, errors.Wrap(err, "failed to set ephemeral builder labels")
+		}
+	}
+	if err := bldr.Save(); err != nil {
+		return nil, err
+	}
+	c.touchEphemeralBuilder(ctx, name)
+	return bldr, nil
+}
+
+// touchEphemeralBuilder records that name was used and removes the ephemeral builders no longer in the cache
+func (c *Client) touchEphemeralBuilder(ctx context.Context, name string) {
+	evicted, err := c.ephemeralBuilders.touch(name)
+	if err != nil {
+		c.logger.Debugf("Unable to record use of ephemeral builder %s: %s", style.Symbol(name), err)
+		return
+	}
+	for _, evictedName := range evicted {
+		c.logger.Debugf("Removing ephemeral builder %s", style.Symbol(evictedName))
+		// not forced, so builders in use by the containers of other builds are left for pack gc to collect
+		if _, err := c.docker.ImageRemove(ctx, evictedName, types.ImageRemoveOptions{}); err != nil {
+			c.logger.Debugf("Unable to remove ephemeral builder %s: %s", style.Symbol(evictedName), err)
+		}
+	}
+}
+
+// ephemeralBuilderKey identifies the result of applying group and buildpacks to the builder in rawBuilderImage
+func ephemeralBuilderKey(rawBuilderImage imgutil.Image, group builder.OrderEntry, buildpacks []buildpack.Buildpack) (string, error) {
+	hash := sha256.New()
+
+	digest, err := rawBuilderImage.Digest()
+	if err != nil {
+		return "", err
+	}
+	topLayer, err := rawBuilderImage.TopLayer()
+	if err != nil {
+		return "", err
+	}
+	createdAt, err := rawBuilderImage.CreatedAt()
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(hash, "builder %s %s %s %s\n", rawBuilderImage.Name(), digest, topLayer, createdAt)
+
+	for _, ref := range group.Group {
+		fmt.Fprintf(hash, "group %s %s %t\n", ref.ID, ref.Version, ref.Optional)
+	}
+
+	for _, bp := range buildpacks {
+		fmt.Fprintf(hash, "buildpack %s %s\n", bp.ID, bp.Version)
+		if err := hashDir(hash, bp.Path); err != nil {
+			return "", errors.Wrapf(err, "reading buildpack %s", style.Symbol(bp.ID))
+		}
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil))[:20], nil
+}
+
+// hashDir writes the names, modes and contents of all files in dir to w
+func hashDir(w io.Writer, dir string) error {
+	return filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s %s\n", filepath.ToSlash(relPath), fi.Mode())
+
+		switch {
+		case fi.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "-> %s\n", target)
+		case fi.Mode().IsRegular():
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			if _, err := io.Copy(w, f); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// builderLRU is a list of ephemeral builder names, most recently used first, kept in a file so it is shared between
+// invocations of pack. The file is locked while it is read and written, as they may run at the same time.
+type builderLRU struct {
+	path string
+	size int
+	mu   sync.Mutex
+}
+
+func newBuilderLRU(path string, size int) *builderLRU {
+	return &builderLRU{path: path, size: size}
+}
+
+// touch moves name to the front of the list and returns any names that no longer fit. A nil builderLRU keeps nothing
+// and evicts nothing.
+func (l *builderLRU) touch(name string) ([]string, error) {
+	if l == nil {
+		return nil, nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return nil, err
+	}
+	unlock, err := lockfile.Lock(l.path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	names, err := l.readFile()
+	if err != nil {
+		return nil, err
+	}
+
+	updated := []string{name}
+	for _, n := range names {
+		if n != name {
+			updated = append(updated, n)
+		}
+	}
+
+	var evicted []string
+	if len(updated) > l.size {
+		evicted = updated[l.size:]
+		updated = updated[:l.size]
+	}
+
+	contents, err := json.Marshal(updated)
+	if err != nil {
+		return nil, err
+	}
+	return evicted, ioutil.WriteFile(l.path, contents, 0644)
+}
+
+// names returns the names in the list, most recently used first
+func (l *builderLRU) names() ([]string, error) {
+	if l == nil {
+		return nil, nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	unlock, err := lockfile.Lock(l.path)
+	if os.IsNotExist(err) {
+		// the directory of the file is only created once a name is recorded
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return l.readFile()
+}
+
+func (l *builderLRU) readFile() ([]string, error) {
+	var names []string
+	contents, err := ioutil.ReadFile(l.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(contents) > 0 {
+		if err := json.Unmarshal(contents, &names); err != nil {
+			return nil, errors.Wrapf(err, "parsing %s", style.Symbol(l.path))
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,177 @@
+package pack
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/buildpack/imgutil/fakes"
+	"github.com/docker/docker/client"
+	"github.com/fatih/color"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpack/pack/builder"
+	"github.com/buildpack/pack/buildpack"
+	"github.com/buildpack/pack/internal/mocks"
+	h "github.com/buildpack/pack/testhelpers"
+)
+
+func TestEphemeralBuilder(t *testing.T) {
+	color.NoColor = true
+	spec.Run(t, "EphemeralBuilder", testEphemeralBuilder, spec.Report(report.Terminal{}))
+}
+
+func testEphemeralBuilder(t *testing.T, when spec.G, it spec.S) {
+	var (
+		subject      *Client
+		builderImage *fakes.Image
+		tmpDir       string
+		outBuf       bytes.Buffer
+	)
+
+	it.Before(func() {
+		builderImage = mocks.NewFakeBuilderImage(t,
+			"example.com/some/builder:tag",
+			[]builder.BuildpackMetadata{
+				{BuildpackInfo: buildpack.BuildpackInfo{ID: "buildpack.id", Version: "buildpack.version"}, Latest: true},
+			},
+			builder.Config{
+				Stack: builder.StackConfig{ID: "some.stack", RunImage: "some/run"},
+			},
+		)
+
+		var err error
+		tmpDir, err = ioutil.TempDir("", "ephemeral-builder-test")
+		h.AssertNil(t, err)
+
+		docker, err := client.NewClientWithOpts(client.FromEnv, client.WithVersion("1.38"))
+		h.AssertNil(t, err)
+
+		subject = &Client{
+			logger:            mocks.NewMockLogger(&outBuf),
+			imageFetcher:      mocks.NewFakeImageFetcher(),
+			ephemeralBuilders: newBuilderLRU(filepath.Join(tmpDir, "ephemeral-builders.json"), 2),
+			docker:            docker,
+		}
+	})
+
+	it.After(func() {
+		builderImage.Cleanup()
+		os.RemoveAll(tmpDir)
+	})
+
+	when("#createEphemeralBuilder", func() {
+		it("uses the builder as is when there is nothing to apply", func() {
+			bldr, err := subject.createEphemeralBuilder(context.TODO(), builderImage, builder.OrderEntry{}, nil)
+			h.AssertNil(t, err)
+			h.AssertEq(t, bldr.Name(), "example.com/some/builder:tag")
+			h.AssertEq(t, builderImage.IsSaved(), false)
+		})
+
+		it("names the ephemeral builder after its contents", func() {
+			group := builder.OrderEntry{Group: []builder.BuildpackRef{{BuildpackInfo: buildpack.BuildpackInfo{ID: "buildpack.id"}}}}
+			key, err := ephemeralBuilderKey(builderImage, group, nil)
+			h.AssertNil(t, err)
+
+			bldr, err := subject.createEphemeralBuilder(context.TODO(), builderImage, group, nil)
+			h.AssertNil(t, err)
+			h.AssertEq(t, bldr.Name(), "pack.local/builder/"+key+":latest")
+			h.AssertEq(t, builderImage.IsSaved(), true)
+			label, err := builderImage.Label("io.buildpacks.pack.ephemeral-builder")
+			h.AssertNil(t, err)
+			h.AssertEq(t, label, "true")
+		})
+	})
+
+	when("#ephemeralBuilderKey", func() {
+		it("is the same for the same options", func() {
+			group := builder.OrderEntry{Group: []builder.BuildpackRef{{BuildpackInfo: buildpack.BuildpackInfo{ID: "A", Version: "1"}}}}
+			first, err := ephemeralBuilderKey(builderImage, group, nil)
+			h.AssertNil(t, err)
+			second, err := ephemeralBuilderKey(builderImage, group, nil)
+			h.AssertNil(t, err)
+			h.AssertEq(t, first, second)
+		})
+
+		it("changes with the group", func() {
+			first, err := ephemeralBuilderKey(builderImage, builder.OrderEntry{Group: []builder.BuildpackRef{{BuildpackInfo: buildpack.BuildpackInfo{ID: "A", Version: "1"}}}}, nil)
+			h.AssertNil(t, err)
+			second, err := ephemeralBuilderKey(builderImage, builder.OrderEntry{Group: []builder.BuildpackRef{{BuildpackInfo: buildpack.BuildpackInfo{ID: "A", Version: "2"}}}}, nil)
+			h.AssertNil(t, err)
+			h.AssertNotEq(t, first, second)
+		})
+
+		it("changes with the contents of buildpacks", func() {
+			bpDir := filepath.Join(tmpDir, "buildpack")
+			h.AssertNil(t, os.MkdirAll(bpDir, 0755))
+			h.AssertNil(t, ioutil.WriteFile(filepath.Join(bpDir, "buildpack.toml"), []byte("v1"), 0644))
+			bps := []buildpack.Buildpack{{BuildpackInfo: buildpack.BuildpackInfo{ID: "some.bp", Version: "1.0"}, Path: bpDir}}
+
+			first, err := ephemeralBuilderKey(builderImage, builder.OrderEntry{}, bps)
+			h.AssertNil(t, err)
+			h.AssertNil(t, ioutil.WriteFile(filepath.Join(bpDir, "buildpack.toml"), []byte("v2"), 0644))
+			second, err := ephemeralBuilderKey(builderImage, builder.OrderEntry{}, bps)
+			h.AssertNil(t, err)
+			h.AssertNotEq(t, first, second)
+		})
+	})
+
+	when("builderLRU#touch", func() {
+		it("evicts the least recently used names", func() {
+			lru := newBuilderLRU(filepath.Join(tmpDir, "lru", "builders.json"), 2)
+
+			evicted, err := lru.touch("a")
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(evicted), 0)
+			_, err = lru.touch("b")
+			h.AssertNil(t, err)
+			_, err = lru.touch("a")
+			h.AssertNil(t, err)
+
+			evicted, err = lru.touch("c")
+			h.AssertNil(t, err)
+			h.AssertEq(t, evicted, []string{"b"})
+
+			names, err := lru.names()
+			h.AssertNil(t, err)
+			h.AssertEq(t, names, []string{"c", "a"})
+		})
+
+		it("loses no names when touched by several invocations of pack at once", func() {
+			path := filepath.Join(tmpDir, "lru", "builders.json")
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					_, err := newBuilderLRU(path, 20).touch(fmt.Sprintf("builder-%d", i))
+					h.AssertNil(t, err)
+				}(i)
+			}
+			wg.Wait()
+
+			names, err := newBuilderLRU(path, 20).names()
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(names), 10)
+		})
+
+		it("has no names before any are recorded", func() {
+			names, err := newBuilderLRU(filepath.Join(tmpDir, "missing-dir", "builders.json"), 2).names()
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(names), 0)
+		})
+
+		it("does nothing when nil", func() {
+			var lru *builderLRU
+			evicted, err := lru.touch("a")
+			h.AssertNil(t, err)
+			h.AssertEq(t, len(evicted), 0)
+		})
+	})
+}
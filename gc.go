@@ -0,0 +1,207 @@
+package pack
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"github.com/pkg/errors"
+
+	"github.com/buildpack/pack/internal/resource"
+	"github.com/buildpack/pack/style"
+)
+
+// DefaultGCAge is how long ago a resource must have been created before it is collected, unless told otherwise. Builds
+// rarely take longer, so resources of running builds on other hosts sharing the daemon are left alone.
+const DefaultGCAge = time.Hour
+
+// Kinds of resource pack creates in the daemon
+const (
+	ContainerResource = "container"
+	VolumeResource    = "volume"
+	ImageResource     = "image"
+)
+
+type GCOptions struct {
+	OlderThan time.Duration // only collect resources created at least this long ago
+	DryRun    bool          // list the resources that would be collected without removing them
+	Kept      bool          // also collect resources kept on purpose, such as the volumes of a build kept on failure
+}
+
+// Resource describes a container, volume or image pack created and left behind
+type Resource struct {
+	Kind    string
+	Name    string
+	Purpose string    // empty when created by a version of pack that did not record it
+	Created time.Time // zero when unknown
+}
+
+// CollectGarbage removes the containers, volumes and images left behind by pack processes that were killed, or by
+// daemons restarted mid-build, and returns them. Resources of pack processes still running, resources kept on purpose
+// unless opts.Kept is set, and resources created less than opts.OlderThan ago are left alone. Resources that cannot be
+// removed, such as those in use, are skipped with a warning.
+func (c *Client) CollectGarbage(ctx context.Context, opts GCOptions) ([]Resource, error) {
+	cutoff := time.Now().Add(-opts.OlderThan)
+
+	// containers go first, as kept ones mount kept volumes
+	orphans, err := c.orphanedContainers(ctx, cutoff, opts.Kept)
+	if err != nil {
+		return nil, err
+	}
+	volumes, err := c.orphanedVolumes(ctx, cutoff, opts.Kept)
+	if err != nil {
+		return nil, err
+	}
+	images, err := c.orphanedImages(ctx, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	orphans = append(append(orphans, volumes...), images...)
+
+	if opts.DryRun {
+		return orphans, nil
+	}
+
+	var removed []Resource
+	for _, r := range orphans {
+		if err := c.removeResource(ctx, r); err != nil {
+			c.logger.Warnf("Skipping %s %s: %s", r.Kind, style.Symbol(r.Name), err)
+			continue
+		}
+		removed = append(removed, r)
+	}
+	return removed, nil
+}
+
+func (c *Client) orphanedContainers(ctx context.Context, cutoff time.Time, kept bool) ([]Resource, error) {
+	ctrs, err := c.docker.ContainerList(ctx, types.ContainerListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", resource.AuthorLabel+"=pack")),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing containers")
+	}
+
+	var orphans []Resource
+	for _, ctr := range ctrs {
+		owner := resource.OwnerOf(ctr.Labels)
+		if owner.PID == 0 && ctr.State == "running" {
+			// created by a version of pack that did not record its process, so it may still be in use
+			continue
+		}
+		name := ctr.ID
+		if len(ctr.Names) > 0 {
+			name = strings.TrimPrefix(ctr.Names[0], "/")
+		}
+		if r, ok := orphaned(ContainerResource, name, owner, time.Unix(ctr.Created, 0), cutoff, kept); ok {
+			orphans = append(orphans, r)
+		}
+	}
+	return orphans, nil
+}
+
+func (c *Client) orphanedVolumes(ctx context.Context, cutoff time.Time, kept bool) ([]Resource, error) {
+	list, err := c.docker.VolumeList(ctx, filters.NewArgs())
+	if err != nil {
+		return nil, errors.Wrap(err, "listing volumes")
+	}
+
+	var orphans []Resource
+	for _, v := range list.Volumes {
+		if !hasAnyPrefix(v.Name, "pack-layers-", "pack-app-", "pack-env-", "pack-ca-certs-") {
+			continue
+		}
+		created, _ := time.Parse(time.RFC3339, v.CreatedAt)
+		if r, ok := orphaned(VolumeResource, v.Name, resource.OwnerOf(v.Labels), created, cutoff, kept); ok {
+			orphans = append(orphans, r)
+		}
+	}
+	return orphans, nil
+}
+
+func (c *Client) orphanedImages(ctx context.Context, cutoff time.Time) ([]Resource, error) {
+	images, err := c.docker.ImageList(ctx, types.ImageListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing images")
+	}
+	kept, err := c.ephemeralBuilders.names()
+	if err != nil {
+		// without them every ephemeral builder would look orphaned
+		return nil, errors.Wrap(err, "reading ephemeral builders kept for reuse")
+	}
+	inUse := map[string]bool{}
+	for _, name := range kept {
+		inUse[name] = true
+	}
+
+	var orphans []Resource
+	for _, img := range images {
+		for _, tag := range img.RepoTags {
+			owner := resource.OwnerOf(img.Labels)
+			switch {
+			case strings.HasPrefix(tag, "pack.local/builder/"):
+				if inUse[tag] {
+					continue
+				}
+			case strings.HasPrefix(tag, "pack.local/run/"):
+				// exported by the lifecycle, so it carries the labels of the app rather than of pack
+				owner = resource.Owner{Purpose: resource.PurposeRunImage}
+			default:
+				continue
+			}
+			if r, ok := orphaned(ImageResource, tag, owner, time.Unix(img.Created, 0), cutoff, false); ok {
+				orphans = append(orphans, r)
+			}
+		}
+	}
+	sort.Slice(orphans, func(i, j int) bool { return orphans[i].Name < orphans[j].Name })
+	return orphans, nil
+}
+
+// orphaned returns the resource called name when it was left behind by a pack process before cutoff. created is when
+// the daemon created it, used when pack did not record that itself. Resources kept on purpose are only orphaned when
+// kept is set.
+func orphaned(kind, name string, owner resource.Owner, created, cutoff time.Time, kept bool) (Resource, bool) {
+	if owner.Keep && !kept {
+		return Resource{}, false
+	}
+	if owner.PID != 0 && owner.Alive() {
+		return Resource{}, false
+	}
+	if !owner.Created.IsZero() {
+		created = owner.Created
+	}
+	if created.IsZero() || created.After(cutoff) {
+		return Resource{}, false
+	}
+	return Resource{Kind: kind, Name: name, Purpose: owner.Purpose, Created: created}, true
+}
+
+func (c *Client) removeResource(ctx context.Context, r Resource) error {
+	var err error
+	switch r.Kind {
+	case ContainerResource:
+		err = c.docker.ContainerRemove(ctx, r.Name, types.ContainerRemoveOptions{Force: true})
+	case VolumeResource:
+		err = c.docker.VolumeRemove(ctx, r.Name, false)
+	case ImageResource:
+		_, err = c.docker.ImageRemove(ctx, r.Name, types.ImageRemoveOptions{PruneChildren: true})
+	}
+	if err != nil && !client.IsErrNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func hasAnyPrefix(s string, prefixes ...string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
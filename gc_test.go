@@ -0,0 +1,268 @@
+package pack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	"github.com/fatih/color"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpack/pack/internal/mocks"
+	"github.com/buildpack/pack/internal/resource"
+	h "github.com/buildpack/pack/testhelpers"
+)
+
+func TestGC(t *testing.T) {
+	color.NoColor = true
+	spec.Run(t, "GC", testGC, spec.Report(report.Terminal{}))
+}
+
+func testGC(t *testing.T, when spec.G, it spec.S) {
+	var (
+		now      = time.Now()
+		cutoff   = now.Add(-time.Hour)
+		longAgo  = now.Add(-2 * time.Hour)
+		hostname string
+		deadPID  = 99999999
+	)
+
+	it.Before(func() {
+		var err error
+		hostname, err = os.Hostname()
+		h.AssertNil(t, err)
+	})
+
+	when("#orphaned", func() {
+		it("only returns resources of exited pack processes created before the cutoff", func() {
+			for _, tc := range []struct {
+				desc     string
+				owner    resource.Owner
+				created  time.Time
+				kept     bool
+				orphaned bool
+			}{
+				{desc: "exited process", owner: resource.Owner{PID: deadPID, Host: hostname}, created: longAgo, orphaned: true},
+				{desc: "running process", owner: resource.Owner{PID: os.Getpid(), Host: hostname}, created: longAgo},
+				{desc: "process on another host", owner: resource.Owner{PID: deadPID, Host: "some-other-host"}, created: longAgo},
+				{desc: "unknown process", owner: resource.Owner{}, created: longAgo, orphaned: true},
+				{desc: "created after the cutoff", owner: resource.Owner{PID: deadPID, Host: hostname}, created: now},
+				{desc: "unknown creation time", owner: resource.Owner{PID: deadPID, Host: hostname}},
+				{desc: "recorded creation time after the cutoff", owner: resource.Owner{PID: deadPID, Host: hostname, Created: now}, created: longAgo},
+				{desc: "recorded creation time before the cutoff", owner: resource.Owner{PID: deadPID, Host: hostname, Created: longAgo}, created: now, orphaned: true},
+				{desc: "kept", owner: resource.Owner{PID: deadPID, Host: hostname, Keep: true}, created: longAgo},
+				{desc: "kept when collecting kept resources", owner: resource.Owner{PID: deadPID, Host: hostname, Keep: true}, created: longAgo, kept: true, orphaned: true},
+				{desc: "kept by a running process when collecting kept resources", owner: resource.Owner{PID: os.Getpid(), Host: hostname, Keep: true}, created: longAgo, kept: true},
+			} {
+				_, ok := orphaned(VolumeResource, "some-volume", tc.owner, tc.created, cutoff, tc.kept)
+				if ok != tc.orphaned {
+					t.Errorf("%s: expected orphaned to be %t, got %t", tc.desc, tc.orphaned, ok)
+				}
+			}
+		})
+
+		it("describes the resource", func() {
+			r, ok := orphaned(ContainerResource, "some-container", resource.Owner{PID: deadPID, Host: hostname, Purpose: "phase", Created: longAgo}, now, cutoff, false)
+			h.AssertEq(t, ok, true)
+			h.AssertEq(t, r.Kind, ContainerResource)
+			h.AssertEq(t, r.Name, "some-container")
+			h.AssertEq(t, r.Purpose, "phase")
+			h.AssertEq(t, r.Created.Equal(longAgo), true)
+		})
+	})
+
+	when("#CollectGarbage", func() {
+		var (
+			daemon  *fakeDaemon
+			server  *httptest.Server
+			subject *Client
+			outBuf  bytes.Buffer
+			tmpDir  string
+		)
+
+		labels := func(purpose string, pid int, keep bool) map[string]string {
+			l := resource.Labels(purpose)
+			l[resource.PIDLabel] = strconv.Itoa(pid)
+			l[resource.CreatedLabel] = longAgo.UTC().Format(time.RFC3339)
+			if keep {
+				l[resource.KeepLabel] = "true"
+			}
+			return l
+		}
+
+		it.Before(func() {
+			var err error
+			outBuf.Reset()
+			tmpDir, err = ioutil.TempDir("", "gc-test")
+			h.AssertNil(t, err)
+
+			daemon = &fakeDaemon{
+				containers: []types.Container{
+					{ID: "orphaned-ctr", Names: []string{"/orphaned-ctr"}, Labels: labels(resource.PurposePhase, deadPID, false)},
+					{ID: "running-ctr", Names: []string{"/running-ctr"}, Labels: labels(resource.PurposePhase, os.Getpid(), false)},
+					{ID: "kept-ctr", Names: []string{"/pack-kept-abc-builder"}, Labels: labels(resource.PurposePhase, deadPID, true)},
+				},
+				volumes: []*types.Volume{
+					{Name: "pack-layers-orphaned", Labels: labels(resource.PurposeLayers, deadPID, false)},
+					{Name: "pack-layers-abc", Labels: labels(resource.PurposeLayers, deadPID, true)},
+					{Name: "some-other-volume", Labels: labels(resource.PurposeLayers, deadPID, false)},
+				},
+				images: []types.ImageSummary{
+					{ID: "sha256:builder", RepoTags: []string{"pack.local/builder/orphaned:latest"}, Labels: labels(resource.PurposeEphemeralBuilder, deadPID, false)},
+					{ID: "sha256:in-use", RepoTags: []string{"pack.local/builder/in-use:latest"}, Labels: labels(resource.PurposeEphemeralBuilder, deadPID, false)},
+					{ID: "sha256:app", RepoTags: []string{"some/app:latest"}},
+				},
+				mountedBy: map[string]string{"pack-layers-abc": "kept-ctr"},
+			}
+			server = httptest.NewServer(daemon)
+
+			docker, err := client.NewClientWithOpts(client.WithHost("tcp://"+strings.TrimPrefix(server.URL, "http://")), client.WithVersion("1.38"))
+			h.AssertNil(t, err)
+			lru := newBuilderLRU(filepath.Join(tmpDir, "ephemeral-builders.json"), 2)
+			_, err = lru.touch("pack.local/builder/in-use:latest")
+			h.AssertNil(t, err)
+
+			subject = &Client{
+				docker:            docker,
+				logger:            mocks.NewMockLogger(&outBuf),
+				ephemeralBuilders: lru,
+			}
+		})
+
+		it.After(func() {
+			server.Close()
+			h.AssertNil(t, os.RemoveAll(tmpDir))
+		})
+
+		names := func(resources []Resource) []string {
+			var names []string
+			for _, r := range resources {
+				names = append(names, r.Kind+" "+r.Name)
+			}
+			return names
+		}
+
+		it("removes the resources of exited pack processes", func() {
+			removed, err := subject.CollectGarbage(context.TODO(), GCOptions{OlderThan: time.Hour})
+			h.AssertNil(t, err)
+
+			h.AssertEq(t, names(removed), []string{
+				"container orphaned-ctr",
+				"volume pack-layers-orphaned",
+				"image pack.local/builder/orphaned:latest",
+			})
+			h.AssertEq(t, daemon.removed(), names(removed))
+		})
+
+		it("removes nothing on a dry run", func() {
+			orphans, err := subject.CollectGarbage(context.TODO(), GCOptions{OlderThan: time.Hour, DryRun: true})
+			h.AssertNil(t, err)
+
+			h.AssertEq(t, len(orphans), 3)
+			h.AssertEq(t, len(daemon.removed()), 0)
+		})
+
+		it("removes kept containers before the volumes they mount when collecting kept resources", func() {
+			removed, err := subject.CollectGarbage(context.TODO(), GCOptions{OlderThan: time.Hour, Kept: true})
+			h.AssertNil(t, err)
+
+			h.AssertEq(t, names(removed), []string{
+				"container orphaned-ctr",
+				"container pack-kept-abc-builder",
+				"volume pack-layers-orphaned",
+				"volume pack-layers-abc",
+				"image pack.local/builder/orphaned:latest",
+			})
+		})
+
+		it("skips resources that cannot be removed with a warning", func() {
+			daemon.mountedBy["pack-layers-orphaned"] = "running-ctr"
+
+			removed, err := subject.CollectGarbage(context.TODO(), GCOptions{OlderThan: time.Hour})
+			h.AssertNil(t, err)
+
+			h.AssertEq(t, names(removed), []string{
+				"container orphaned-ctr",
+				"image pack.local/builder/orphaned:latest",
+			})
+			h.AssertContains(t, outBuf.String(), "Skipping volume 'pack-layers-orphaned'")
+		})
+	})
+}
+
+// fakeDaemon serves the parts of the Docker API that CollectGarbage uses. Volumes cannot be removed while the
+// container in mountedBy exists, as with the daemon.
+type fakeDaemon struct {
+	mu         sync.Mutex
+	containers []types.Container
+	volumes    []*types.Volume
+	images     []types.ImageSummary
+	mountedBy  map[string]string // volume name to the ID of the container that mounts it
+	removals   []string
+}
+
+func (d *fakeDaemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1.38")
+	switch {
+	case r.Method == http.MethodGet && path == "/containers/json":
+		json.NewEncoder(w).Encode(d.containers)
+	case r.Method == http.MethodGet && path == "/volumes":
+		json.NewEncoder(w).Encode(volume.VolumeListOKBody{Volumes: d.volumes})
+	case r.Method == http.MethodGet && path == "/images/json":
+		json.NewEncoder(w).Encode(d.images)
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "/containers/"):
+		name := strings.TrimPrefix(path, "/containers/")
+		for i, ctr := range d.containers {
+			if ctr.ID == name || "/"+name == ctr.Names[0] {
+				d.containers = append(d.containers[:i], d.containers[i+1:]...)
+				d.removals = append(d.removals, ContainerResource+" "+name)
+				return
+			}
+		}
+		http.Error(w, `{"message": "no such container"}`, http.StatusNotFound)
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "/volumes/"):
+		name := strings.TrimPrefix(path, "/volumes/")
+		if ctrID, ok := d.mountedBy[name]; ok && d.hasContainer(ctrID) {
+			http.Error(w, `{"message": "volume is in use"}`, http.StatusConflict)
+			return
+		}
+		d.removals = append(d.removals, VolumeResource+" "+name)
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "/images/"):
+		d.removals = append(d.removals, ImageResource+" "+strings.TrimPrefix(path, "/images/"))
+		json.NewEncoder(w).Encode([]types.ImageDeleteResponseItem{})
+	default:
+		http.Error(w, `{"message": "not implemented by fake daemon"}`, http.StatusNotImplemented)
+	}
+}
+
+func (d *fakeDaemon) hasContainer(id string) bool {
+	for _, ctr := range d.containers {
+		if ctr.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *fakeDaemon) removed() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string{}, d.removals...)
+}
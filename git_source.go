@@ -0,0 +1,73 @@
+package pack
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+
+	"github.com/buildpack/pack/internal/git"
+	"github.com/buildpack/pack/style"
+)
+
+const projectMetadataLabel = "io.buildpacks.project.metadata"
+
+type projectMetadata struct {
+	Source *projectSource `json:"source"`
+}
+
+type projectSource struct {
+	Type     string                `json:"type"`
+	Version  projectSourceVersion  `json:"version"`
+	Metadata projectSourceMetadata `json:"metadata"`
+}
+
+type projectSourceVersion struct {
+	Commit string `json:"commit"`
+}
+
+type projectSourceMetadata struct {
+	Repository string   `json:"repository"`
+	Refs       []string `json:"refs,omitempty"`
+}
+
+// checkoutGitSource checks out src into dir and returns the app path within it
+func (c *Client) checkoutGitSource(ctx context.Context, src git.Source, dir string) (string, *projectSource, error) {
+	c.logger.Debugf("Checking out %s from %s", style.Symbol(refOrHead(src.Ref)), style.Symbol(src.URL))
+	commit, err := git.Checkout(ctx, src, dir)
+	if err != nil {
+		return "", nil, err
+	}
+	c.logger.Debugf("Using commit %s", style.Symbol(commit))
+
+	source := &projectSource{
+		Type:     "git",
+		Version:  projectSourceVersion{Commit: commit},
+		Metadata: projectSourceMetadata{Repository: src.URL},
+	}
+	if src.Ref != "" {
+		source.Metadata.Refs = []string{src.Ref}
+	}
+
+	// cleaning the subdir as an absolute path keeps it inside the checkout
+	return filepath.Join(dir, filepath.Clean("/"+src.Subdir)), source, nil
+}
+
+// projectMetadataLabels returns the labels that record the source the app image was built from, none when it was not
+// built from a git source
+func projectMetadataLabels(source *projectSource) (map[string]string, error) {
+	if source == nil {
+		return nil, nil
+	}
+	label, err := json.Marshal(projectMetadata{Source: source})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{projectMetadataLabel: string(label)}, nil
+}
+
+func refOrHead(ref string) string {
+	if ref == "" {
+		return "HEAD"
+	}
+	return ref
+}
@@ -0,0 +1,77 @@
+package pack
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/buildpack/lifecycle/metadata"
+	"github.com/pkg/errors"
+
+	"github.com/buildpack/pack/image"
+	"github.com/buildpack/pack/style"
+)
+
+const buildMetadataLabel = "io.buildpacks.build.metadata"
+
+type ImageInfo struct {
+	StackID    string                       `json:"stack"`
+	Stack      metadata.StackMetadata       `json:"stackMetadata"`
+	RunImage   metadata.RunImageMetadata    `json:"runImage"`
+	Buildpacks []metadata.BuildpackMetadata `json:"buildpacks"`
+	Processes  []ProcessInfo                `json:"processes"`
+}
+
+type ProcessInfo struct {
+	Type    string   `json:"type"`
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+	Direct  bool     `json:"direct"`
+}
+
+type buildMetadata struct {
+	Processes []ProcessInfo `json:"processes"`
+}
+
+func (c *Client) InspectImage(name string, daemon bool) (*ImageInfo, error) {
+	img, err := c.imageFetcher.Fetch(context.Background(), name, daemon, false)
+	if err != nil {
+		if errors.Cause(err) == image.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	label, err := img.Label(metadata.AppMetadataLabel)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get label %s from image %s", style.Symbol(metadata.AppMetadataLabel), style.Symbol(name))
+	} else if label == "" {
+		return nil, errors.Errorf("image %s missing label %s -- was it built by pack?", style.Symbol(name), style.Symbol(metadata.AppMetadataLabel))
+	}
+
+	md, err := metadata.GetAppMetadata(img)
+	if err != nil {
+		return nil, err
+	}
+
+	stackID, err := img.Label("io.buildpacks.stack.id")
+	if err != nil {
+		return nil, err
+	}
+
+	var bmd buildMetadata
+	if label, err := img.Label(buildMetadataLabel); err != nil {
+		return nil, err
+	} else if label != "" {
+		if err := json.Unmarshal([]byte(label), &bmd); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse label %s", style.Symbol(buildMetadataLabel))
+		}
+	}
+
+	return &ImageInfo{
+		StackID:    stackID,
+		Stack:      md.Stack,
+		RunImage:   md.RunImage,
+		Buildpacks: md.Buildpacks,
+		Processes:  bmd.Processes,
+	}, nil
+}
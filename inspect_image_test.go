@@ -0,0 +1,126 @@
+package pack
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/buildpack/imgutil/fakes"
+	"github.com/fatih/color"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpack/pack/image"
+	m "github.com/buildpack/pack/internal/mocks"
+	"github.com/buildpack/pack/mocks"
+	h "github.com/buildpack/pack/testhelpers"
+)
+
+func TestInspectImage(t *testing.T) {
+	color.NoColor = true
+	spec.Run(t, "InspectImage", testInspectImage, spec.Parallel(), spec.Report(report.Terminal{}))
+}
+
+func testInspectImage(t *testing.T, when spec.G, it spec.S) {
+	var (
+		subject          *Client
+		mockImageFetcher *mocks.MockImageFetcher
+		mockController   *gomock.Controller
+		appImage         *fakes.Image
+		out              bytes.Buffer
+	)
+
+	it.Before(func() {
+		mockController = gomock.NewController(t)
+		mockImageFetcher = mocks.NewMockImageFetcher(mockController)
+
+		subject = &Client{
+			logger:       m.NewMockLogger(&out),
+			imageFetcher: mockImageFetcher,
+		}
+
+		appImage = fakes.NewImage("some/app", "", "")
+		h.AssertNil(t, appImage.SetLabel("io.buildpacks.stack.id", "test.stack.id"))
+	})
+
+	it.After(func() {
+		mockController.Finish()
+	})
+
+	when("the image exists", func() {
+		for _, useDaemon := range []bool{true, false} {
+			useDaemon := useDaemon
+			when(fmt.Sprintf("daemon is %t", useDaemon), func() {
+				it.Before(func() {
+					mockImageFetcher.EXPECT().Fetch(gomock.Any(), "some/app", useDaemon, false).Return(appImage, nil)
+				})
+
+				when("the image has lifecycle metadata", func() {
+					it.Before(func() {
+						h.AssertNil(t, appImage.SetLabel("io.buildpacks.lifecycle.metadata", `{
+  "stack": {
+    "runImage": {
+      "image": "some/run-image",
+      "mirrors": ["gcr.io/some/run-image"]
+    }
+  },
+  "runImage": {
+    "topLayer": "some-top-layer",
+    "sha": "some-run-image-sha"
+  },
+  "buildpacks": [
+    {
+      "key": "some.buildpack",
+      "version": "1.2.3",
+      "layers": {
+        "some-layer": {"launch": true, "cache": true}
+      }
+    }
+  ]
+}`))
+						h.AssertNil(t, appImage.SetLabel("io.buildpacks.build.metadata", `{
+  "processes": [{"type": "web", "command": "some-command", "args": ["some", "args"], "direct": true}]
+}`))
+					})
+
+					it("returns the image info", func() {
+						info, err := subject.InspectImage("some/app", useDaemon)
+						h.AssertNil(t, err)
+						h.AssertEq(t, info.StackID, "test.stack.id")
+						h.AssertEq(t, info.Stack.RunImage.Image, "some/run-image")
+						h.AssertEq(t, info.Stack.RunImage.Mirrors, []string{"gcr.io/some/run-image"})
+						h.AssertEq(t, info.RunImage.TopLayer, "some-top-layer")
+						h.AssertEq(t, info.RunImage.SHA, "some-run-image-sha")
+						h.AssertEq(t, len(info.Buildpacks), 1)
+						h.AssertEq(t, info.Buildpacks[0].ID, "some.buildpack")
+						h.AssertEq(t, info.Buildpacks[0].Version, "1.2.3")
+						h.AssertEq(t, info.Buildpacks[0].Layers["some-layer"].Launch, true)
+						h.AssertEq(t, info.Buildpacks[0].Layers["some-layer"].Cache, true)
+						h.AssertEq(t, info.Processes, []ProcessInfo{
+							{Type: "web", Command: "some-command", Args: []string{"some", "args"}, Direct: true},
+						})
+					})
+				})
+
+				when("the image is missing lifecycle metadata", func() {
+					it("returns an error", func() {
+						_, err := subject.InspectImage("some/app", useDaemon)
+						h.AssertError(t, err, "missing label 'io.buildpacks.lifecycle.metadata'")
+					})
+				})
+			})
+		}
+	})
+
+	when("the image does not exist", func() {
+		it("returns nil", func() {
+			mockImageFetcher.EXPECT().Fetch(gomock.Any(), "some/app", true, false).Return(nil, errors.Wrap(image.ErrNotFound, "some-error"))
+
+			info, err := subject.InspectImage("some/app", true)
+			h.AssertNil(t, err)
+			h.AssertNil(t, info)
+		})
+	})
+}
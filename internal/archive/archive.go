@@ -3,13 +3,16 @@ package archive
 import (
 	"archive/tar"
 	"archive/zip"
+	"bufio"
 	"bytes"
 	"compress/gzip"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -21,15 +24,19 @@ func init() {
 	NormalizedDateTime = time.Date(1980, time.January, 1, 0, 0, 1, 0, time.UTC)
 }
 
-func ReadDirAsTar(srcDir, basePath string, uid, gid int, mode int64) io.ReadCloser {
-	return readAsTar(srcDir, basePath, uid, gid, mode, WriteDirToTar)
+func ReadDirAsTar(srcDir, basePath string, uid, gid int, mode int64, filter *Filter) io.ReadCloser {
+	return readAsTar(srcDir, basePath, uid, gid, mode, filter, WriteDirToTar)
 }
 
-func ReadZipAsTar(srcPath, basePath string, uid, gid int, mode int64) io.ReadCloser {
-	return readAsTar(srcPath, basePath, uid, gid, mode, WriteZipToTar)
+func ReadZipAsTar(srcPath, basePath string, uid, gid int, mode int64, filter *Filter) io.ReadCloser {
+	return readAsTar(srcPath, basePath, uid, gid, mode, filter, WriteZipToTar)
 }
 
-func readAsTar(src, basePath string, uid, gid int, mode int64, writeFn func(tw *tar.Writer, srcDir, basePath string, uid, gid int, mode int64) error) io.ReadCloser {
+func ReadTarAsTar(srcPath, basePath string, uid, gid int, mode int64, filter *Filter) io.ReadCloser {
+	return readAsTar(srcPath, basePath, uid, gid, mode, filter, WriteTarToTar)
+}
+
+func readAsTar(src, basePath string, uid, gid int, mode int64, filter *Filter, writeFn func(tw *tar.Writer, srcDir, basePath string, uid, gid int, mode int64, filter *Filter) error) io.ReadCloser {
 	r, w := io.Pipe()
 	go func() {
 		var err error
@@ -45,7 +52,7 @@ func readAsTar(src, basePath string, uid, gid int, mode int64, writeFn func(tw *
 			}
 		}()
 
-		err = writeFn(tw, src, basePath, uid, gid, mode)
+		err = writeFn(tw, src, basePath, uid, gid, mode, filter)
 	}()
 	return r
 }
@@ -143,7 +150,21 @@ func ReadTarEntry(tarPath string, entryPath ...string) (*tar.Header, []byte, err
 		}
 	}
 
-	return nil, nil, fmt.Errorf("could not find entry path '%s' in tar", entryPath)
+	return nil, nil, entryNotExistError(fmt.Sprintf("could not find entry path '%s' in tar", entryPath))
+}
+
+// entryNotExistError is returned by ReadTarEntry when the tar has none of the entries
+type entryNotExistError string
+
+func (e entryNotExistError) Error() string {
+	return string(e)
+}
+
+// IsEntryNotExist reports whether err is returned by ReadTarEntry because the tar has none of the entries, rather
+// than because it could not be read
+func IsEntryNotExist(err error) bool {
+	_, ok := errors.Cause(err).(entryNotExistError)
+	return ok
 }
 
 func contains(slice []string, element string) bool {
@@ -155,7 +176,10 @@ func contains(slice []string, element string) bool {
 	return false
 }
 
-func WriteDirToTar(tw *tar.Writer, srcDir, basePath string, uid, gid int, mode int64) error {
+// WriteDirToTar writes the contents of srcDir to tw under basePath. Paths matched by filter, which may be nil,
+// are left out.
+func WriteDirToTar(tw *tar.Writer, srcDir, basePath string, uid, gid int, mode int64, filter *Filter) error {
+	var pending pendingDirs
 	return filepath.Walk(srcDir, func(file string, fi os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -193,6 +217,31 @@ func WriteDirToTar(tw *tar.Writer, srcDir, basePath string, uid, gid int, mode i
 		header.Name = filepath.ToSlash(filepath.Join(basePath, relPath))
 		finalizeHeader(header, uid, gid, mode)
 
+		relPath = filepath.ToSlash(relPath)
+		if filter.excluded(relPath, fi.IsDir()) {
+			if fi.IsDir() {
+				if err := filter.skipDir(file); err != nil {
+					return err
+				}
+				return filepath.SkipDir
+			}
+			filter.skip(fi.Size())
+			return nil
+		}
+
+		if !filter.included(relPath, fi.IsDir()) {
+			if fi.IsDir() {
+				pending.add(header)
+			} else {
+				filter.skip(fi.Size())
+			}
+			return nil
+		}
+
+		if err := pending.flush(tw, header.Name); err != nil {
+			return err
+		}
+
 		if err := tw.WriteHeader(header); err != nil {
 			return err
 		}
@@ -213,14 +262,26 @@ func WriteDirToTar(tw *tar.Writer, srcDir, basePath string, uid, gid int, mode i
 	})
 }
 
-func WriteZipToTar(tw *tar.Writer, srcZip, basePath string, uid, gid int, mode int64) error {
+// WriteZipToTar writes the contents of srcZip to tw under basePath. Paths matched by filter, which may be nil,
+// are left out.
+func WriteZipToTar(tw *tar.Writer, srcZip, basePath string, uid, gid int, mode int64, filter *Filter) error {
 	zipReader, err := zip.OpenReader(srcZip)
 	if err != nil {
 		return err
 	}
 	defer zipReader.Close()
 
+	var pending pendingDirs
 	for _, f := range zipReader.File {
+		relPath := strings.TrimSuffix(f.Name, "/")
+		isDir := f.FileInfo().IsDir()
+		if filter.excluded(relPath, isDir) {
+			if !isDir {
+				filter.skip(int64(f.UncompressedSize64))
+			}
+			continue
+		}
+
 		var header *tar.Header
 		if f.Mode()&os.ModeSymlink != 0 {
 			target, err := func() (string, error) {
@@ -257,6 +318,19 @@ func WriteZipToTar(tw *tar.Writer, srcZip, basePath string, uid, gid int, mode i
 		header.Name = filepath.ToSlash(filepath.Join(basePath, f.Name))
 		finalizeHeader(header, uid, gid, mode)
 
+		if !filter.included(relPath, isDir) {
+			if isDir {
+				pending.add(header)
+			} else {
+				filter.skip(int64(f.UncompressedSize64))
+			}
+			continue
+		}
+
+		if err := pending.flush(tw, header.Name); err != nil {
+			return err
+		}
+
 		if err := tw.WriteHeader(header); err != nil {
 			return err
 		}
@@ -282,6 +356,102 @@ func WriteZipToTar(tw *tar.Writer, srcZip, basePath string, uid, gid int, mode i
 	return nil
 }
 
+// WriteTarToTar writes the entries of the tar, or gzipped tar, at srcTar to tw under basePath. Paths matched by
+// filter, which may be nil, are left out.
+func WriteTarToTar(tw *tar.Writer, srcTar, basePath string, uid, gid int, mode int64, filter *Filter) error {
+	file, err := os.Open(srcTar)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	r, err := decompress(file)
+	if err != nil {
+		return err
+	}
+
+	var pending pendingDirs
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to get next tar entry")
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir, tar.TypeSymlink, tar.TypeLink:
+		default:
+			if !header.FileInfo().Mode().IsRegular() {
+				continue
+			}
+		}
+
+		relPath := strings.TrimPrefix(path.Clean("/"+header.Name), "/")
+		if relPath == "" {
+			continue
+		}
+
+		isDir := header.Typeflag == tar.TypeDir
+		if filter.excluded(relPath, isDir) {
+			if !isDir {
+				filter.skip(header.Size)
+			}
+			continue
+		}
+
+		header.Name = rerootedPath(basePath, header.Name)
+		if header.Typeflag == tar.TypeLink {
+			header.Linkname = rerootedPath(basePath, header.Linkname)
+		}
+		finalizeHeader(header, uid, gid, mode)
+		header.PAXRecords = nil
+		header.Format = tar.FormatUnknown
+
+		if !filter.included(relPath, isDir) {
+			if isDir {
+				pending.add(header)
+			} else {
+				filter.skip(header.Size)
+			}
+			continue
+		}
+
+		if err := pending.flush(tw, header.Name); err != nil {
+			return err
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if header.FileInfo().Mode().IsRegular() {
+			if _, err := io.Copy(tw, tr); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// rerootedPath returns name from a tar under basePath, without allowing it to escape basePath
+func rerootedPath(basePath, name string) string {
+	return path.Join(basePath, path.Clean("/"+name))
+}
+
+func decompress(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if bytes.Equal(magic, []byte("\x1F\x8B")) {
+		return gzip.NewReader(br)
+	}
+	return br, nil
+}
+
 func finalizeHeader(header *tar.Header, uid, gid int, mode int64) {
 	if mode != -1 {
 		header.Mode = mode
@@ -293,6 +463,28 @@ func finalizeHeader(header *tar.Header, uid, gid int, mode int64) {
 	header.Gname = ""
 }
 
+// IsTar reports whether file is a tar archive, which may be gzipped
+func IsTar(file *os.File) (bool, error) {
+	r, err := decompress(file)
+	if err != nil {
+		if err == gzip.ErrHeader {
+			return false, nil
+		}
+		return false, err
+	}
+
+	header := make([]byte, 512)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return false, nil
+		}
+		return false, err
+	}
+
+	// ustar, pax and gnu formats all have a magic string at offset 257
+	return bytes.Equal(header[257:262], []byte("ustar")), nil
+}
+
 func IsZip(file *os.File) (bool, error) {
 	b := make([]byte, 4)
 	_, err := file.Read(b)
@@ -2,6 +2,7 @@ package archive_test
 
 import (
 	"archive/tar"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"math/rand"
@@ -9,6 +10,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 
@@ -75,6 +77,22 @@ func testArchive(t *testing.T, when spec.G, it spec.S) {
 				h.AssertEq(t, string(bytes), "file-1 content")
 			})
 		})
+
+		when("none of the paths exist", func() {
+			it("returns an entry not exist error", func() {
+				_, _, err := archive.ReadTarEntry(tarFile.Name(), "file2")
+				h.AssertError(t, err, "could not find entry path")
+				h.AssertEq(t, archive.IsEntryNotExist(err), true)
+			})
+		})
+
+		when("the tar cannot be read", func() {
+			it("returns another error", func() {
+				_, _, err := archive.ReadTarEntry(filepath.Join(tmpDir, "missing.tar"), "file1")
+				h.AssertNotNil(t, err)
+				h.AssertEq(t, archive.IsEntryNotExist(err), false)
+			})
+		})
 	})
 
 	when("#WriteDirToTar", func() {
@@ -90,7 +108,7 @@ func testArchive(t *testing.T, when spec.G, it spec.S) {
 
 				tw := tar.NewWriter(fh)
 
-				err = archive.WriteDirToTar(tw, src, "/nested/dir/dir-in-archive", 1234, 2345, 0777)
+				err = archive.WriteDirToTar(tw, src, "/nested/dir/dir-in-archive", 1234, 2345, 0777, nil)
 				h.AssertNil(t, err)
 				h.AssertNil(t, tw.Close())
 				h.AssertNil(t, fh.Close())
@@ -117,7 +135,7 @@ func testArchive(t *testing.T, when spec.G, it spec.S) {
 
 				tw := tar.NewWriter(fh)
 
-				err = archive.WriteDirToTar(tw, src, "/nested/dir/dir-in-archive", 1234, 2345, -1)
+				err = archive.WriteDirToTar(tw, src, "/nested/dir/dir-in-archive", 1234, 2345, -1, nil)
 				h.AssertNil(t, err)
 				h.AssertNil(t, tw.Close())
 				h.AssertNil(t, fh.Close())
@@ -174,7 +192,7 @@ func testArchive(t *testing.T, when spec.G, it spec.S) {
 
 					tw := tar.NewWriter(fh)
 
-					err = archive.WriteDirToTar(tw, tmpSrcDir, "/nested/dir/dir-in-archive", 1234, 2345, 0777)
+					err = archive.WriteDirToTar(tw, tmpSrcDir, "/nested/dir/dir-in-archive", 1234, 2345, 0777, nil)
 					h.AssertNil(t, err)
 					h.AssertNil(t, tw.Close())
 					h.AssertNil(t, fh.Close())
@@ -195,6 +213,60 @@ func testArchive(t *testing.T, when spec.G, it spec.S) {
 				})
 			})
 		})
+		when("a filter is provided", func() {
+			var srcDir string
+
+			it.Before(func() {
+				srcDir = filepath.Join(tmpDir, "app")
+				for path, contents := range map[string]string{
+					"debug.log":                 "some-log",
+					"keep.txt":                  "some-content",
+					"node_modules/pkg/index.js": "some-js",
+					"src/main.go":               "some-go",
+					"src/gen/out.log":           "other-log",
+					"src/important.log":         "important",
+				} {
+					h.AssertNil(t, os.MkdirAll(filepath.Dir(filepath.Join(srcDir, path)), 0755))
+					h.AssertNil(t, ioutil.WriteFile(filepath.Join(srcDir, path), []byte(contents), 0644))
+				}
+			})
+
+			it("leaves out excluded paths", func() {
+				filter, err := archive.NewFilter([]string{"# comment", "*.log", "!important.log", "node_modules/"}, nil)
+				h.AssertNil(t, err)
+
+				h.AssertEq(t, writeDirToTarNames(t, tmpDir, srcDir, filter), []string{
+					"/workspace/keep.txt",
+					"/workspace/src",
+					"/workspace/src/gen",
+					"/workspace/src/important.log",
+					"/workspace/src/main.go",
+				})
+				h.AssertEq(t, filter.SkippedFiles, 3)
+				h.AssertEq(t, filter.SkippedBytes, int64(len("some-log")+len("some-js")+len("other-log")))
+			})
+
+			it("keeps only included paths and their parent dirs", func() {
+				filter, err := archive.NewFilter(nil, []string{"/src/*.go"})
+				h.AssertNil(t, err)
+
+				h.AssertEq(t, writeDirToTarNames(t, tmpDir, srcDir, filter), []string{
+					"/workspace/src",
+					"/workspace/src/main.go",
+				})
+				h.AssertEq(t, filter.SkippedFiles, 5)
+			})
+
+			it("supports ** patterns", func() {
+				filter, err := archive.NewFilter([]string{"src/**/*.log"}, nil)
+				h.AssertNil(t, err)
+
+				names := writeDirToTarNames(t, tmpDir, srcDir, filter)
+				h.AssertContains(t, strings.Join(names, ","), "/workspace/debug.log")
+				h.AssertNotContains(t, strings.Join(names, ","), "/workspace/src/gen/out.log")
+				h.AssertNotContains(t, strings.Join(names, ","), "/workspace/src/important.log")
+			})
+		})
 	})
 
 	when("#WriteZipToTar", func() {
@@ -210,7 +282,7 @@ func testArchive(t *testing.T, when spec.G, it spec.S) {
 
 				tw := tar.NewWriter(fh)
 
-				err = archive.WriteZipToTar(tw, src, "/nested/dir/dir-in-archive", 1234, 2345, 0777)
+				err = archive.WriteZipToTar(tw, src, "/nested/dir/dir-in-archive", 1234, 2345, 0777, nil)
 				h.AssertNil(t, err)
 				h.AssertNil(t, tw.Close())
 				h.AssertNil(t, fh.Close())
@@ -237,7 +309,7 @@ func testArchive(t *testing.T, when spec.G, it spec.S) {
 
 				tw := tar.NewWriter(fh)
 
-				err = archive.WriteZipToTar(tw, src, "/nested/dir/dir-in-archive", 1234, 2345, -1)
+				err = archive.WriteZipToTar(tw, src, "/nested/dir/dir-in-archive", 1234, 2345, -1, nil)
 				h.AssertNil(t, err)
 				h.AssertNil(t, tw.Close())
 				h.AssertNil(t, fh.Close())
@@ -256,7 +328,141 @@ func testArchive(t *testing.T, when spec.G, it spec.S) {
 				}
 			})
 		})
+
+		when("a filter is provided", func() {
+			it("leaves out excluded paths", func() {
+				filter, err := archive.NewFilter([]string{"sub-dir/"}, nil)
+				h.AssertNil(t, err)
+
+				fh, err := os.Create(filepath.Join(tmpDir, "some.tar"))
+				h.AssertNil(t, err)
+
+				tw := tar.NewWriter(fh)
+
+				err = archive.WriteZipToTar(tw, src, "/nested/dir/dir-in-archive", 1234, 2345, -1, filter)
+				h.AssertNil(t, err)
+				h.AssertNil(t, tw.Close())
+				h.AssertNil(t, fh.Close())
+
+				file, err := os.Open(filepath.Join(tmpDir, "some.tar"))
+				h.AssertNil(t, err)
+				defer file.Close()
+
+				tr := tar.NewReader(file)
+
+				verify := tarVerifier{t, tr, 1234, 2345}
+				verify.nextFile("/nested/dir/dir-in-archive/some-file.txt", "some-content", 0644)
+				_, err = tr.Next()
+				h.AssertEq(t, err == io.EOF, true)
+				h.AssertEq(t, filter.SkippedFiles, 1)
+			})
+		})
 	})
+	when("#WriteTarToTar", func() {
+		var src string
+		it.Before(func() {
+			src = filepath.Join("testdata", "tar-to-tar.tar.gz")
+		})
+
+		it("re-roots the entries under the base path", func() {
+			fh, err := os.Create(filepath.Join(tmpDir, "some.tar"))
+			h.AssertNil(t, err)
+
+			tw := tar.NewWriter(fh)
+
+			err = archive.WriteTarToTar(tw, src, "/nested/dir/dir-in-archive", 1234, 2345, 0777, nil)
+			h.AssertNil(t, err)
+			h.AssertNil(t, tw.Close())
+			h.AssertNil(t, fh.Close())
+
+			file, err := os.Open(filepath.Join(tmpDir, "some.tar"))
+			h.AssertNil(t, err)
+			defer file.Close()
+
+			tr := tar.NewReader(file)
+
+			verify := tarVerifier{t, tr, 1234, 2345}
+			verify.nextFile("/nested/dir/dir-in-archive/some-file.txt", "some-content", 0777)
+			verify.nextDirectory("/nested/dir/dir-in-archive/sub-dir", 0777)
+			verify.nextSymLink("/nested/dir/dir-in-archive/sub-dir/link-file", "../some-file.txt")
+		})
+
+		when("a filter is provided", func() {
+			it("leaves out excluded paths", func() {
+				filter, err := archive.NewFilter([]string{"sub-dir"}, nil)
+				h.AssertNil(t, err)
+
+				fh, err := os.Create(filepath.Join(tmpDir, "some.tar"))
+				h.AssertNil(t, err)
+
+				tw := tar.NewWriter(fh)
+
+				err = archive.WriteTarToTar(tw, src, "/workspace", 1234, 2345, -1, filter)
+				h.AssertNil(t, err)
+				h.AssertNil(t, tw.Close())
+				h.AssertNil(t, fh.Close())
+
+				file, err := os.Open(filepath.Join(tmpDir, "some.tar"))
+				h.AssertNil(t, err)
+				defer file.Close()
+
+				tr := tar.NewReader(file)
+
+				verify := tarVerifier{t, tr, 1234, 2345}
+				verify.nextFile("/workspace/some-file.txt", "some-content", 0664)
+				_, err = tr.Next()
+				h.AssertEq(t, err == io.EOF, true)
+				h.AssertEq(t, filter.SkippedFiles, 1)
+			})
+		})
+	})
+
+	when("#IsTar", func() {
+		for desc, testData := range map[string][]interface{}{
+			"gzipped tar": {filepath.Join("testdata", "tar-to-tar.tar.gz"), true},
+			"zip":         {filepath.Join("testdata", "zip-to-tar.zip"), false},
+			"text file":   {filepath.Join("testdata", "dir-to-tar", "some-file.txt"), false},
+		} {
+			path := testData[0].(string)
+			expected := testData[1].(bool)
+
+			it(fmt.Sprintf("returns %t for a %s", expected, desc), func() {
+				file, err := os.Open(path)
+				h.AssertNil(t, err)
+				defer file.Close()
+
+				isTar, err := archive.IsTar(file)
+				h.AssertNil(t, err)
+				h.AssertEq(t, isTar, expected)
+			})
+		}
+	})
+}
+
+func writeDirToTarNames(t *testing.T, tmpDir, srcDir string, filter *archive.Filter) []string {
+	t.Helper()
+	fh, err := os.Create(filepath.Join(tmpDir, "filtered.tar"))
+	h.AssertNil(t, err)
+	defer fh.Close()
+
+	tw := tar.NewWriter(fh)
+	h.AssertNil(t, archive.WriteDirToTar(tw, srcDir, "/workspace", 1234, 2345, -1, filter))
+	h.AssertNil(t, tw.Close())
+
+	_, err = fh.Seek(0, io.SeekStart)
+	h.AssertNil(t, err)
+
+	var names []string
+	tr := tar.NewReader(fh)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		h.AssertNil(t, err)
+		names = append(names, header.Name)
+	}
+	return names
 }
 
 func fileMode(t *testing.T, path string) int64 {
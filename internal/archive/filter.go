@@ -0,0 +1,219 @@
+package archive
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Filter leaves paths out of an archive using gitignore-style patterns. Paths are matched relative to the
+// root of the archive source. A nil Filter includes everything.
+type Filter struct {
+	exclude []pattern
+	include []pattern
+
+	SkippedFiles int
+	SkippedBytes int64
+}
+
+type pattern struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// NewFilter creates a filter from exclude and include patterns. Later exclude patterns take precedence and may
+// be negated with a leading `!`. When include patterns are provided only paths matching one of them, or inside a
+// matching directory, are kept. Blank lines and lines starting with `#` are ignored.
+func NewFilter(exclude, include []string) (*Filter, error) {
+	f := &Filter{}
+	var err error
+	if f.exclude, err = compilePatterns(exclude); err != nil {
+		return nil, err
+	}
+	if f.include, err = compilePatterns(include); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func compilePatterns(lines []string) ([]pattern, error) {
+	var patterns []pattern
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p, err := compilePattern(line)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid pattern '%s'", line)
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, nil
+}
+
+func compilePattern(glob string) (pattern, error) {
+	var p pattern
+	if strings.HasPrefix(glob, "!") {
+		p.negate = true
+		glob = glob[1:]
+	} else if strings.HasPrefix(glob, `\!`) || strings.HasPrefix(glob, `\#`) {
+		glob = glob[1:]
+	}
+
+	if strings.HasSuffix(glob, "/") {
+		p.dirOnly = true
+		glob = strings.TrimRight(glob, "/")
+	}
+
+	// patterns without a slash match at any depth, others are relative to the root
+	anchored := strings.Contains(glob, "/")
+	expr := globToRegexp(strings.TrimPrefix(glob, "/"))
+	if !anchored {
+		expr = "(.*/)?" + expr
+	}
+
+	var err error
+	p.re, err = regexp.Compile("^" + expr + "$")
+	return p, err
+}
+
+func globToRegexp(glob string) string {
+	var buf strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				if i+2 < len(glob) && glob[i+2] == '/' {
+					buf.WriteString("(.*/)?")
+					i += 2
+				} else {
+					buf.WriteString(".*")
+					i++
+				}
+			} else {
+				buf.WriteString("[^/]*")
+			}
+		case '?':
+			buf.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				buf.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			buf.WriteString("[" + class + "]")
+			i += end
+		case '\\':
+			if i+1 < len(glob) {
+				buf.WriteString(regexp.QuoteMeta(string(glob[i+1])))
+				i++
+			}
+		default:
+			buf.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return buf.String()
+}
+
+// excluded reports whether path, or one of its parent directories, matches the exclude patterns
+func (f *Filter) excluded(path string, isDir bool) bool {
+	if f == nil || len(f.exclude) == 0 {
+		return false
+	}
+
+	parts := strings.Split(path, "/")
+	for i := range parts {
+		if matchLast(f.exclude, strings.Join(parts[:i+1], "/"), isDir || i < len(parts)-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// included reports whether path, or one of its parent directories, matches the include patterns
+func (f *Filter) included(path string, isDir bool) bool {
+	if f == nil || len(f.include) == 0 {
+		return true
+	}
+
+	parts := strings.Split(path, "/")
+	for i := range parts {
+		if matchLast(f.include, strings.Join(parts[:i+1], "/"), isDir || i < len(parts)-1) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchLast(patterns []pattern, path string, isDir bool) bool {
+	matched := false
+	for _, p := range patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if p.re.MatchString(path) {
+			matched = !p.negate
+		}
+	}
+	return matched
+}
+
+func (f *Filter) skip(size int64) {
+	if f == nil {
+		return
+	}
+	f.SkippedFiles++
+	f.SkippedBytes += size
+}
+
+func (f *Filter) skipDir(dir string) error {
+	if f == nil {
+		return nil
+	}
+	return filepath.Walk(dir, func(_ string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			f.skip(fi.Size())
+		}
+		return nil
+	})
+}
+
+// pendingDirs holds directory headers that are only written once an entry inside them is kept
+type pendingDirs struct {
+	headers []*tar.Header
+}
+
+func (p *pendingDirs) add(header *tar.Header) {
+	p.trim(header.Name)
+	p.headers = append(p.headers, header)
+}
+
+func (p *pendingDirs) flush(tw *tar.Writer, name string) error {
+	p.trim(name)
+	for _, header := range p.headers {
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+	}
+	p.headers = nil
+	return nil
+}
+
+func (p *pendingDirs) trim(name string) {
+	for len(p.headers) > 0 && !strings.HasPrefix(name, p.headers[len(p.headers)-1].Name+"/") {
+		p.headers = p.headers[:len(p.headers)-1]
+	}
+}
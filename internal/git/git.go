@@ -0,0 +1,101 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Source is a git repository to build an app from
+type Source struct {
+	URL    string
+	Ref    string // defaults to the repository's HEAD
+	Subdir string // app dir relative to the root of the repository
+}
+
+var sourcePrefixes = []string{"git+", "git://", "ssh://", "http://", "https://"}
+
+// ParseSource parses an app path of the form `<repository-url>[#<ref>[:<subdir>]]`. The URL must use the
+// `git`, `ssh`, `http` or `https` scheme, or be prefixed with `git+` (for example `git+file:///some/repo`).
+// ok is false when path does not refer to a git repository.
+func ParseSource(path string) (src Source, ok bool) {
+	for _, prefix := range sourcePrefixes {
+		if strings.HasPrefix(path, prefix) {
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		return Source{}, false
+	}
+
+	path = strings.TrimPrefix(path, "git+")
+	if i := strings.LastIndex(path, "#"); i >= 0 {
+		path, src.Ref = path[:i], path[i+1:]
+		if j := strings.Index(src.Ref, ":"); j >= 0 {
+			src.Ref, src.Subdir = src.Ref[:j], src.Ref[j+1:]
+		}
+	}
+	src.URL = path
+	return src, true
+}
+
+// Checkout clones src into dir and checks out src.Ref, returning the commit SHA. The repository metadata is
+// removed afterwards, leaving only the files tracked at that commit.
+func Checkout(ctx context.Context, src Source, dir string) (string, error) {
+	if _, err := run(ctx, "", "clone", "--quiet", "--no-checkout", src.URL, dir); err != nil {
+		return "", err
+	}
+
+	commit, err := resolveRef(ctx, dir, src.Ref)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := run(ctx, dir, "checkout", "--quiet", "--detach", commit); err != nil {
+		return "", err
+	}
+
+	if err := os.RemoveAll(filepath.Join(dir, ".git")); err != nil {
+		return "", errors.Wrap(err, "removing repository metadata")
+	}
+
+	return commit, nil
+}
+
+func resolveRef(ctx context.Context, dir, ref string) (string, error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	// branches only exist as remote-tracking branches in a fresh clone
+	for _, candidate := range []string{ref, "origin/" + ref} {
+		out, err := run(ctx, dir, "rev-parse", "--verify", "--quiet", candidate+"^{commit}")
+		if err == nil {
+			return out, nil
+		}
+	}
+	return "", errors.Errorf("ref '%s' not found", ref)
+}
+
+func run(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", errors.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", errors.Wrapf(err, "git %s", args[0])
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,150 @@
+package git_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpack/pack/internal/git"
+	h "github.com/buildpack/pack/testhelpers"
+)
+
+func TestGit(t *testing.T) {
+	spec.Run(t, "Git", testGit, spec.Parallel(), spec.Report(report.Terminal{}))
+}
+
+func testGit(t *testing.T, when spec.G, it spec.S) {
+	when("#ParseSource", func() {
+		it("parses a url", func() {
+			src, ok := git.ParseSource("https://github.com/some/repo.git")
+			h.AssertEq(t, ok, true)
+			h.AssertEq(t, src, git.Source{URL: "https://github.com/some/repo.git"})
+		})
+
+		it("parses a url with a ref", func() {
+			src, ok := git.ParseSource("git://example.com/some/repo#v1.2.3")
+			h.AssertEq(t, ok, true)
+			h.AssertEq(t, src, git.Source{URL: "git://example.com/some/repo", Ref: "v1.2.3"})
+		})
+
+		it("parses a url with a ref and subdir", func() {
+			src, ok := git.ParseSource("git+file:///some/repo#main:apps/web")
+			h.AssertEq(t, ok, true)
+			h.AssertEq(t, src, git.Source{URL: "file:///some/repo", Ref: "main", Subdir: "apps/web"})
+		})
+
+		it("parses a url with only a subdir", func() {
+			src, ok := git.ParseSource("git+ssh://git@example.com/some/repo#:apps/web")
+			h.AssertEq(t, ok, true)
+			h.AssertEq(t, src, git.Source{URL: "ssh://git@example.com/some/repo", Subdir: "apps/web"})
+		})
+
+		it("ignores paths", func() {
+			for _, path := range []string{"", "some/dir", "/some/dir", "app.zip", "file:///some/dir"} {
+				_, ok := git.ParseSource(path)
+				h.AssertEq(t, ok, false)
+			}
+		})
+	})
+
+	when("#Checkout", func() {
+		var (
+			repoDir, checkoutDir string
+			firstCommit          string
+		)
+
+		gitCmd := func(args ...string) string {
+			t.Helper()
+			cmd := exec.Command("git", append([]string{"-c", "user.name=pack", "-c", "user.email=pack@example.com"}, args...)...)
+			cmd.Dir = repoDir
+			out, err := cmd.CombinedOutput()
+			h.AssertNil(t, err)
+			return string(out)
+		}
+
+		it.Before(func() {
+			if _, err := exec.LookPath("git"); err != nil {
+				t.Skip("git not installed")
+			}
+
+			var err error
+			repoDir, err = ioutil.TempDir("", "git-test-repo")
+			h.AssertNil(t, err)
+			checkoutDir, err = ioutil.TempDir("", "git-test-checkout")
+			h.AssertNil(t, err)
+			checkoutDir = filepath.Join(checkoutDir, "app")
+
+			gitCmd("init", "--quiet")
+			h.AssertNil(t, ioutil.WriteFile(filepath.Join(repoDir, "file.txt"), []byte("first"), 0644))
+			gitCmd("add", ".")
+			gitCmd("commit", "--quiet", "-m", "first")
+			gitCmd("tag", "v1")
+			firstCommit = gitCmd("rev-parse", "HEAD")[:40]
+
+			gitCmd("checkout", "--quiet", "-b", "feature")
+			h.AssertNil(t, ioutil.WriteFile(filepath.Join(repoDir, "file.txt"), []byte("feature"), 0644))
+			gitCmd("commit", "--quiet", "-am", "feature")
+			gitCmd("checkout", "--quiet", "-")
+
+			h.AssertNil(t, ioutil.WriteFile(filepath.Join(repoDir, "file.txt"), []byte("uncommitted"), 0644))
+			h.AssertNil(t, ioutil.WriteFile(filepath.Join(repoDir, "untracked.txt"), []byte("untracked"), 0644))
+		})
+
+		it.After(func() {
+			os.RemoveAll(repoDir)
+			os.RemoveAll(filepath.Dir(checkoutDir))
+		})
+
+		it("checks out a clean tree at HEAD", func() {
+			commit, err := git.Checkout(context.TODO(), git.Source{URL: repoDir}, checkoutDir)
+			h.AssertNil(t, err)
+			h.AssertEq(t, commit, firstCommit)
+
+			contents, err := ioutil.ReadFile(filepath.Join(checkoutDir, "file.txt"))
+			h.AssertNil(t, err)
+			h.AssertEq(t, string(contents), "first")
+
+			_, err = os.Stat(filepath.Join(checkoutDir, "untracked.txt"))
+			h.AssertEq(t, os.IsNotExist(err), true)
+			_, err = os.Stat(filepath.Join(checkoutDir, ".git"))
+			h.AssertEq(t, os.IsNotExist(err), true)
+		})
+
+		it("checks out a branch", func() {
+			_, err := git.Checkout(context.TODO(), git.Source{URL: repoDir, Ref: "feature"}, checkoutDir)
+			h.AssertNil(t, err)
+
+			contents, err := ioutil.ReadFile(filepath.Join(checkoutDir, "file.txt"))
+			h.AssertNil(t, err)
+			h.AssertEq(t, string(contents), "feature")
+		})
+
+		it("checks out a tag", func() {
+			commit, err := git.Checkout(context.TODO(), git.Source{URL: repoDir, Ref: "v1"}, checkoutDir)
+			h.AssertNil(t, err)
+			h.AssertEq(t, commit, firstCommit)
+		})
+
+		it("checks out a commit", func() {
+			commit, err := git.Checkout(context.TODO(), git.Source{URL: repoDir, Ref: firstCommit[:10]}, checkoutDir)
+			h.AssertNil(t, err)
+			h.AssertEq(t, commit, firstCommit)
+		})
+
+		it("fails for an unknown ref", func() {
+			_, err := git.Checkout(context.TODO(), git.Source{URL: repoDir, Ref: "missing"}, checkoutDir)
+			h.AssertError(t, err, "ref 'missing' not found")
+		})
+
+		it("fails for an unknown repository", func() {
+			_, err := git.Checkout(context.TODO(), git.Source{URL: filepath.Join(repoDir, "missing")}, checkoutDir)
+			h.AssertError(t, err, "git clone:")
+		})
+	})
+}
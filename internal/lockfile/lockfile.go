@@ -0,0 +1,44 @@
+// Package lockfile serialises access to files shared between invocations of pack, using a lock file next to them.
+// Only os.OpenFile with O_EXCL is used, so it works the same on every platform.
+package lockfile
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	// retryInterval is how often a held lock is tried again
+	retryInterval = 10 * time.Millisecond
+	// timeout is how long a held lock is waited for before giving up
+	timeout = 10 * time.Second
+	// staleAge is how old a lock file must be before it is assumed to be left behind by a killed process. Locks are
+	// only held for a read and a write of a small file.
+	staleAge = 30 * time.Second
+)
+
+// Lock takes the lock for path, waiting while another process holds it, and returns the function that releases it
+func Lock(path string) (func(), error) {
+	lockPath := path + ".lock"
+	deadline := time.Now().Add(timeout)
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		if fi, err := os.Stat(lockPath); err == nil && time.Since(fi.ModTime()) > staleAge {
+			os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for lock %s", lockPath)
+		}
+		time.Sleep(retryInterval)
+	}
+}
@@ -0,0 +1,77 @@
+package lockfile_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpack/pack/internal/lockfile"
+	h "github.com/buildpack/pack/testhelpers"
+)
+
+func TestLockfile(t *testing.T) {
+	spec.Run(t, "Lockfile", testLockfile, spec.Report(report.Terminal{}))
+}
+
+func testLockfile(t *testing.T, when spec.G, it spec.S) {
+	var (
+		tmpDir string
+		path   string
+	)
+
+	it.Before(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "lockfile-test")
+		h.AssertNil(t, err)
+		path = filepath.Join(tmpDir, "some-file.json")
+	})
+
+	it.After(func() {
+		h.AssertNil(t, os.RemoveAll(tmpDir))
+	})
+
+	when("#Lock", func() {
+		it("waits until the lock is released", func() {
+			unlock, err := lockfile.Lock(path)
+			h.AssertNil(t, err)
+
+			locked := make(chan struct{})
+			go func() {
+				unlockAgain, err := lockfile.Lock(path)
+				h.AssertNil(t, err)
+				close(locked)
+				unlockAgain()
+			}()
+
+			select {
+			case <-locked:
+				t.Fatal("expected the lock to be held")
+			case <-time.After(100 * time.Millisecond):
+			}
+			unlock()
+			select {
+			case <-locked:
+			case <-time.After(5 * time.Second):
+				t.Fatal("expected the lock to be taken once released")
+			}
+		})
+
+		it("takes over a lock left behind by a killed process", func() {
+			h.AssertNil(t, ioutil.WriteFile(path+".lock", nil, 0644))
+			old := time.Now().Add(-time.Minute)
+			h.AssertNil(t, os.Chtimes(path+".lock", old, old))
+
+			unlock, err := lockfile.Lock(path)
+			h.AssertNil(t, err)
+			unlock()
+
+			_, err = os.Stat(path + ".lock")
+			h.AssertEq(t, os.IsNotExist(err), true)
+		})
+	})
+}
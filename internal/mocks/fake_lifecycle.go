@@ -2,15 +2,29 @@ package mocks
 
 import (
 	"context"
+	"io"
 
 	"github.com/buildpack/pack/build"
 )
 
 type FakeLifecycle struct {
-	Opts build.LifecycleOptions
+	Opts         build.LifecycleOptions
+	DetectResult *build.DetectResult
+	ShellDetect  bool
 }
 
 func (f *FakeLifecycle) Execute(ctx context.Context, opts build.LifecycleOptions) error {
 	f.Opts = opts
 	return nil
 }
+
+func (f *FakeLifecycle) ExecuteDetect(ctx context.Context, opts build.LifecycleOptions) (*build.DetectResult, error) {
+	f.Opts = opts
+	return f.DetectResult, nil
+}
+
+func (f *FakeLifecycle) ExecuteShell(ctx context.Context, opts build.LifecycleOptions, detect bool, in io.Reader, out io.Writer) error {
+	f.Opts = opts
+	f.ShellDetect = detect
+	return nil
+}
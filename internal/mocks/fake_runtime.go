@@ -0,0 +1,370 @@
+package mocks
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	dcontainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// FakeRuntime is an in-memory container.Runtime. Started containers do nothing and exit with status 0 unless OnStart
+// is set, which plays the part of the process in them. No lifecycle binary is run, so unlike the phase tests, which run
+// build/testdata/fake-lifecycle in Docker, tests of Lifecycle.Execute stub each phase with OnStart. Like the daemon,
+// it refuses to remove a volume a container that has not been removed still mounts.
+type FakeRuntime struct {
+	OnStart    func(ctr *FakeContainer) // called when a container starts, may set its output, exit code and files
+	Host       string
+	ImageFiles map[string][]byte // files every container is created with, by absolute path
+
+	mu         sync.Mutex
+	containers []*FakeContainer
+	volumes    map[string]types.Volume
+	images     map[string]types.ImageInspect
+}
+
+// FakeContainer is a container created in a FakeRuntime
+type FakeContainer struct {
+	ID         string
+	Name       string
+	Config     *dcontainer.Config
+	HostConfig *dcontainer.HostConfig
+	Files      map[string][]byte // files copied into or written in the container, by absolute path
+	Stdout     string
+	Stderr     string
+	ExitCode   int64
+	Started    bool
+	Removed    bool
+
+	wait chan dcontainer.ContainerWaitOKBody
+}
+
+func NewFakeRuntime() *FakeRuntime {
+	return &FakeRuntime{
+		Host:       "tcp://127.0.0.1:2375",
+		ImageFiles: map[string][]byte{},
+		volumes:    map[string]types.Volume{},
+		images:     map[string]types.ImageInspect{},
+	}
+}
+
+// AddImage makes an image called name available to inspect
+func (r *FakeRuntime) AddImage(name string, inspect types.ImageInspect) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.images[name] = inspect
+}
+
+// Containers returns all containers created, in order, including removed ones
+func (r *FakeRuntime) Containers() []*FakeContainer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*FakeContainer{}, r.containers...)
+}
+
+// Volumes returns the names of the volumes that exist, sorted
+func (r *FakeRuntime) Volumes() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var names []string
+	for name := range r.volumes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *FakeRuntime) ContainerCreate(ctx context.Context, config *dcontainer.Config, hostConfig *dcontainer.HostConfig, networkingConfig *network.NetworkingConfig, containerName string) (dcontainer.ContainerCreateCreatedBody, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if containerName != "" {
+		for _, ctr := range r.containers {
+			if ctr.Name == containerName && !ctr.Removed {
+				return dcontainer.ContainerCreateCreatedBody{}, conflictError(fmt.Sprintf("container name %s is already in use by container %s", containerName, ctr.ID))
+			}
+		}
+	}
+	ctr := &FakeContainer{
+		ID:         fmt.Sprintf("fake-container-%d", len(r.containers)+1),
+		Name:       containerName,
+		Config:     config,
+		HostConfig: hostConfig,
+		Files:      map[string][]byte{},
+		wait:       make(chan dcontainer.ContainerWaitOKBody, 1),
+	}
+	for p, contents := range r.ImageFiles {
+		ctr.Files[p] = contents
+	}
+	r.containers = append(r.containers, ctr)
+	return dcontainer.ContainerCreateCreatedBody{ID: ctr.ID}, nil
+}
+
+func (r *FakeRuntime) CopyToContainer(ctx context.Context, containerID, dstPath string, content io.Reader, options types.CopyToContainerOptions) error {
+	ctr, err := r.container(containerID)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(content)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		contents, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		r.mu.Lock()
+		ctr.Files[path.Join(dstPath, header.Name)] = contents
+		r.mu.Unlock()
+	}
+}
+
+func (r *FakeRuntime) CopyFromContainer(ctx context.Context, containerID, srcPath string) (io.ReadCloser, types.ContainerPathStat, error) {
+	ctr, err := r.container(containerID)
+	if err != nil {
+		return nil, types.ContainerPathStat{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var paths []string
+	for p := range ctr.Files {
+		if p == srcPath || strings.HasPrefix(p, strings.TrimSuffix(srcPath, "/")+"/") {
+			paths = append(paths, p)
+		}
+	}
+	if len(paths) == 0 {
+		return nil, types.ContainerPathStat{}, notFoundError(fmt.Sprintf("no such file %s in container %s", srcPath, containerID))
+	}
+	sort.Strings(paths)
+
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for _, p := range paths {
+		name := path.Join(path.Base(srcPath), strings.TrimPrefix(p, srcPath))
+		if err := tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(ctr.Files[p]))}); err != nil {
+			return nil, types.ContainerPathStat{}, err
+		}
+		if _, err := tw.Write(ctr.Files[p]); err != nil {
+			return nil, types.ContainerPathStat{}, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, types.ContainerPathStat{}, err
+	}
+	return ioutil.NopCloser(buf), types.ContainerPathStat{Name: path.Base(srcPath)}, nil
+}
+
+func (r *FakeRuntime) ContainerStart(ctx context.Context, containerID string, options types.ContainerStartOptions) error {
+	ctr, err := r.container(containerID)
+	if err != nil {
+		return err
+	}
+	ctr.Started = true
+	if r.OnStart != nil {
+		r.OnStart(ctr)
+	}
+	ctr.wait <- dcontainer.ContainerWaitOKBody{StatusCode: ctr.ExitCode}
+	return nil
+}
+
+func (r *FakeRuntime) ContainerWait(ctx context.Context, containerID string, condition dcontainer.WaitCondition) (<-chan dcontainer.ContainerWaitOKBody, <-chan error) {
+	errChan := make(chan error, 1)
+	ctr, err := r.container(containerID)
+	if err != nil {
+		errChan <- err
+		return nil, errChan
+	}
+	return ctr.wait, errChan
+}
+
+func (r *FakeRuntime) ContainerLogs(ctx context.Context, containerID string, options types.ContainerLogsOptions) (io.ReadCloser, error) {
+	ctr, err := r.container(containerID)
+	if err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	if _, err := stdcopy.NewStdWriter(buf, stdcopy.Stdout).Write([]byte(ctr.Stdout)); err != nil {
+		return nil, err
+	}
+	if _, err := stdcopy.NewStdWriter(buf, stdcopy.Stderr).Write([]byte(ctr.Stderr)); err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(buf), nil
+}
+
+func (r *FakeRuntime) ContainerAttach(ctx context.Context, containerID string, options types.ContainerAttachOptions) (types.HijackedResponse, error) {
+	return types.HijackedResponse{}, fmt.Errorf("attaching to containers is not supported by the fake runtime")
+}
+
+func (r *FakeRuntime) ContainerResize(ctx context.Context, containerID string, options types.ResizeOptions) error {
+	return nil
+}
+
+func (r *FakeRuntime) ContainerRemove(ctx context.Context, containerID string, options types.ContainerRemoveOptions) error {
+	ctr, err := r.container(containerID)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ctr.Removed = true
+	return nil
+}
+
+func (r *FakeRuntime) VolumeCreate(ctx context.Context, options volume.VolumeCreateBody) (types.Volume, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if v, ok := r.volumes[options.Name]; ok {
+		return v, nil
+	}
+	v := types.Volume{
+		Name:      options.Name,
+		Driver:    "local",
+		Labels:    options.Labels,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	r.volumes[options.Name] = v
+	return v, nil
+}
+
+func (r *FakeRuntime) VolumeInspect(ctx context.Context, volumeID string) (types.Volume, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v, ok := r.volumes[volumeID]
+	if !ok {
+		return types.Volume{}, notFoundError("no such volume: " + volumeID)
+	}
+	return v, nil
+}
+
+func (r *FakeRuntime) VolumeRemove(ctx context.Context, volumeID string, force bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.volumes[volumeID]; !ok && !force {
+		return notFoundError("no such volume: " + volumeID)
+	}
+	// like the daemon, force does not remove a volume that a container still mounts
+	for _, ctr := range r.containers {
+		if !ctr.Removed && ctr.mounts(volumeID) {
+			return conflictError(fmt.Sprintf("remove %s: volume is in use - [%s]", volumeID, ctr.ID))
+		}
+	}
+	delete(r.volumes, volumeID)
+	return nil
+}
+
+func (r *FakeRuntime) ImageInspectWithRaw(ctx context.Context, imageID string) (types.ImageInspect, []byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	inspect, ok := r.images[imageID]
+	if !ok {
+		return types.ImageInspect{}, nil, notFoundError("no such image: " + imageID)
+	}
+	return inspect, nil, nil
+}
+
+// ContainerCommit makes an image of the container available to inspect as the reference, with the image the container
+// was created from as its parent and the labels of both
+func (r *FakeRuntime) ContainerCommit(ctx context.Context, containerID string, options types.ContainerCommitOptions) (types.IDResponse, error) {
+	ctr, err := r.container(containerID)
+	if err != nil {
+		return types.IDResponse{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	parent := r.images[ctr.Config.Image]
+	commit := types.ImageInspect{ID: "sha256:committed-" + ctr.ID, Parent: parent.ID, Config: &dcontainer.Config{Labels: map[string]string{}}}
+	if parent.Config != nil {
+		for k, v := range parent.Config.Labels {
+			commit.Config.Labels[k] = v
+		}
+	}
+	if options.Config != nil {
+		for k, v := range options.Config.Labels {
+			commit.Config.Labels[k] = v
+		}
+	}
+	r.images[options.Reference] = commit
+	return types.IDResponse{ID: commit.ID}, nil
+}
+
+// ImageTag makes the image source available to inspect as target too
+func (r *FakeRuntime) ImageTag(ctx context.Context, source, target string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	inspect, ok := r.images[source]
+	if !ok {
+		return notFoundError("no such image: " + source)
+	}
+	r.images[target] = inspect
+	return nil
+}
+
+func (r *FakeRuntime) DaemonHost() string {
+	return r.Host
+}
+
+func (r *FakeRuntime) container(id string) (*FakeContainer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, ctr := range r.containers {
+		if (ctr.ID == id || (ctr.Name != "" && ctr.Name == id)) && !ctr.Removed {
+			return ctr, nil
+		}
+	}
+	return nil, notFoundError("no such container: " + id)
+}
+
+// mounts returns whether a bind of the container mounts the volume called name
+func (c *FakeContainer) mounts(name string) bool {
+	if c.HostConfig == nil {
+		return false
+	}
+	for _, bind := range c.HostConfig.Binds {
+		if strings.SplitN(bind, ":", 2)[0] == name {
+			return true
+		}
+	}
+	return false
+}
+
+// conflictError is returned when an operation conflicts with the state of the runtime, as the Docker client does
+type conflictError string
+
+func (e conflictError) Error() string {
+	return string(e)
+}
+
+func (e conflictError) Conflict() {}
+
+// notFoundError is recognised by client.IsErrNotFound, as the errors of the Docker client are
+type notFoundError string
+
+func (e notFoundError) Error() string {
+	return string(e)
+}
+
+func (e notFoundError) NotFound() bool {
+	return true
+}
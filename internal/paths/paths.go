@@ -27,6 +27,23 @@ func FilePathToUri(path string) (string, error) {
 	}
 }
 
+// ResolveRelativeUri returns uri as an absolute file URI when it is a relative path, resolving it against
+// relativeTo. URIs with a scheme and absolute paths are returned unchanged.
+func ResolveRelativeUri(uri, relativeTo string) (string, error) {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+
+	if parsed.Scheme == "" {
+		if !filepath.IsAbs(parsed.Path) {
+			return FilePathToUri(filepath.Join(relativeTo, parsed.Path))
+		}
+	}
+
+	return uri, nil
+}
+
 // examples:
 //
 // - unix file: file://laptop/some%20dir/file.tgz
@@ -34,7 +51,6 @@ func FilePathToUri(path string) (string, error) {
 // - windows drive: file:///C:/Documents%20and%20Settings/file.tgz
 //
 // - windows share: file://laptop/My%20Documents/file.tgz
-//
 func UriToFilePath(uri string) (string, error) {
 	var (
 		osPath = uri
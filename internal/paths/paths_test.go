@@ -132,4 +132,27 @@ func testPaths(t *testing.T, when spec.G, it spec.S) {
 			})
 		})
 	})
+	when("#ResolveRelativeUri", func() {
+		it.Before(func() {
+			h.SkipIf(t, runtime.GOOS == "windows", "Skipped on windows")
+		})
+
+		it("resolves relative paths against the given dir", func() {
+			uri, err := ResolveRelativeUri("some/bp", "/some/dir")
+			h.AssertNil(t, err)
+			h.AssertEq(t, uri, "file:///some/dir/some/bp")
+		})
+
+		it("returns absolute paths unchanged", func() {
+			uri, err := ResolveRelativeUri("/other/bp", "/some/dir")
+			h.AssertNil(t, err)
+			h.AssertEq(t, uri, "/other/bp")
+		})
+
+		it("returns uris with a scheme unchanged", func() {
+			uri, err := ResolveRelativeUri("https://example.com/bp.tgz", "/some/dir")
+			h.AssertNil(t, err)
+			h.AssertEq(t, uri, "https://example.com/bp.tgz")
+		})
+	})
 }
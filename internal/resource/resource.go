@@ -0,0 +1,96 @@
+package resource
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Labels pack sets on the containers, volumes and images it creates, so that ones left behind when pack is killed can
+// be found and removed
+const (
+	AuthorLabel  = "author" // set to "pack", the only label set by earlier versions of pack
+	PIDLabel     = "io.buildpacks.pack.pid"
+	HostLabel    = "io.buildpacks.pack.host"
+	CreatedLabel = "io.buildpacks.pack.created"
+	PurposeLabel = "io.buildpacks.pack.purpose"
+	KeepLabel    = "io.buildpacks.pack.keep" // set on resources kept on purpose, such as the volumes of a failed build
+)
+
+// Purposes of the resources pack creates
+const (
+	PurposeLayers           = "layers"            // lifecycle layers volume
+	PurposeApp              = "app"               // lifecycle app volume
+	PurposeEnv              = "env"               // lifecycle build-time env volume
+	PurposeCACerts          = "ca-certs"          // lifecycle CA certs volume
+	PurposePhase            = "phase"             // lifecycle phase container
+	PurposeAppContainer     = "app-container"     // container running an app built by pack run
+	PurposeEphemeralBuilder = "ephemeral-builder" // builder with build options applied
+	PurposeRunImage         = "pack-run"          // app image built by pack run
+	PurposeCacheHelper      = "cache-helper"      // container giving access to a cache volume
+	PurposeCache            = "cache"             // build or launch cache volume, kept between builds
+)
+
+// Labels returns the labels for a resource created now by this process for purpose
+func Labels(purpose string) map[string]string {
+	host, _ := os.Hostname()
+	return map[string]string{
+		AuthorLabel:  "pack",
+		PIDLabel:     strconv.Itoa(os.Getpid()),
+		HostLabel:    host,
+		CreatedLabel: time.Now().UTC().Format(time.RFC3339),
+		PurposeLabel: purpose,
+	}
+}
+
+// Owner describes the process that created a resource, as far as its labels record it
+type Owner struct {
+	PID     int // zero when unknown
+	Host    string
+	Created time.Time // zero when unknown
+	Purpose string
+	Keep    bool
+}
+
+// OwnerOf reads the owner of a resource from its labels
+func OwnerOf(labels map[string]string) Owner {
+	owner := Owner{
+		Host:    labels[HostLabel],
+		Purpose: labels[PurposeLabel],
+		Keep:    labels[KeepLabel] == "true",
+	}
+	owner.PID, _ = strconv.Atoi(labels[PIDLabel])
+	owner.Created, _ = time.Parse(time.RFC3339, labels[CreatedLabel])
+	return owner
+}
+
+// Alive reports whether the process that created the resource may still be running. Processes on other hosts, and
+// unknown processes, are assumed to be.
+func (o Owner) Alive() bool {
+	if o.PID == 0 {
+		return true
+	}
+	if host, err := os.Hostname(); err != nil || host != o.Host {
+		return true
+	}
+	return processRunning(o.PID)
+}
+
+func processRunning(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		// finding a process on windows fails unless it exists
+		return true
+	}
+	err = p.Signal(syscall.Signal(0))
+	if sysErr, ok := err.(*os.SyscallError); ok {
+		err = sysErr.Err
+	}
+	// a process owned by another user cannot be signalled but is running
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,77 @@
+package resource_test
+
+import (
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpack/pack/internal/resource"
+	h "github.com/buildpack/pack/testhelpers"
+)
+
+func TestResource(t *testing.T) {
+	spec.Run(t, "Resource", testResource, spec.Report(report.Terminal{}))
+}
+
+func testResource(t *testing.T, when spec.G, it spec.S) {
+	when("#Labels", func() {
+		it("records this process as the owner", func() {
+			owner := resource.OwnerOf(resource.Labels(resource.PurposeLayers))
+
+			h.AssertEq(t, owner.PID, os.Getpid())
+			h.AssertEq(t, owner.Purpose, resource.PurposeLayers)
+			h.AssertEq(t, owner.Keep, false)
+			if owner.Created.IsZero() {
+				t.Fatal("expected creation time to be recorded")
+			}
+		})
+
+		it("keeps the label set by earlier versions of pack", func() {
+			h.AssertEq(t, resource.Labels(resource.PurposePhase)[resource.AuthorLabel], "pack")
+		})
+	})
+
+	when("#OwnerOf", func() {
+		it("returns an unknown owner for unlabelled resources", func() {
+			owner := resource.OwnerOf(map[string]string{"author": "pack"})
+
+			h.AssertEq(t, owner.PID, 0)
+			h.AssertEq(t, owner.Created.IsZero(), true)
+		})
+
+		it("reads the keep label", func() {
+			h.AssertEq(t, resource.OwnerOf(map[string]string{resource.KeepLabel: "true"}).Keep, true)
+		})
+	})
+
+	when("Owner#Alive", func() {
+		var labels map[string]string
+
+		it.Before(func() {
+			labels = resource.Labels(resource.PurposeApp)
+		})
+
+		it("is true for this process", func() {
+			h.AssertEq(t, resource.OwnerOf(labels).Alive(), true)
+		})
+
+		it("is false for a process that is not running", func() {
+			labels[resource.PIDLabel] = strconv.Itoa(1 << 22)
+			h.AssertEq(t, resource.OwnerOf(labels).Alive(), false)
+		})
+
+		it("is true for a process on another host", func() {
+			labels[resource.PIDLabel] = strconv.Itoa(1 << 22)
+			labels[resource.HostLabel] = "some-other-host"
+			h.AssertEq(t, resource.OwnerOf(labels).Alive(), true)
+		})
+
+		it("is true for an unknown process", func() {
+			delete(labels, resource.PIDLabel)
+			h.AssertEq(t, resource.OwnerOf(labels).Alive(), true)
+		})
+	})
+}
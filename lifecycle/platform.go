@@ -0,0 +1,125 @@
+package lifecycle
+
+import (
+	"github.com/Masterminds/semver"
+)
+
+// Platform is how pack drives the lifecycles in a range of versions. It owns everything that differs between them:
+// the arguments their phases take, how their caches are kept and the files they read from builder images. Supporting
+// a new lifecycle version means adding a Platform to platforms.
+type Platform interface {
+	// BuildCache is how the build cache is kept in the daemon
+	BuildCache() CacheMode
+	// LaunchCache reports whether the exporter can reuse app layers from a launch cache volume
+	LaunchCache() bool
+	// SkipLayersArgs returns the analyzer args that make it ignore the layers of the previous image when the cache is
+	// cleared, or false when the analyzer cannot ignore them and is skipped instead
+	SkipLayersArgs() ([]string, bool)
+	// Layout describes the files the lifecycle reads from builder images
+	Layout() Layout
+}
+
+// CacheMode is how a build cache is kept in the daemon
+type CacheMode int
+
+const (
+	ImageCacheMode  CacheMode = iota // in an image, passed to the restorer and cacher with -image
+	VolumeCacheMode                  // in a volume, mounted for the restorer and cacher at -path
+)
+
+// OrderFormat is the format of the order.toml on builder images
+type OrderFormat int
+
+const (
+	OrderGroups OrderFormat = iota // [[groups]] of buildpacks
+	OrderOrder                     // [[order]] of groups of buildpacks, some optional
+)
+
+// Layout describes the files a lifecycle reads from builder images
+type Layout struct {
+	OrderFormat    OrderFormat
+	LatestSymlinks bool // buildpacks marked latest are also linked under /buildpacks as 'latest'
+}
+
+// platforms are the supported platforms with the first lifecycle version each drives, oldest first
+var platforms = []struct {
+	since    *semver.Version
+	platform Platform
+}{
+	{semver.MustParse("0.0.0"), platform01{}},
+	{semver.MustParse("0.2.0"), platform02{}},
+	{semver.MustParse("0.3.0"), platform03{}},
+	{semver.MustParse("0.4.0"), platform04{}},
+}
+
+// PlatformFor returns the platform that drives the lifecycle at version. Lifecycles of unknown version are driven by
+// platformUnknown.
+func PlatformFor(version *semver.Version) Platform {
+	if version == nil {
+		return platformUnknown{}
+	}
+	for i := len(platforms) - 1; i > 0; i-- {
+		if !version.LessThan(platforms[i].since) {
+			return platforms[i].platform
+		}
+	}
+	return platforms[0].platform
+}
+
+// platform01 drives lifecycles before 0.2.0, which keep the build cache in an image
+type platform01 struct{}
+
+func (platform01) BuildCache() CacheMode {
+	return ImageCacheMode
+}
+
+func (platform01) LaunchCache() bool {
+	return false
+}
+
+func (platform01) SkipLayersArgs() ([]string, bool) {
+	return nil, false
+}
+
+func (platform01) Layout() Layout {
+	return Layout{OrderFormat: OrderGroups, LatestSymlinks: true}
+}
+
+// platform02 drives lifecycle 0.2.x, which keeps caches in volumes
+type platform02 struct{ platform01 }
+
+func (platform02) BuildCache() CacheMode {
+	return VolumeCacheMode
+}
+
+func (platform02) LaunchCache() bool {
+	return true
+}
+
+// platform03 drives lifecycle 0.3.x, whose analyzer can ignore the layers of the previous image
+type platform03 struct{ platform02 }
+
+func (platform03) SkipLayersArgs() ([]string, bool) {
+	return []string{"-skip-layers"}, true
+}
+
+// platform04 drives lifecycles from 0.4.0, which read groups of optional buildpacks from order.toml and resolve
+// buildpack versions themselves
+type platform04 struct{ platform03 }
+
+func (platform04) Layout() Layout {
+	return Layout{OrderFormat: OrderOrder}
+}
+
+// platformUnknown drives lifecycles of unknown version, as pack did before it had platforms. The build cache is kept
+// in an image, which every lifecycle can restore from, while the analyzer args and the layout are those of the latest
+// platform.
+type platformUnknown struct{ platform04 }
+
+func (platformUnknown) BuildCache() CacheMode {
+	return ImageCacheMode
+}
+
+func (platformUnknown) LaunchCache() bool {
+	return false
+}
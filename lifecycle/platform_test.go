@@ -0,0 +1,70 @@
+package lifecycle_test
+
+import (
+	"testing"
+
+	"github.com/Masterminds/semver"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpack/pack/lifecycle"
+	h "github.com/buildpack/pack/testhelpers"
+)
+
+func TestPlatform(t *testing.T) {
+	spec.Run(t, "Platform", testPlatform, spec.Parallel(), spec.Report(report.Terminal{}))
+}
+
+func testPlatform(t *testing.T, when spec.G, it spec.S) {
+	when("#PlatformFor", func() {
+		it("keeps the build cache in an image before 0.2.0", func() {
+			platform := lifecycle.PlatformFor(semver.MustParse("0.1.9"))
+			h.AssertEq(t, platform.BuildCache(), lifecycle.ImageCacheMode)
+			h.AssertEq(t, platform.LaunchCache(), false)
+		})
+
+		it("keeps caches in volumes from 0.2.0", func() {
+			platform := lifecycle.PlatformFor(semver.MustParse("0.2.0"))
+			h.AssertEq(t, platform.BuildCache(), lifecycle.VolumeCacheMode)
+			h.AssertEq(t, platform.LaunchCache(), true)
+		})
+
+		it("skips the analyzer when clearing the cache before 0.3.0", func() {
+			_, ok := lifecycle.PlatformFor(semver.MustParse("0.2.9")).SkipLayersArgs()
+			h.AssertEq(t, ok, false)
+		})
+
+		it("passes -skip-layers to the analyzer from 0.3.0", func() {
+			args, ok := lifecycle.PlatformFor(semver.MustParse("0.3.0")).SkipLayersArgs()
+			h.AssertEq(t, ok, true)
+			h.AssertEq(t, args, []string{"-skip-layers"})
+		})
+
+		it("writes groups and latest symlinks before 0.4.0", func() {
+			h.AssertEq(t, lifecycle.PlatformFor(semver.MustParse("0.3.9")).Layout(), lifecycle.Layout{
+				OrderFormat:    lifecycle.OrderGroups,
+				LatestSymlinks: true,
+			})
+		})
+
+		it("treats prereleases as the version before", func() {
+			h.AssertEq(t, lifecycle.PlatformFor(semver.MustParse("0.4.0-rc.1")).Layout().OrderFormat, lifecycle.OrderGroups)
+		})
+
+		it("writes order from 0.4.0", func() {
+			h.AssertEq(t, lifecycle.PlatformFor(semver.MustParse("0.4.0")).Layout(), lifecycle.Layout{
+				OrderFormat: lifecycle.OrderOrder,
+			})
+		})
+
+		it("keeps the build cache of unknown versions in an image, as before platforms, and is otherwise the latest", func() {
+			platform := lifecycle.PlatformFor(nil)
+			h.AssertEq(t, platform.BuildCache(), lifecycle.ImageCacheMode)
+			h.AssertEq(t, platform.LaunchCache(), false)
+			args, ok := platform.SkipLayersArgs()
+			h.AssertEq(t, ok, true)
+			h.AssertEq(t, args, []string{"-skip-layers"})
+			h.AssertEq(t, platform.Layout(), lifecycle.Layout{OrderFormat: lifecycle.OrderOrder})
+		})
+	})
+}
@@ -0,0 +1,68 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/buildpack/pack/style"
+)
+
+// NewPrefixLogger returns a logger that prefixes all messages and writer output of l, so that the output of
+// concurrent operations can be told apart
+func NewPrefixLogger(l Logger, prefix string) Logger {
+	return &prefixLogger{
+		logger: l,
+		name:   prefix,
+		prefix: fmt.Sprintf("[%s] ", style.Prefix(prefix)),
+	}
+}
+
+type prefixLogger struct {
+	logger Logger
+	name   string
+	prefix string
+}
+
+func (l *prefixLogger) Debug(msg string) {
+	l.logger.Debug(l.prefix + msg)
+}
+
+func (l *prefixLogger) Debugf(format string, v ...interface{}) {
+	l.logger.Debug(l.prefix + fmt.Sprintf(format, v...))
+}
+
+func (l *prefixLogger) Info(msg string) {
+	l.logger.Info(l.prefix + msg)
+}
+
+func (l *prefixLogger) Infof(format string, v ...interface{}) {
+	l.logger.Info(l.prefix + fmt.Sprintf(format, v...))
+}
+
+func (l *prefixLogger) Warn(msg string) {
+	l.logger.Warn(l.prefix + msg)
+}
+
+func (l *prefixLogger) Warnf(format string, v ...interface{}) {
+	l.logger.Warn(l.prefix + fmt.Sprintf(format, v...))
+}
+
+func (l *prefixLogger) Error(msg string) {
+	l.logger.Error(l.prefix + msg)
+}
+
+func (l *prefixLogger) Errorf(format string, v ...interface{}) {
+	l.logger.Error(l.prefix + fmt.Sprintf(format, v...))
+}
+
+func (l *prefixLogger) Writer() io.Writer {
+	return NewPrefixWriter(l.logger.Writer(), l.name)
+}
+
+func (l *prefixLogger) DebugWriter() io.Writer {
+	return NewPrefixWriter(GetDebugWriter(l.logger), l.name)
+}
+
+func (l *prefixLogger) DebugErrorWriter() io.Writer {
+	return NewPrefixWriter(GetDebugErrorWriter(l.logger), l.name)
+}
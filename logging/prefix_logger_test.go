@@ -0,0 +1,39 @@
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/sclevine/spec"
+
+	h "github.com/buildpack/pack/testhelpers"
+)
+
+func TestPrefixLogger(t *testing.T) {
+	color.NoColor = true
+	spec.Run(t, "PrefixLogger", func(t *testing.T, when spec.G, it spec.S) {
+		var w bytes.Buffer
+		var logger Logger
+
+		it.Before(func() {
+			logger = NewPrefixLogger(New(&w), "some-app")
+		})
+
+		it.After(func() {
+			w.Reset()
+		})
+
+		it("should prefix messages", func() {
+			logger.Infof("test%s", "foo")
+			h.AssertContains(t, w.String(), "INFO:   [some-app] testfoo")
+		})
+
+		it("should prefix writer output", func() {
+			_, err := fmt.Fprint(GetDebugWriter(logger), "some output")
+			h.AssertNil(t, err)
+			h.AssertContains(t, w.String(), "[some-app] some output")
+		})
+	})
+}
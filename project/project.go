@@ -0,0 +1,121 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+
+	"github.com/buildpack/pack/builder"
+	"github.com/buildpack/pack/internal/paths"
+	"github.com/buildpack/pack/style"
+)
+
+// DescriptorFile is the name of the project descriptor looked up in the root of an app directory
+const DescriptorFile = "project.toml"
+
+type Descriptor struct {
+	Build BuildConfig `toml:"build"`
+}
+
+type BuildConfig struct {
+	Builder    string            `toml:"builder"`
+	RunImage   string            `toml:"run-image"`
+	Buildpacks []BuildpackConfig `toml:"buildpacks"`
+	Env        []EnvVar          `toml:"env"`
+	Include    []string          `toml:"include"`
+	Exclude    []string          `toml:"exclude"`
+}
+
+type BuildpackConfig struct {
+	ID      string `toml:"id"`
+	Version string `toml:"version"`
+	URI     string `toml:"uri"`
+}
+
+type EnvVar struct {
+	Name  string `toml:"name"`
+	Value string `toml:"value"`
+}
+
+// ReadDescriptor reads the project descriptor from appDir. An empty descriptor is returned if appDir
+// does not contain one.
+func ReadDescriptor(appDir string) (Descriptor, error) {
+	path := filepath.Join(appDir, DescriptorFile)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return Descriptor{}, nil
+	}
+
+	var descriptor Descriptor
+	if _, err := toml.DecodeFile(path, &descriptor); err != nil {
+		return Descriptor{}, errors.Wrapf(err, "parse contents of '%s'", path)
+	}
+
+	if err := descriptor.resolve(appDir); err != nil {
+		return Descriptor{}, errors.Wrapf(err, "invalid project descriptor '%s'", path)
+	}
+
+	return descriptor, nil
+}
+
+// resolve validates the descriptor and resolves relative buildpack paths using `relativeToDir`
+func (d *Descriptor) resolve(relativeToDir string) error {
+	if len(d.Build.Include) > 0 && len(d.Build.Exclude) > 0 {
+		return fmt.Errorf("%s and %s cannot both be set", style.Symbol("include"), style.Symbol("exclude"))
+	}
+
+	for i, bp := range d.Build.Buildpacks {
+		switch {
+		case bp.URI != "":
+			uri, err := paths.ResolveRelativeUri(bp.URI, relativeToDir)
+			if err != nil {
+				return errors.Wrap(err, "transforming buildpack URI")
+			}
+			d.Build.Buildpacks[i].URI = uri
+		case bp.ID == "":
+			return fmt.Errorf("buildpack %d must have an %s or %s", i+1, style.Symbol("id"), style.Symbol("uri"))
+		}
+	}
+
+	for i, env := range d.Build.Env {
+		if env.Name == "" {
+			return fmt.Errorf("env var %d is missing a %s", i+1, style.Symbol("name"))
+		}
+		if _, err := builder.EnvFile(env.Name, builder.EnvOverride); err != nil {
+			return errors.Wrapf(err, "env var %d", i+1)
+		}
+	}
+
+	return nil
+}
+
+// BuildpackRefs returns the buildpacks in the form accepted on the command line, either a URI or an
+// ID with an optional `@<version>` suffix
+func (c BuildConfig) BuildpackRefs() []string {
+	var refs []string
+	for _, bp := range c.Buildpacks {
+		switch {
+		case bp.URI != "":
+			refs = append(refs, bp.URI)
+		case bp.Version != "":
+			refs = append(refs, bp.ID+"@"+bp.Version)
+		default:
+			refs = append(refs, bp.ID)
+		}
+	}
+	return refs
+}
+
+// EnvMap returns the declared build env keyed on the platform env files that override each variable, as is
+// BuildOptions.Env
+func (c BuildConfig) EnvMap() map[string]string {
+	env := map[string]string{}
+	for _, e := range c.Env {
+		// names were validated when the descriptor was read
+		file, _ := builder.EnvFile(e.Name, builder.EnvOverride)
+		env[file] = e.Value
+	}
+	return env
+}
@@ -0,0 +1,156 @@
+package project_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/buildpack/pack/project"
+	h "github.com/buildpack/pack/testhelpers"
+)
+
+func TestProject(t *testing.T) {
+	spec.Run(t, "Project", testProject, spec.Parallel(), spec.Report(report.Terminal{}))
+}
+
+func testProject(t *testing.T, when spec.G, it spec.S) {
+	var appDir string
+
+	it.Before(func() {
+		var err error
+		appDir, err = ioutil.TempDir("", "project-test")
+		h.AssertNil(t, err)
+	})
+
+	it.After(func() {
+		os.RemoveAll(appDir)
+	})
+
+	writeDescriptor := func(contents string) {
+		h.AssertNil(t, ioutil.WriteFile(filepath.Join(appDir, project.DescriptorFile), []byte(contents), 0644))
+	}
+
+	when("#ReadDescriptor", func() {
+		when("there is no descriptor", func() {
+			it("returns an empty descriptor", func() {
+				descriptor, err := project.ReadDescriptor(appDir)
+				h.AssertNil(t, err)
+				h.AssertEq(t, descriptor, project.Descriptor{})
+			})
+		})
+
+		when("the descriptor is valid", func() {
+			it.Before(func() {
+				h.SkipIf(t, runtime.GOOS == "windows", "Skipped on windows")
+
+				writeDescriptor(`
+[build]
+builder = "some/builder"
+run-image = "some/run"
+exclude = ["*.log", "tmp/"]
+
+[[build.buildpacks]]
+id = "some.buildpack"
+version = "1.2.3"
+
+[[build.buildpacks]]
+id = "other.buildpack"
+
+[[build.buildpacks]]
+uri = "local/buildpack"
+
+[[build.buildpacks]]
+uri = "https://example.com/buildpack.tgz"
+
+[[build.env]]
+name = "SOME_KEY"
+value = "some-value"
+`)
+			})
+
+			it("returns the descriptor", func() {
+				descriptor, err := project.ReadDescriptor(appDir)
+				h.AssertNil(t, err)
+				h.AssertEq(t, descriptor.Build.Builder, "some/builder")
+				h.AssertEq(t, descriptor.Build.RunImage, "some/run")
+				h.AssertEq(t, descriptor.Build.Exclude, []string{"*.log", "tmp/"})
+				h.AssertEq(t, descriptor.Build.EnvMap(), map[string]string{"SOME_KEY.override": "some-value"})
+			})
+
+			it("resolves buildpack paths relative to the app dir", func() {
+				descriptor, err := project.ReadDescriptor(appDir)
+				h.AssertNil(t, err)
+				h.AssertEq(t, descriptor.Build.BuildpackRefs(), []string{
+					"some.buildpack@1.2.3",
+					"other.buildpack",
+					"file://" + filepath.Join(appDir, "local", "buildpack"),
+					"https://example.com/buildpack.tgz",
+				})
+			})
+		})
+
+		when("the descriptor cannot be parsed", func() {
+			it("returns an error", func() {
+				writeDescriptor(`[build`)
+				_, err := project.ReadDescriptor(appDir)
+				h.AssertError(t, err, "parse contents of")
+			})
+		})
+
+		when("both include and exclude are set", func() {
+			it("returns an error", func() {
+				writeDescriptor(`
+[build]
+include = ["src/"]
+exclude = ["*.log"]
+`)
+				_, err := project.ReadDescriptor(appDir)
+				h.AssertError(t, err, "'include' and 'exclude' cannot both be set")
+			})
+		})
+
+		when("a buildpack has no id or uri", func() {
+			it("returns an error", func() {
+				writeDescriptor(`
+[[build.buildpacks]]
+version = "1.2.3"
+`)
+				_, err := project.ReadDescriptor(appDir)
+				h.AssertError(t, err, "buildpack 1 must have an 'id' or 'uri'")
+			})
+		})
+
+		when("an env var name is not a file name", func() {
+			it("returns an error", func() {
+				writeDescriptor(`
+[[build.env]]
+name = "../../etc/some-file"
+value = "some-value"
+`)
+				_, err := project.ReadDescriptor(appDir)
+				h.AssertError(t, err, "env var 1: invalid env var name '../../etc/some-file'")
+			})
+		})
+
+		when("an env var has no name", func() {
+			it("returns an error without the value", func() {
+				writeDescriptor(`
+[[build.env]]
+name = "SOME_KEY"
+value = "some-value"
+
+[[build.env]]
+value = "some-secret"
+`)
+				_, err := project.ReadDescriptor(appDir)
+				h.AssertError(t, err, "env var 2 is missing a 'name'")
+				h.AssertNotContains(t, err.Error(), "some-secret")
+			})
+		})
+	})
+}
@@ -0,0 +1,75 @@
+package pack
+
+import (
+	"time"
+
+	"github.com/buildpack/pack/build"
+	"github.com/buildpack/pack/buildpack"
+)
+
+// BuildReport is a machine-readable summary of a build, populated by Client.Build when set on BuildOptions.
+type BuildReport struct {
+	Image          ImageReport               `json:"image" toml:"image"`
+	AdditionalTags []ImageReport             `json:"additionalTags,omitempty" toml:"additional-tags,omitempty"`
+	Builder        ImageReport               `json:"builder" toml:"builder"`
+	RunImage       RunImageReport            `json:"runImage" toml:"run-image"`
+	Buildpacks     []buildpack.BuildpackInfo `json:"buildpacks" toml:"buildpacks"`
+	Phases         []PhaseReport             `json:"phases" toml:"phases"`
+	Caches         []string                  `json:"caches" toml:"caches"`
+}
+
+type ImageReport struct {
+	Name   string `json:"name" toml:"name"`
+	Digest string `json:"digest" toml:"digest"`
+}
+
+type RunImageReport struct {
+	Image  string `json:"image" toml:"image"`                       // run image requested by the user or declared by the builder
+	Mirror string `json:"mirror,omitempty" toml:"mirror,omitempty"` // mirror used instead of Image, if any
+	Digest string `json:"digest" toml:"digest"`
+}
+
+type PhaseReport struct {
+	Name            string    `json:"name" toml:"name"`
+	StartedAt       time.Time `json:"startedAt" toml:"started-at"`
+	DurationSeconds float64   `json:"durationSeconds" toml:"duration-seconds"`
+	ExitCode        int       `json:"exitCode" toml:"exit-code"`
+}
+
+// recorder returns an event handler that records events into the report before forwarding them to next
+func (r *BuildReport) recorder(next build.EventHandler) build.EventHandler {
+	return func(e build.Event) {
+		r.record(e)
+		next.Emit(e)
+	}
+}
+
+func (r *BuildReport) record(e build.Event) {
+	switch e.Type {
+	case build.EventBuilderFetchFinished:
+		r.Builder = ImageReport{Name: e.Image, Digest: e.Digest}
+	case build.EventRunImageFetchFinished:
+		if e.Image != r.RunImage.Image {
+			r.RunImage.Mirror = e.Image
+		}
+		r.RunImage.Digest = e.Digest
+	case build.EventCacheSelected:
+		r.Caches = append(r.Caches, e.Cache)
+	case build.EventGroupDetected:
+		r.Buildpacks = e.Buildpacks
+	case build.EventPhaseFinished:
+		r.Phases = append(r.Phases, PhaseReport{
+			Name:            e.Phase,
+			StartedAt:       e.Time.Add(-e.Duration),
+			DurationSeconds: e.Duration.Seconds(),
+			ExitCode:        e.ExitCode,
+		})
+	case build.EventImageExported:
+		// the image itself is always exported first, followed by any additional tags
+		if r.Image.Name == "" {
+			r.Image = ImageReport{Name: e.Image, Digest: e.Digest}
+		} else {
+			r.AdditionalTags = append(r.AdditionalTags, ImageReport{Name: e.Image, Digest: e.Digest})
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/buildpack/pack/app"
+	"github.com/buildpack/pack/internal/git"
 	"github.com/buildpack/pack/style"
 )
 
@@ -16,28 +17,41 @@ type RunOptions struct {
 	Builder    string // defaults to default builder on the client config
 	RunImage   string // defaults to the best mirror from the builder image
 	Env        map[string]string
+	Secrets    map[string]string
+	Bindings   map[string]string
+	CACerts    []string
 	NoPull     bool
 	ClearCache bool
 	Buildpacks []string
+	Exclude    []string
+	Include    []string
 	Ports      []string
 }
 
 func (c *Client) Run(ctx context.Context, opts RunOptions) error {
-	appPath, err := c.processAppPath(opts.AppPath)
-	if err != nil {
-		return errors.Wrapf(err, "invalid app dir '%s'", opts.AppPath)
+	appPath := opts.AppPath
+	if _, ok := git.ParseSource(opts.AppPath); !ok {
+		var err error
+		if appPath, err = c.processAppPath(opts.AppPath); err != nil {
+			return errors.Wrapf(err, "invalid app dir '%s'", opts.AppPath)
+		}
 	}
 	sum := sha256.Sum256([]byte(appPath))
 	imageName := fmt.Sprintf("pack.local/run/%x", sum[:8])
-	err = c.Build(ctx, BuildOptions{
+	err := c.Build(ctx, BuildOptions{
 		AppPath:    appPath,
 		Builder:    opts.Builder,
 		RunImage:   opts.RunImage,
 		Env:        opts.Env,
+		Secrets:    opts.Secrets,
+		Bindings:   opts.Bindings,
+		CACerts:    opts.CACerts,
 		Image:      imageName,
 		NoPull:     opts.NoPull,
 		ClearCache: opts.ClearCache,
 		Buildpacks: opts.Buildpacks,
+		Exclude:    opts.Exclude,
+		Include:    opts.Include,
 	})
 	if err != nil {
 		return errors.Wrap(err, "build failed")
@@ -0,0 +1,33 @@
+package pack
+
+import (
+	"context"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+type ShellOptions struct {
+	DetectOptions           // app, builder and buildpacks to open the shell with
+	Detect        bool      // run the detector first so the selected group is in place
+	Stdin         io.Reader // required
+	Stdout        io.Writer // required
+}
+
+// Shell opens an interactive shell in the environment the builder phase sees for the app, as the CNB user and with
+// the ephemeral builder's buildpacks and env applied. Everything created is removed once the shell exits.
+func (c *Client) Shell(ctx context.Context, opts ShellOptions) error {
+	if opts.Stdin == nil || opts.Stdout == nil {
+		return errors.New("shell requires stdin and stdout")
+	}
+	if opts.AppReader != nil {
+		return errors.New("cannot read the app from a stream when opening a shell")
+	}
+
+	lifecycleOpts, cleanup, err := c.prepareLifecycle(ctx, opts.DetectOptions)
+	defer cleanup()
+	if err != nil {
+		return err
+	}
+	return c.lifecycle.ExecuteShell(ctx, lifecycleOpts, opts.Detect, opts.Stdin, opts.Stdout)
+}
@@ -445,6 +445,7 @@ func CreateTgz(t *testing.T, srcDir, tarDir string, mode int64) string {
 		srcDir,
 		tarDir,
 		0, 0, mode,
+		nil,
 	)
 	AssertNil(t, err)
 